@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/deebakkarthi/coraserver/db"
+)
+
+// termV2 is a term of the academic calendar as /api/v2 shows it
+type termV2 struct {
+	Name  string `json:"name"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+type holidayV2 struct {
+	Name  string `json:"name"`
+	Start string `json:"start"`
+	// End defaults to Start
+	End string `json:"end"`
+}
+
+type dayOrderV2 struct {
+	Date string `json:"date"`
+	// Day is the day whose timetable the date follows, MON to FRI
+	Day string `json:"day"`
+}
+
+// calendarV2 is the body of PUT /api/v2/calendar and the answer of GET
+type calendarV2 struct {
+	Terms     []termV2     `json:"terms"`
+	Holidays  []holidayV2  `json:"holidays"`
+	DayOrders []dayOrderV2 `json:"dayOrders"`
+}
+
+func toCalendarV2(c db.Calendar) calendarV2 {
+	response := calendarV2{Terms: []termV2{}, Holidays: []holidayV2{}, DayOrders: []dayOrderV2{}}
+	for _, t := range c.Terms {
+		response.Terms = append(response.Terms, termV2{t.Name, t.Start.Format(dateLayout), t.End.Format(dateLayout)})
+	}
+	for _, h := range c.Holidays {
+		response.Holidays = append(response.Holidays, holidayV2{h.Name, h.Start.Format(dateLayout), h.End.Format(dateLayout)})
+	}
+	for _, o := range c.DayOrders {
+		response.DayOrders = append(response.DayOrders, dayOrderV2{o.Date.Format(dateLayout), o.Day})
+	}
+	return response
+}
+
+// parseDate reads the date in field, which may be empty if optional
+func parseDate(field string, value string, optional bool) (time.Time, error) {
+	if value == "" && optional {
+		return time.Time{}, nil
+	}
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return date, &fieldError{field, field + " must look like 2006-01-02"}
+	}
+	return date, nil
+}
+
+// fromCalendarV2 parses the dates of c, naming the field of the first bad one
+func fromCalendarV2(c calendarV2) (db.Calendar, error) {
+	var calendar db.Calendar
+	for i, t := range c.Terms {
+		term := db.Term{Name: t.Name}
+		var err error
+		if term.Start, err = parseDate(fmt.Sprintf("terms[%d].start", i), t.Start, false); err != nil {
+			return calendar, err
+		}
+		if term.End, err = parseDate(fmt.Sprintf("terms[%d].end", i), t.End, false); err != nil {
+			return calendar, err
+		}
+		calendar.Terms = append(calendar.Terms, term)
+	}
+	for i, h := range c.Holidays {
+		holiday := db.Holiday{Name: h.Name}
+		var err error
+		if holiday.Start, err = parseDate(fmt.Sprintf("holidays[%d].start", i), h.Start, false); err != nil {
+			return calendar, err
+		}
+		if holiday.End, err = parseDate(fmt.Sprintf("holidays[%d].end", i), h.End, true); err != nil {
+			return calendar, err
+		}
+		calendar.Holidays = append(calendar.Holidays, holiday)
+	}
+	for i, o := range c.DayOrders {
+		date, err := parseDate(fmt.Sprintf("dayOrders[%d].date", i), o.Date, false)
+		if err != nil {
+			return calendar, err
+		}
+		calendar.DayOrders = append(calendar.DayOrders, db.DayOrder{Date: date, Day: strings.ToUpper(o.Day)})
+	}
+	return calendar, nil
+}
+
+func (s *server) v2Calendar(w http.ResponseWriter, r *http.Request) {
+	calendar, err := s.store.GetCalendar(r.Context())
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toCalendarV2(calendar))
+}
+
+/*
+v2SetCalendar replaces the academic calendar with the one in the body. The
+calendar is small and published a term at a time, so it is sent whole rather
+than edited entry by entry.
+*/
+func (s *server) v2SetCalendar(w http.ResponseWriter, r *http.Request) {
+	var body calendarV2
+	if err := decodeJSON(w, r, &body); err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	calendar, err := fromCalendarV2(body)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	id, _ := identityFrom(r.Context())
+	if err := s.store.SetCalendar(r.Context(), actor(id), calendar); err != nil {
+		writeDBError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
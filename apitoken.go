@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/deebakkarthi/coraserver/db"
+)
+
+// apiTokenPrefix starts every API token, telling them apart from sessions
+const apiTokenPrefix = "cora_"
+
+// apiTokenStore is the part of db.Store the session manager needs
+type apiTokenStore interface {
+	UseAPIToken(ctx context.Context, hash string, now time.Time) (db.APIToken, error)
+}
+
+// hashAPIToken is what is stored in place of the token
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+/*
+verifyAPIToken looks up an API token and returns the identity it acts as. A
+read token is a student, a book token the faculty it was minted for and an
+admin token the admin that minted it. A calendar token is no good for the
+API, it only opens a feed.
+*/
+func (m *sessionManager) verifyAPIToken(ctx context.Context, token string) (identity, error) {
+	t, err := m.apiTokens.UseAPIToken(ctx, hashAPIToken(token), time.Now())
+	if errors.Is(err, db.ErrNotFound) {
+		return identity{}, errInvalidSession
+	}
+	if err != nil {
+		return identity{}, err
+	}
+	if t.Scope == db.ScopeCalendar {
+		return identity{}, errInvalidSession
+	}
+	id := identity{Name: t.Name}
+	switch t.Scope {
+	case db.ScopeBook:
+		id.Mail, id.Role = t.Faculty, roleFaculty
+	case db.ScopeAdmin:
+		id.Mail, id.Role = t.CreatedBy, roleAdmin
+	}
+	return id, nil
+}
+
+// apiTokenResponse is a freshly minted token, the only time it is shown
+type apiTokenResponse struct {
+	db.APIToken
+	Token string `json:"token"`
+}
+
+// mintAPIToken creates a token on behalf of id
+func (s *server) mintAPIToken(r *http.Request, id identity, name string, scope string, faculty string) (apiTokenResponse, error) {
+	var response apiTokenResponse
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return response, err
+	}
+	secret, err := randomToken(32)
+	if err != nil {
+		return response, err
+	}
+	response.APIToken = db.APIToken{
+		ID:        hex.EncodeToString(idBytes),
+		Name:      name,
+		Scope:     scope,
+		Faculty:   faculty,
+		CreatedBy: id.Mail,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	response.Token = apiTokenPrefix + response.ID + "_" + secret
+	err = s.store.CreateAPIToken(r.Context(), actor(id), response.APIToken, hashAPIToken(response.Token))
+	if err != nil {
+		return response, err
+	}
+	log.Printf("API token %s (%s) created by %s", response.ID, response.Scope, id.Mail)
+	return response, nil
+}
+
+func (s *server) createAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	id, _ := identityFrom(r.Context())
+	response, err := s.mintAPIToken(r, id, r.URL.Query().Get("name"),
+		r.URL.Query().Get("scope"), r.URL.Query().Get("faculty"))
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *server) getAPITokensHandler(w http.ResponseWriter, r *http.Request) {
+	id, _ := identityFrom(r.Context())
+	tokens, err := s.store.GetAPITokens(r.Context(), actor(id))
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tokens)
+}
+
+func (s *server) revokeAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	id, _ := identityFrom(r.Context())
+	tokenID := r.URL.Query().Get("id")
+	if err := s.store.RevokeAPIToken(r.Context(), actor(id), tokenID); err != nil {
+		writeDBError(w, err)
+		return
+	}
+	log.Printf("API token %s revoked by %s", tokenID, id.Mail)
+	w.WriteHeader(http.StatusNoContent)
+}
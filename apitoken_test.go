@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/deebakkarthi/coraserver/db"
+)
+
+func TestAPITokens(t *testing.T) {
+	store, err := db.Open(db.Config{Driver: db.DriverMemory, Fixture: filepath.Join("db", "scripts", "fixture.json")})
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	defer store.Close()
+	m, _ := newSessionManager("", 0, false)
+	m.apiTokens = store
+	s := &server{store: store, sessions: m}
+	adminSession, _ := m.issue(identity{Mail: "admin@cb.amrita.edu", Role: roleAdmin})
+
+	// call runs handler behind requireRole(min) with the given bearer token
+	call := func(min role, handler http.HandlerFunc, token string, query url.Values) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/db/test?"+query.Encode(), nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		m.requireRole(min, handler)(w, r)
+		return w
+	}
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		id, _ := identityFrom(r.Context())
+		w.Write([]byte(id.Mail))
+	}
+	mint := func(query url.Values) apiTokenResponse {
+		t.Helper()
+		w := call(roleAdmin, s.createAPITokenHandler, adminSession, query)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Failed to create token: %d %s", w.Code, w.Body)
+		}
+		var response apiTokenResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Bad response: %v", err)
+		}
+		return response
+	}
+
+	kiosk := mint(url.Values{"name": {"Kiosk"}, "scope": {db.ScopeRead}})
+	script := mint(url.Values{"name": {"Script"}, "scope": {db.ScopeBook}, "faculty": {"n_harini@cb.amrita.edu"}})
+
+	if w := call(roleStudent, ok, kiosk.Token, nil); w.Code != http.StatusOK {
+		t.Errorf("Read token rejected for availability: %d", w.Code)
+	}
+	if w := call(roleFaculty, ok, kiosk.Token, nil); w.Code != http.StatusForbidden {
+		t.Errorf("Read token allowed to book: %d", w.Code)
+	}
+	w := call(roleFaculty, ok, script.Token, nil)
+	if w.Code != http.StatusOK || w.Body.String() != "n_harini@cb.amrita.edu" {
+		t.Errorf("Book token should act as its faculty, got %d %q", w.Code, w.Body)
+	}
+	if w := call(roleAdmin, ok, script.Token, nil); w.Code != http.StatusForbidden {
+		t.Errorf("Book token allowed admin access: %d", w.Code)
+	}
+	if w := call(roleAdmin, s.createAPITokenHandler, adminSession, url.Values{"scope": {"root"}}); w.Code != http.StatusBadRequest {
+		t.Errorf("Token with unknown scope: %d", w.Code)
+	}
+
+	// The list shows when a token was last used but never the token itself
+	w = call(roleAdmin, s.getAPITokensHandler, adminSession, nil)
+	var tokens []map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &tokens); err != nil || len(tokens) != 2 {
+		t.Fatalf("Unexpected token list %s", w.Body)
+	}
+	for _, token := range tokens {
+		if token["lastUsed"] == nil {
+			t.Errorf("Token %v has no last use", token["id"])
+		}
+		if _, ok := token["token"]; ok {
+			t.Errorf("Token list reveals the token")
+		}
+	}
+
+	w = call(roleAdmin, s.revokeAPITokenHandler, adminSession, url.Values{"id": {kiosk.ID}})
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Failed to revoke token: %d %s", w.Code, w.Body)
+	}
+	if w := call(roleStudent, ok, kiosk.Token, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Revoked token accepted: %d", w.Code)
+	}
+}
@@ -1,369 +1,846 @@
 package db
 
 import (
-	"database/sql"
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
 	"strings"
 	"time"
+)
 
-	"github.com/go-sql-driver/mysql"
-	_ "github.com/go-sql-driver/mysql"
+// Names of the backends understood by Open
+const (
+	DriverMySQL  = "mysql"
+	DriverSQLite = "sqlite"
+	DriverMemory = "memory"
 )
 
-var cfg, _ = mysql.ParseDSN("cora:@/cora_db?parseTime=true")
-
-func GetFreeClass(slot int, date time.Time) []string {
-	var classroom []string
-	day := strings.ToUpper(date.Weekday().String()[:3])
-	db, err := sql.Open("mysql", cfg.FormatDSN())
-
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer db.Close()
-
-	stmt, err := db.Prepare(
-		`SELECT class_id FROM static s WHERE
-        slot_id = ? AND
-        day = ? AND
-        subject_id = "FREE" AND
-        NOT EXISTS (SELECT 1 FROM dynamic WHERE
-        slot_id=s.slot_id AND
-    date=? AND class_id=s.class_id)
-        `)
-	if err != nil {
-		panic(err)
-	}
-	defer stmt.Close()
-
-	rows, err := stmt.Query(slot, day, date)
-	// Process the query results
-	for rows.Next() {
-		var tmp string
-		err := rows.Scan(&tmp)
-		if err != nil {
-			panic(err)
+/*
+Config describes how to reach the database. It is embedded in config.json
+under the "database" key so that it is read once at startup together with the
+OAuth settings. Zero values fall back to sensible defaults.
+
+Driver picks the backend and defaults to MySQL. For MySQL the DSN is a
+go-sql-driver/mysql data source name, for SQLite it is the path of the
+database file. The memory backend keeps everything in the process and starts
+from the JSON file named by Fixture, if any.
+*/
+type Config struct {
+	Driver       string `json:"driver"`
+	DSN          string `json:"dsn"`
+	MaxOpenConns int    `json:"maxOpenConns"`
+	MaxIdleConns int    `json:"maxIdleConns"`
+	// ConnMaxLifetime is in seconds
+	ConnMaxLifetime int    `json:"connMaxLifetime"`
+	Fixture         string `json:"fixture"`
+}
+
+/*
+Errors returned by a Store wrap one of these sentinels so that callers can
+tell them apart with errors.Is without knowing which driver is in use. A
+cancelled or expired context is returned as is (context.Canceled or
+context.DeadlineExceeded).
+*/
+var (
+	// ErrNotFound means a referenced row (class, slot, faculty, subject or
+	// booking) does not exist
+	ErrNotFound = errors.New("db: not found")
+	// ErrConflict means the write clashes with existing data, e.g. the slot
+	// is already booked
+	ErrConflict = errors.New("db: conflict")
+	// ErrUnavailable means the database could not be reached
+	ErrUnavailable = errors.New("db: unavailable")
+	// ErrForbidden means the acting user may not modify the row
+	ErrForbidden = errors.New("db: forbidden")
+	// ErrInvalid means an argument is out of range, e.g. a day that is not
+	// a weekday
+	ErrInvalid = errors.New("db: invalid argument")
+)
+
+/*
+Actor is the user on whose behalf a change is made. ID is the faculty id
+(their e-mail address). An admin may act on anybody's bookings.
+*/
+type Actor struct {
+	ID    string
+	Admin bool
+}
+
+// mayModify reports whether actor may change a booking owned by faculty
+func (a Actor) mayModify(faculty string) bool {
+	return a.Admin || (a.ID != "" && a.ID == faculty)
+}
+
+/*
+Store is everything the HTTP handlers need from the database. A Store is
+created once at startup with Open and shared by every request; it owns a
+connection pool and the prepared statements, so it must be closed on shutdown.
+*/
+type Store interface {
+	GetFreeClass(ctx context.Context, slot int, date time.Time) ([]string, error)
+	GetFreeSlot(ctx context.Context, class string, date time.Time) ([]int, error)
+	MultiFreeSlot(ctx context.Context, startSlot int, endSlot int, date time.Time) ([]string, error)
+	// GetTimetableByDay lists what happens in class on date, slot by slot.
+	// A booking takes the place of the static entry for its slot.
+	GetTimetableByDay(ctx context.Context, class string, date time.Time) ([]TimetableEntry, error)
+	// GetTimetableRange is GetTimetableByDay for every date from from to to,
+	// both included, in a single query. The range may span at most
+	// MaxRangeDays days.
+	GetTimetableRange(ctx context.Context, class string, from time.Time, to time.Time) ([]DayTimetable, error)
+	// GetFacultySchedule is the same for a faculty member: the classes they
+	// teach according to the static timetable, unless the room is booked
+	// over, and their bookings.
+	GetFacultySchedule(ctx context.Context, faculty string, from time.Time, to time.Time) ([]DayTimetable, error)
+	GetAllSlot(ctx context.Context) ([]int, error)
+	GetAllClass(ctx context.Context) ([]string, error)
+	GetAllSubject(ctx context.Context) ([]string, error)
+	GetBooking(ctx context.Context, faculty string) ([]BookingRecord, error)
+	Booking(ctx context.Context, class string, date time.Time, slot int, faculty string, subject string) (int64, error)
+	MultiBooking(ctx context.Context, class string, date time.Time, startSlot int, endSlot int, faculty string, subject string) (int64, error)
+	// CancelBooking deletes a booking made by actor, or by anyone if actor
+	// is an admin. It fails with ErrNotFound when there is no such booking
+	// and ErrForbidden when it belongs to somebody else.
+	CancelBooking(ctx context.Context, actor Actor, class string, date time.Time, slot int) error
+	// BookSeries books every date of a recurring booking. Unless partial is
+	// set the series is booked as a whole or not at all, and a
+	// *ConflictError lists the slots in the way on every date. With partial
+	// the dates that can't be booked are skipped and returned as conflicts.
+	// The series is returned with its ID, Until, Skip and Occurrences filled
+	// in.
+	BookSeries(ctx context.Context, s Series, partial bool) (Series, []Conflict, error)
+	// GetSeries returns a series with its skipped and booked dates. Like
+	// CancelSeries it is for its faculty member or an admin.
+	GetSeries(ctx context.Context, actor Actor, id int64) (Series, error)
+	// CancelSeries deletes a series and every booking made for it. Like
+	// CancelBooking it is for its faculty member or an admin.
+	CancelSeries(ctx context.Context, actor Actor, id int64) error
+	// CancelOccurrence deletes the bookings of a series on one date and adds
+	// the date to Skip. It fails with ErrNotFound when nothing is booked for
+	// the series on that date.
+	CancelOccurrence(ctx context.Context, actor Actor, id int64, date time.Time) error
+	// SetStatic adds or replaces a cell of the weekly timetable. Only admins
+	// may change it; a room is freed by setting the "FREE" subject and
+	// faculty.
+	SetStatic(ctx context.Context, actor Actor, e StaticEntry) error
+	// SetVersionStatic is SetStatic for a version of the timetable other
+	// than DefaultVersion, which is the one SetStatic changes
+	SetVersionStatic(ctx context.Context, actor Actor, version string, e StaticEntry) error
+	// GetTimetableVersions lists the versions of the static timetable.
+	// SetTimetableVersion adds or changes one, publishing a version puts it
+	// in force on its dates, or fails with ErrConflict when another
+	// published version shares one of them. These and the methods below are
+	// for admins.
+	GetTimetableVersions(ctx context.Context, actor Actor) ([]TimetableVersion, error)
+	SetTimetableVersion(ctx context.Context, actor Actor, v TimetableVersion) error
+	// DeleteTimetableVersion deletes a version that is not published,
+	// timetable and all. It fails with ErrInvalid for a published one and
+	// for DefaultVersion, which can't be unpublished either.
+	DeleteTimetableVersion(ctx context.Context, actor Actor, name string) error
+	// CopyTimetableVersion replaces the timetable of version to with a copy
+	// of the timetable of version from, e.g. to start next term's from this
+	// one.
+	CopyTimetableVersion(ctx context.Context, actor Actor, from string, to string) error
+	// PreviewTimetableRange is GetTimetableRange as if version were in force
+	// on every date, whether it is published or not
+	PreviewTimetableRange(ctx context.Context, actor Actor, version string, class string, from time.Time, to time.Time) ([]DayTimetable, error)
+	// GetCalendar returns the academic calendar. SetCalendar replaces it as
+	// a whole and is for admins only.
+	GetCalendar(ctx context.Context) (Calendar, error)
+	SetCalendar(ctx context.Context, actor Actor, c Calendar) error
+	// CreateAPIToken stores a token minted by actor, an admin, under the
+	// SHA-256 hash of its secret. GetAPITokens and RevokeAPIToken are
+	// likewise for admins only.
+	CreateAPIToken(ctx context.Context, actor Actor, t APIToken, hash string) error
+	GetAPITokens(ctx context.Context, actor Actor) ([]APIToken, error)
+	RevokeAPIToken(ctx context.Context, actor Actor, id string) error
+	// UseAPIToken returns the token with the given hash and records that it
+	// was used at now. It fails with ErrNotFound for unknown or revoked
+	// tokens.
+	UseAPIToken(ctx context.Context, hash string, now time.Time) (APIToken, error)
+	Close() error
+}
+
+type BookingRecord struct {
+	Class   string    `json:"class"`
+	Date    time.Time `json:"date"`
+	Slot    int       `json:"slot"`
+	Faculty string    `json:"faculty"`
+	Subject string    `json:"subject"`
+	// Series is the ID of the series the booking was made for, if any
+	Series int64 `json:"series,omitempty"`
+}
+
+// Sources of a TimetableEntry
+const (
+	// SourceStatic is the regular weekly timetable
+	SourceStatic = "static"
+	// SourceBooking is a booking for that date, which overrides the static
+	// entry
+	SourceBooking = "booking"
+)
+
+// TimetableEntry is what a room is used for during one slot of a given date
+type TimetableEntry struct {
+	Room string `json:"room"`
+	Slot int    `json:"slot"`
+	// Start and End are wall clock times formatted as "15:04"
+	Start       string `json:"start"`
+	End         string `json:"end"`
+	Subject     string `json:"subject"`
+	SubjectName string `json:"subjectName"`
+	Faculty     string `json:"faculty"`
+	Source      string `json:"source"`
+}
+
+// DayTimetable is the timetable of a room on one date
+type DayTimetable struct {
+	Date time.Time `json:"date"`
+	// Day is the day of the static timetable the date follows, see
+	// Calendar.Day
+	Day     string           `json:"day"`
+	Entries []TimetableEntry `json:"entries"`
+}
+
+// sortEntries orders timetable entries by slot, then room
+func sortEntries(entries []TimetableEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Slot != entries[j].Slot {
+			return entries[i].Slot < entries[j].Slot
 		}
-		classroom = append(classroom, tmp)
-	}
-	return classroom
+		return entries[i].Room < entries[j].Room
+	})
 }
 
-func GetFreeSlot(class string, date time.Time) []int {
-	var slot []int
-	day := strings.ToUpper(date.Weekday().String()[:3])
-	db, err := sql.Open("mysql", cfg.FormatDSN())
-	if err != nil {
-		log.Println(err)
-		return slot
-	}
-	defer db.Close()
+// MaxRangeDays bounds the date ranges a Store answers for in one call
+const MaxRangeDays = 92
 
-	stmt, err := db.Prepare(
-		`SELECT slot_id FROM static s WHERE
-        class_id = ? AND
-        day = ? AND
-        subject_id = "FREE" AND NOT EXISTS (SELECT 1 FROM dynamic WHERE
-        class_id=s.class_id AND date=? AND slot_id=s.slot_id)`)
-	if err != nil {
-		log.Println(err)
+// checkRange validates a date range and returns it without the time of day
+func checkRange(from time.Time, to time.Time) (time.Time, time.Time, error) {
+	from, to = dateOnly(from), dateOnly(to)
+	if to.Before(from) {
+		return from, to, fmt.Errorf("%w: range ends before it starts", ErrInvalid)
 	}
-	defer stmt.Close()
-
-	rows, err := stmt.Query(class, day, date)
-	if err != nil {
-		log.Println(err)
+	if to.Sub(from) >= MaxRangeDays*24*time.Hour {
+		return from, to, fmt.Errorf("%w: range longer than %d days", ErrInvalid, MaxRangeDays)
 	}
-	// Process the query results
-	for rows.Next() {
-		var tmp int
-		err := rows.Scan(&tmp)
-		if err != nil {
-			log.Println(err)
-		}
-		slot = append(slot, tmp)
-	}
-	return slot
-}
-
-func MultiFreeSlot(startSlot int, endSlot int, date time.Time) []string {
-	var slot []string
-	day := strings.ToUpper(date.Weekday().String()[:3])
-	db, err := sql.Open("mysql", cfg.FormatDSN())
-	if err != nil {
-		log.Println(err)
-		return slot
-	}
-	defer db.Close()
-
-	stmt, err := db.Prepare(`
-    SELECT class_id FROM (SELECT class_id, COUNT(class_id) as num_free FROM
-    static s WHERE slot_id BETWEEN ? AND ? AND subject_id="FREE" AND day=? AND
-    NOT EXISTS (SELECT 1 FROM dynamic WHERE slot_id=s.slot_id AND date=? AND
-    class_id=s.class_id) GROUP BY class_id) as tmp WHERE num_free=(?-?)+1;
-    `)
-	/*
-	   SELECT class_id FROM (SELECT class_id, COUNT(class_id) as num_free FROM
-	   static s WHERE slot_id BETWEEN 5 AND 8 AND subject_id="FREE" AND
-	   day="TUE" AND NOT EXISTS (SELECT 1 FROM DYNAMIC WHERE slot_id=s.slot_id
-	   AND date="2023-06-13" AND class_id=s.class_id) GROUP BY class_id) as
-	   tmp WHERE num_free=(8-5)+1;
-	*/
-	if err != nil {
-		log.Println(err)
-	}
-	defer stmt.Close()
-
-	rows, err := stmt.Query(startSlot, endSlot, day, date, endSlot, startSlot)
-	if err != nil {
-		log.Println(err)
-	}
-	// Process the query results
-	for rows.Next() {
-		var tmp string
-		err := rows.Scan(&tmp)
-		if err != nil {
-			log.Println(err)
+	return from, to, nil
+}
+
+// Reasons a slot cannot be booked, reported in Conflict.Reason
+const (
+	// ReasonNotFree means the room has a regular class in that slot, or the
+	// slot does not exist
+	ReasonNotFree = "not-free"
+	// ReasonBooked means somebody else has already booked the room
+	ReasonBooked = "booked"
+	// ReasonFacultyTeaching means the faculty member has a regular class in
+	// another room at that time
+	ReasonFacultyTeaching = "faculty-teaching"
+	// ReasonFacultyBooked means the faculty member has already booked
+	// another room at that time
+	ReasonFacultyBooked = "faculty-booked"
+	// ReasonClosed means the date is a holiday or outside the terms of the
+	// academic calendar
+	ReasonClosed = "closed"
+)
+
+/*
+Conflict describes one reason a slot could not be booked. A slot can appear
+more than once, e.g. when the room is taken and the faculty member is also
+busy elsewhere. For the faculty reasons OtherClass is the room the faculty
+member is busy in.
+*/
+type Conflict struct {
+	Class      string    `json:"class"`
+	Date       time.Time `json:"date"`
+	Slot       int       `json:"slot"`
+	Reason     string    `json:"reason"`
+	OtherClass string    `json:"otherClass,omitempty"`
+}
+
+/*
+ConflictError is returned when a booking is rejected because some of the
+requested slots are unavailable. It lists every one of them, not just the
+first, and matches ErrConflict under errors.Is.
+*/
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	var b strings.Builder
+	b.WriteString(ErrConflict.Error())
+	for i, c := range e.Conflicts {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString(", ")
 		}
-		slot = append(slot, tmp)
-	}
-	return slot
-}
-
-func GetTimetableByDay(class string, date time.Time) []string {
-	var subject []string
-	day := strings.ToUpper(date.Weekday().String()[:3])
-	db, err := sql.Open("mysql", cfg.FormatDSN())
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer db.Close()
-
-	/*
-	   SELECT subject_id FROM (SELECT slot_id, subject_id FROM dynamic WHERE
-	   date="2023-06-14" AND class_id="A104" UNION SELECT slot_id, subject_id
-	   FROM static WHERE day="WED" AND class_id="A104") as c GROUP BY slot_id;
-	*/
-	stmt, err := db.Prepare(`
-    SELECT subject_id FROM
-    (SELECT slot_id, subject_id FROM dynamic WHERE date=? AND class_id=? UNION
-    SELECT slot_id, subject_id FROM static WHERE day=? AND class_id=?) as tmp
-    GROUP BY slot_id;
-    `)
-	if err != nil {
-		panic(err)
-	}
-	defer stmt.Close()
-
-	rows, err := stmt.Query(date, class, day, class)
-	// Process the query results
-	for rows.Next() {
-		var tmp string
-		err := rows.Scan(&tmp)
-		if err != nil {
-			panic(err)
+		fmt.Fprintf(&b, "%s on %s slot %d is %s", c.Class, c.Date.Format(dateLayout), c.Slot, c.Reason)
+		if c.OtherClass != "" {
+			fmt.Fprintf(&b, " in %s", c.OtherClass)
 		}
-		subject = append(subject, tmp)
 	}
-	return subject
+	return b.String()
 }
 
-func GetAllSlot() []int {
-	var slot []int
-	db, err := sql.Open("mysql", cfg.FormatDSN())
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer db.Close()
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
 
-	stmt, err := db.Prepare(
-		`SELECT id FROM slot;`)
-	if err != nil {
-		panic(err)
-	}
-	defer stmt.Close()
+// slotState is what a booking needs to know about one slot of a room
+type slotState struct {
+	// closed is set when the academic calendar has no classes on the date
+	closed bool
+	// free is set when the room is FREE in the static timetable
+	free bool
+	// booked is set when the room already has a booking
+	booked bool
+	// teaching is the other room where the faculty member has a regular
+	// class, if any
+	teaching string
+	// facultyBooked is the other room the faculty member has booked, if any
+	facultyBooked string
+}
 
-	rows, err := stmt.Query()
-	for rows.Next() {
-		var tmp int
-		err := rows.Scan(&tmp)
-		if err != nil {
-			panic(err)
+/*
+rangeConflicts checks every slot from startSlot to endSlot against state. It
+returns a *ConflictError when at least one slot is unavailable.
+*/
+func rangeConflicts(class string, date time.Time, startSlot int, endSlot int, state map[int]slotState) error {
+	var conflicts []Conflict
+	for slot := startSlot; slot <= endSlot; slot++ {
+		st := state[slot]
+		conflict := Conflict{Class: class, Date: date, Slot: slot}
+		if st.closed {
+			conflict.Reason = ReasonClosed
+			conflicts = append(conflicts, conflict)
+			continue
+		}
+		if !st.free {
+			conflict.Reason = ReasonNotFree
+			conflicts = append(conflicts, conflict)
+		}
+		if st.booked {
+			conflict.Reason = ReasonBooked
+			conflicts = append(conflicts, conflict)
+		}
+		if st.teaching != "" {
+			conflict.Reason = ReasonFacultyTeaching
+			conflict.OtherClass = st.teaching
+			conflicts = append(conflicts, conflict)
+		}
+		if st.facultyBooked != "" {
+			conflict.Reason = ReasonFacultyBooked
+			conflict.OtherClass = st.facultyBooked
+			conflicts = append(conflicts, conflict)
 		}
-		slot = append(slot, tmp)
 	}
-	return slot
+	if len(conflicts) > 0 {
+		return &ConflictError{Conflicts: conflicts}
+	}
+	return nil
 }
 
-func GetAllClass() []string {
-	var class []string
-	db, err := sql.Open("mysql", cfg.FormatDSN())
-	if err != nil {
-		log.Fatal(err)
+/*
+Series is a booking that repeats every Interval weeks, on the weekday of Start,
+until Until. Each date books the slots from StartSlot to EndSlot. The dates
+in Skip are left out, whether they were asked to be or could not be booked or
+were cancelled later.
+*/
+type Series struct {
+	ID        int64     `json:"id"`
+	Class     string    `json:"class"`
+	Faculty   string    `json:"faculty"`
+	Subject   string    `json:"subject"`
+	StartSlot int       `json:"startSlot"`
+	EndSlot   int       `json:"endSlot"`
+	Start     time.Time `json:"start"`
+	Until     time.Time `json:"until"`
+	// Interval is in weeks and defaults to 1
+	Interval int `json:"interval"`
+	// Count may be given to BookSeries instead of Until. It counts the
+	// dates before any are skipped.
+	Count int         `json:"-"`
+	Skip  []time.Time `json:"skip"`
+	// Occurrences are the dates that are booked for the series
+	Occurrences []time.Time `json:"occurrences"`
+}
+
+// MaxSeriesDates bounds the number of dates of a Series, skipped ones included
+const MaxSeriesDates = 52
+
+// checkSeries validates s and fills in Interval and Until
+func checkSeries(s Series) (Series, error) {
+	if s.Class == "" {
+		return s, fmt.Errorf("%w: series without a room", ErrInvalid)
+	}
+	if s.StartSlot <= 0 || s.EndSlot < s.StartSlot {
+		return s, fmt.Errorf("%w: slots %d to %d", ErrInvalid, s.StartSlot, s.EndSlot)
+	}
+	if s.Interval == 0 {
+		s.Interval = 1
+	}
+	if s.Interval < 0 {
+		return s, fmt.Errorf("%w: interval of %d weeks", ErrInvalid, s.Interval)
+	}
+	s.Start = dateOnly(s.Start)
+	switch {
+	case s.Count > 0 && !s.Until.IsZero():
+		return s, fmt.Errorf("%w: a series ends at a date or after a count, not both", ErrInvalid)
+	case s.Count > MaxSeriesDates:
+		return s, fmt.Errorf("%w: a series may have at most %d dates", ErrInvalid, MaxSeriesDates)
+	case s.Count > 0:
+		s.Until = s.Start.AddDate(0, 0, 7*s.Interval*(s.Count-1))
+	case s.Until.IsZero():
+		return s, fmt.Errorf("%w: a series needs an end date or a count", ErrInvalid)
+	}
+	s.Count = 0
+	s.Until = dateOnly(s.Until)
+	if s.Until.Before(s.Start) {
+		return s, fmt.Errorf("%w: series ends before it starts", ErrInvalid)
+	}
+	dates := s.allDates()
+	if len(dates) > MaxSeriesDates {
+		return s, fmt.Errorf("%w: a series may have at most %d dates", ErrInvalid, MaxSeriesDates)
+	}
+	// The last date is the last one the series falls on
+	s.Until = dates[len(dates)-1]
+	on := make(map[time.Time]bool)
+	for _, date := range dates {
+		on[date] = true
+	}
+	skip := s.Skip
+	s.Skip = nil
+	for _, date := range skip {
+		date = dateOnly(date)
+		if !on[date] {
+			return s, fmt.Errorf("%w: the series does not fall on %s", ErrInvalid, date.Format(dateLayout))
+		}
+		s.Skip = addDate(s.Skip, date)
 	}
-	defer db.Close()
+	if len(s.Skip) == len(dates) {
+		return s, fmt.Errorf("%w: every date of the series is skipped", ErrInvalid)
+	}
+	return s, nil
+}
 
-	stmt, err := db.Prepare(
-		`SELECT UNIQUE class_id FROM static;`)
-	if err != nil {
-		panic(err)
+// allDates lists every date of s from Start to Until, skipped ones included
+func (s Series) allDates() []time.Time {
+	var dates []time.Time
+	for date := s.Start; !date.After(s.Until); date = date.AddDate(0, 0, 7*s.Interval) {
+		dates = append(dates, date)
+		if len(dates) > MaxSeriesDates {
+			break
+		}
 	}
-	defer stmt.Close()
+	return dates
+}
 
-	rows, err := stmt.Query()
-	for rows.Next() {
-		var tmp string
-		err := rows.Scan(&tmp)
-		if err != nil {
-			panic(err)
+// dates lists the dates of s that are not skipped
+func (s Series) dates() []time.Time {
+	var dates []time.Time
+	for _, date := range s.allDates() {
+		if !hasDate(s.Skip, date) {
+			dates = append(dates, date)
 		}
-		class = append(class, tmp)
 	}
-	return class
+	return dates
 }
 
-func GetAllSubject() []string {
-	var subject []string
-	db, err := sql.Open("mysql", cfg.FormatDSN())
-	if err != nil {
-		log.Fatal(err)
+func hasDate(dates []time.Time, date time.Time) bool {
+	for _, d := range dates {
+		if d.Equal(date) {
+			return true
+		}
 	}
-	defer db.Close()
+	return false
+}
 
-	stmt, err := db.Prepare(
-		`SELECT id FROM subject WHERE id!="FREE";`)
-	if err != nil {
-		panic(err)
+// addDate inserts date into the sorted dates unless it is there already
+func addDate(dates []time.Time, date time.Time) []time.Time {
+	i := sort.Search(len(dates), func(i int) bool { return !dates[i].Before(date) })
+	if i < len(dates) && dates[i].Equal(date) {
+		return dates
 	}
-	defer stmt.Close()
+	dates = append(dates, time.Time{})
+	copy(dates[i+1:], dates[i:])
+	dates[i] = date
+	return dates
+}
 
-	rows, err := stmt.Query()
-	for rows.Next() {
-		var tmp string
-		err := rows.Scan(&tmp)
+/*
+seriesConflicts checks every date of s with the slot states read by states.
+It returns the dates that can be booked and the conflicts of the others.
+*/
+func seriesConflicts(s Series, states func(date time.Time) (map[int]slotState, error)) ([]time.Time, []Conflict, error) {
+	var free []time.Time
+	var conflicts []Conflict
+	for _, date := range s.dates() {
+		state, err := states(date)
 		if err != nil {
-			panic(err)
+			return nil, nil, err
 		}
-		subject = append(subject, tmp)
+		var conflictErr *ConflictError
+		if err := rangeConflicts(s.Class, date, s.StartSlot, s.EndSlot, state); errors.As(err, &conflictErr) {
+			conflicts = append(conflicts, conflictErr.Conflicts...)
+			continue
+		}
+		free = append(free, date)
 	}
-	return subject
+	return free, conflicts, nil
 }
 
-type BookingRecord struct {
-	Class   string    `json:"class"`
-	Date    time.Time `json:"date"`
-	Slot    int       `json:"slot"`
-	Faculty string    `json:"faculty"`
-	Subject string    `json:"subject"`
+// Slot is one period of the day
+type Slot struct {
+	ID int `json:"id"`
+	// Start and End are wall clock times formatted as "15:04"
+	Start string `json:"start"`
+	End   string `json:"end"`
 }
 
-func CancelBooking(class string, date time.Time, slot int) error {
-	db, err := sql.Open("mysql", cfg.FormatDSN())
-	if err != nil {
-		log.Println(err)
-		return nil
-	}
-	defer db.Close()
-	stmt, err := db.Prepare(`DELETE FROM dynamic WHERE class_id=? AND date=? AND slot_id=?`)
-	if err != nil {
-		log.Println(err)
-		return nil
+type Subject struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type Faculty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+/*
+StaticEntry is one cell of the regular weekly timetable. A room that is not
+in use during a slot has the "FREE" subject and faculty.
+*/
+type StaticEntry struct {
+	Class   string `json:"class"`
+	Day     string `json:"day"`
+	Slot    int    `json:"slot"`
+	Faculty string `json:"faculty"`
+	Subject string `json:"subject"`
+}
+
+// weekdays are the days the static timetable has entries for
+var weekdays = map[string]bool{"MON": true, "TUE": true, "WED": true, "THU": true, "FRI": true}
+
+// checkStatic holds the checks SetStatic makes before touching the store
+func checkStatic(actor Actor, e StaticEntry) error {
+	if !actor.Admin {
+		return fmt.Errorf("%w: only admins may change the timetable", ErrForbidden)
 	}
-	defer stmt.Close()
-	_, err = stmt.Exec(class, date, slot)
-	if err != nil {
-		log.Println(err)
-		return err
+	if !weekdays[e.Day] {
+		return fmt.Errorf("%w: day %q", ErrInvalid, e.Day)
 	}
 	return nil
 }
 
-func GetBooking(faculty string) []BookingRecord {
-	var booking []BookingRecord
-	db, err := sql.Open("mysql", cfg.FormatDSN())
-	if err != nil {
-		log.Println(err)
+// DefaultVersion holds the static timetable as it was before there were
+// versions. It is published and in force on every date, unless another
+// version is.
+const DefaultVersion = "default"
+
+/*
+TimetableVersion is a named static timetable, such as one semester's. A
+published version is in force from Start to End, both included; a zero Start
+or End leaves that side open. Published versions other than DefaultVersion
+may not overlap, DefaultVersion is in force on the dates none of them covers.
+A version that is not published is staged: admins can fill it in and preview
+it, but nothing else looks at it.
+*/
+type TimetableVersion struct {
+	Name      string    `json:"name"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Published bool      `json:"published"`
+}
+
+// covers reports whether date falls between the Start and End of v
+func (v TimetableVersion) covers(date time.Time) bool {
+	return (v.Start.IsZero() || !date.Before(v.Start)) && (v.End.IsZero() || !date.After(v.End))
+}
+
+// overlaps reports whether v and w share a date
+func (v TimetableVersion) overlaps(w TimetableVersion) bool {
+	return (v.Start.IsZero() || w.End.IsZero() || !v.Start.After(w.End)) &&
+		(w.Start.IsZero() || v.End.IsZero() || !w.Start.After(v.End))
+}
+
+// checkOverlap fails with ErrConflict when v is published and shares a date
+// with another published version of versions. DefaultVersion overlaps every
+// version, so it is left out on both sides.
+func checkOverlap(versions []TimetableVersion, v TimetableVersion) error {
+	if !v.Published || v.Name == DefaultVersion {
 		return nil
 	}
-	defer db.Close()
-	stmt, err := db.Prepare(`SELECT * FROM dynamic WHERE faculty_id=?`)
-	if err != nil {
-		log.Println(err)
-		return nil
+	for _, w := range versions {
+		if w.Published && w.Name != DefaultVersion && w.Name != v.Name && v.overlaps(w) {
+			return fmt.Errorf("%w: version %s overlaps published version %s", ErrConflict, v.Name, w.Name)
+		}
 	}
-	defer stmt.Close()
-	rows, err := stmt.Query(faculty)
-	for rows.Next() {
-		var tmp BookingRecord
-		err := rows.Scan(&tmp.Class, &tmp.Date, &tmp.Slot, &tmp.Faculty, &tmp.Subject)
-		if err != nil {
-			panic(err)
+	return nil
+}
+
+// versionOn is the name of the version in force on date, or "" if there is
+// none
+func versionOn(versions []TimetableVersion, date time.Time) string {
+	date = dateOnly(date)
+	var inForce *TimetableVersion
+	for i, v := range versions {
+		if !v.Published || !v.covers(date) {
+			continue
 		}
-		booking = append(booking, tmp)
+		if inForce == nil || v.Start.After(inForce.Start) ||
+			(v.Start.Equal(inForce.Start) && v.Name < inForce.Name) {
+			inForce = &versions[i]
+		}
+	}
+	if inForce == nil {
+		return ""
 	}
-	return booking
+	return inForce.Name
 }
 
-func Booking(class string, date time.Time, slot int, faculty string, subject string) (int64, error) {
-	db, err := sql.Open("mysql", cfg.FormatDSN())
-	if err != nil {
-		log.Println(err)
-		return 0, err
+// checkVersion holds the checks SetTimetableVersion makes before touching the
+// store. It returns v without the times of day.
+func checkVersion(actor Actor, v TimetableVersion) (TimetableVersion, error) {
+	if !actor.Admin {
+		return v, fmt.Errorf("%w: only admins may change the timetable", ErrForbidden)
+	}
+	if v.Name == "" {
+		return v, fmt.Errorf("%w: timetable version without a name", ErrInvalid)
+	}
+	if v.Name == DefaultVersion && (!v.Published || !v.Start.IsZero() || !v.End.IsZero()) {
+		return v, fmt.Errorf("%w: version %s is always published, on every date", ErrInvalid, v.Name)
 	}
-	defer db.Close()
+	if !v.Start.IsZero() {
+		v.Start = dateOnly(v.Start)
+	}
+	if !v.End.IsZero() {
+		v.End = dateOnly(v.End)
+	}
+	if !v.Start.IsZero() && !v.End.IsZero() && v.End.Before(v.Start) {
+		return v, fmt.Errorf("%w: version %s ends before it starts", ErrInvalid, v.Name)
+	}
+	return v, nil
+}
+
+// Term is a teaching period of the academic calendar, both dates included
+type Term struct {
+	Name  string    `json:"name"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Holiday is a day, or a run of days such as an exam week, without classes
+type Holiday struct {
+	Name  string    `json:"name"`
+	Start time.Time `json:"start"`
+	// End defaults to Start
+	End time.Time `json:"end"`
+}
 
-	day := strings.ToUpper(date.Weekday().String()[:3])
+// DayOrder makes a date follow the timetable of another day, e.g. a
+// Saturday that makes up for a holiday with Monday's classes
+type DayOrder struct {
+	Date time.Time `json:"date"`
+	Day  string    `json:"day"`
+}
 
-	/*
-	   INSERT INTO dynamic SELECT "A104", "2023-06-13", 1,
-	   "cb.en.u4cse20613@cb.students.amrita.edu", "19CSE311" FROM dual WHERE
-	   (SELECT subject_id FROM static WHERE class_id="A104" AND slot_id=1 AND
-	   day="TUE")="FREE";
-	*/
-	stmt, err := db.Prepare(`INSERT INTO dynamic SELECT ?, ?, ?, ?, ? FROM
-    dual WHERE (SELECT subject_id FROM static WHERE class_id = ? AND day = ?
-    AND slot_id = ?)="FREE";`)
+/*
+Calendar is the academic calendar. It decides which day of the static
+timetable a date follows, if any: none on a holiday or outside the terms, the
+Day of its DayOrder if it has one, otherwise its weekday. A calendar without
+terms does not restrict the dates, so that a fresh database works as before.
+*/
+type Calendar struct {
+	Terms     []Term     `json:"terms"`
+	Holidays  []Holiday  `json:"holidays"`
+	DayOrders []DayOrder `json:"dayOrders"`
+}
 
-	if err != nil {
-		log.Println(err)
-		return 0, err
+// Day is the day of the static timetable date follows, or "" when there are
+// no classes on date
+func (c Calendar) Day(date time.Time) string {
+	date = dateOnly(date)
+	within := func(start time.Time, end time.Time) bool {
+		return !date.Before(start) && !date.After(end)
+	}
+	inTerm, holiday := false, false
+	for _, t := range c.Terms {
+		inTerm = inTerm || within(t.Start, t.End)
 	}
-	defer stmt.Close()
-	result, err := stmt.Exec(class, date, slot, faculty, subject, class, day, slot)
-	if err != nil {
-		log.Println(err)
-		return 0, err
+	for _, h := range c.Holidays {
+		holiday = holiday || within(h.Start, h.End)
+	}
+	order := ""
+	for _, o := range c.DayOrders {
+		if o.Date.Equal(date) {
+			order = o.Day
+		}
 	}
-	rowsAffected, _ := result.RowsAffected()
-	return rowsAffected, nil
+	return calendarDay(date, len(c.Terms) > 0, inTerm, holiday, order)
 }
 
-func MultiBooking(class string, date time.Time, startSlot int, endSlot int, faculty string, subject string) (int64, error) {
-	var rowsAffected int64
-	db, err := sql.Open("mysql", cfg.FormatDSN())
-	if err != nil {
-		log.Println(err)
-		return 0, err
+// calendarDay holds the rules of Calendar.Day for the SQL backends, which
+// look the facts up in a single query
+func calendarDay(date time.Time, terms bool, inTerm bool, holiday bool, order string) string {
+	switch {
+	case holiday, terms && !inTerm:
+		return ""
+	case order != "":
+		return order
 	}
-	defer db.Close()
+	return weekday(date)
+}
 
-	day := strings.ToUpper(date.Weekday().String()[:3])
-	stmt, err := db.Prepare(`INSERT INTO dynamic SELECT ?, ?, ?, ?, ? FROM
-    dual WHERE (SELECT subject_id FROM static WHERE class_id = ? AND day = ?
-    AND slot_id = ?)="FREE";`)
-	if err != nil {
-		log.Println(err)
-		return 0, err
+// checkCalendar holds the checks SetCalendar makes before touching the store.
+// It returns c with the times of day dropped and every Holiday.End set.
+func checkCalendar(actor Actor, c Calendar) (Calendar, error) {
+	if !actor.Admin {
+		return c, fmt.Errorf("%w: only admins may change the calendar", ErrForbidden)
+	}
+	// The caller's slices are left alone
+	c.Terms = append([]Term(nil), c.Terms...)
+	c.Holidays = append([]Holiday(nil), c.Holidays...)
+	c.DayOrders = append([]DayOrder(nil), c.DayOrders...)
+	terms := make(map[string]bool)
+	for i, t := range c.Terms {
+		t.Start, t.End = dateOnly(t.Start), dateOnly(t.End)
+		if t.Name == "" || terms[t.Name] {
+			return c, fmt.Errorf("%w: every term needs a name of its own", ErrInvalid)
+		}
+		if t.End.Before(t.Start) {
+			return c, fmt.Errorf("%w: term %s ends before it starts", ErrInvalid, t.Name)
+		}
+		terms[t.Name] = true
+		c.Terms[i] = t
+	}
+	holidays := make(map[time.Time]bool)
+	for i, h := range c.Holidays {
+		h.Start, h.End = dateOnly(h.Start), dateOnly(h.End)
+		if h.End.IsZero() {
+			h.End = h.Start
+		}
+		if h.Name == "" {
+			return c, fmt.Errorf("%w: holiday on %s without a name", ErrInvalid, h.Start.Format(dateLayout))
+		}
+		if h.End.Before(h.Start) {
+			return c, fmt.Errorf("%w: holiday %s ends before it starts", ErrInvalid, h.Name)
+		}
+		if holidays[h.Start] {
+			return c, fmt.Errorf("%w: two holidays start on %s", ErrInvalid, h.Start.Format(dateLayout))
+		}
+		holidays[h.Start] = true
+		c.Holidays[i] = h
+	}
+	orders := make(map[time.Time]bool)
+	for i, o := range c.DayOrders {
+		o.Date = dateOnly(o.Date)
+		if !weekdays[o.Day] {
+			return c, fmt.Errorf("%w: day %q", ErrInvalid, o.Day)
+		}
+		if orders[o.Date] {
+			return c, fmt.Errorf("%w: two day orders for %s", ErrInvalid, o.Date.Format(dateLayout))
+		}
+		orders[o.Date] = true
+		c.DayOrders[i] = o
 	}
-	defer stmt.Close()
-	for slot := startSlot; slot <= endSlot; slot++ {
-		result, err := stmt.Exec(class, date, slot, faculty, subject, class, day, slot)
-		if err != nil {
-			log.Println(err)
-			return rowsAffected, err
+	return c, nil
+}
+
+// Scopes of an APIToken
+const (
+	// ScopeRead may query availability and timetables
+	ScopeRead = "read"
+	// ScopeBook may also book and cancel on behalf of APIToken.Faculty
+	ScopeBook = "book"
+	// ScopeAdmin may do anything an admin can
+	ScopeAdmin = "admin"
+	// ScopeCalendar only reads the calendar feed of APIToken.Faculty. A
+	// faculty member may mint one for themselves.
+	ScopeCalendar = "calendar"
+)
+
+/*
+APIToken is a long lived credential for scripts and kiosks that can't go
+through the interactive login. The secret itself is never stored.
+*/
+type APIToken struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Scope   string `json:"scope"`
+	Faculty string `json:"faculty,omitempty"`
+	// CreatedBy is who minted the token, an admin unless it is a calendar
+	// token
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	// LastUsed is nil for a token that has never been used
+	LastUsed *time.Time `json:"lastUsed,omitempty"`
+}
+
+// checkAPIToken holds the checks CreateAPIToken makes before touching the store
+func checkAPIToken(actor Actor, t APIToken) error {
+	self := t.Scope == ScopeCalendar && actor.ID != "" && actor.ID == t.Faculty
+	if !actor.Admin && !self {
+		return fmt.Errorf("%w: only admins may create API tokens", ErrForbidden)
+	}
+	if t.ID == "" {
+		return fmt.Errorf("%w: API token without an id", ErrInvalid)
+	}
+	switch t.Scope {
+	case ScopeRead, ScopeAdmin:
+	case ScopeBook, ScopeCalendar:
+		if t.Faculty == "" {
+			return fmt.Errorf("%w: a %s token needs a faculty", ErrInvalid, t.Scope)
 		}
-		tmp, _ := result.RowsAffected()
-		rowsAffected += tmp
+	default:
+		return fmt.Errorf("%w: scope %q", ErrInvalid, t.Scope)
 	}
-	return rowsAffected, nil
+	return nil
+}
+
+/*
+Fixture is a complete data set that can be loaded into an empty Store. It is
+how the memory backend is populated and how the conformance tests seed every
+backend with the same data.
+*/
+type Fixture struct {
+	Slots    []Slot          `json:"slots"`
+	Subjects []Subject       `json:"subjects"`
+	Faculty  []Faculty       `json:"faculty"`
+	Static   []StaticEntry   `json:"static"`
+	Bookings []BookingRecord `json:"bookings"`
+	Calendar Calendar        `json:"calendar"`
+	// Versions are added to DefaultVersion, which every Store starts with
+	Versions []TimetableVersion `json:"versions"`
+}
+
+// loader is implemented by every backend so that a Fixture can be loaded
+type loader interface {
+	load(ctx context.Context, f *Fixture) error
+}
+
+// Open connects to the database described by cfg and prepares every query.
+func Open(cfg Config) (Store, error) {
+	switch cfg.Driver {
+	case "", DriverMySQL:
+		return openMySQL(cfg)
+	case DriverSQLite:
+		return openSQLite(cfg)
+	case DriverMemory:
+		return openMemory(cfg)
+	}
+	return nil, fmt.Errorf("db: unknown driver %q", cfg.Driver)
+}
+
+// dateOnly is the date of t at midnight UTC, as kept in a DATE column
+func dateOnly(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func weekday(date time.Time) string {
+	return strings.ToUpper(date.Weekday().String()[:3])
 }
@@ -1,102 +1,26 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
-
-	_ "github.com/go-sql-driver/mysql"
 )
 
 // Using the same conn as prod
 // TODO: Maybe consider using a testing account in the db
 const testDSN = "cora:@/cora_db?parseTime=true"
 
-// setupTestDB creates tables and inserts test data
-func setupTestDB(t *testing.T) *sql.DB {
-	db, err := sql.Open("mysql", testDSN)
-	if err != nil {
-		t.Skip("MySQL test database not available:", err)
-	}
-
-	// Create test tables with exact schema
-	createTables := []string{
-		`CREATE TABLE IF NOT EXISTS slot (
-			id INT,
-			stime TIME NOT NULL, 
-			etime TIME NOT NULL,
-			PRIMARY KEY (id)
-		)`,
-		`CREATE TABLE IF NOT EXISTS subject (
-			id CHAR(8),
-			name VARCHAR(64) NOT NULL, 
-			PRIMARY KEY (id)
-		)`,
-		`CREATE TABLE IF NOT EXISTS faculty (
-			id CHAR(254),
-			name VARCHAR(64) NOT NULL,
-			PRIMARY KEY (id)
-		)`,
-		`CREATE TABLE IF NOT EXISTS static (
-			class_id CHAR(4),
-			day ENUM ("MON", "TUE", "WED", "THU", "FRI"), 
-			slot_id INT, 
-			faculty_id CHAR(254),
-			subject_id CHAR(8),
-			FOREIGN KEY (slot_id) REFERENCES slot (id), 
-			FOREIGN KEY (faculty_id) REFERENCES faculty (id), 
-			FOREIGN KEY (subject_id) REFERENCES subject (id), 
-			PRIMARY KEY (class_id, day, slot_id)
-		)`,
-		`CREATE TABLE IF NOT EXISTS dynamic (
-			class_id CHAR(4),
-			date DATE, 
-			slot_id INT, 
-			faculty_id CHAR(254) NOT NULL, 
-			subject_id CHAR(8) NOT NULL, 
-			FOREIGN KEY (faculty_id) REFERENCES faculty (id), 
-			FOREIGN KEY (slot_id) REFERENCES slot (id), 
-			FOREIGN KEY (subject_id) REFERENCES subject (id), 
-			PRIMARY KEY (class_id, date, slot_id)
-		)`,
-	}
-
-	// Drop and recreate tables to ensure clean state
-	dropTables := []string{
-		"SET FOREIGN_KEY_CHECKS = 0",
-		"DROP TABLE IF EXISTS dynamic",
-		"DROP TABLE IF EXISTS static",
-		"DROP TABLE IF EXISTS faculty",
-		"DROP TABLE IF EXISTS subject",
-		"DROP TABLE IF EXISTS slot",
-		"SET FOREIGN_KEY_CHECKS = 1",
-	}
-
-	for _, query := range dropTables {
-		if _, err := db.Exec(query); err != nil {
-			t.Logf("Warning: Could not drop table: %v", err)
-		}
-	}
-
-	for _, query := range createTables {
-		if _, err := db.Exec(query); err != nil {
-			t.Fatalf("Failed to create table: %v", err)
-		}
-	}
+var ctx = context.Background()
 
-	// Insert test data
-	insertTestData(t, db)
-	return db
-}
-
-func insertTestData(t *testing.T, db *sql.DB) {
-	// Insert slots with time information
-	slotData := []struct {
-		id    int
-		stime string
-		etime string
-	}{
+// testFixture is loaded into every backend before each test
+var testFixture = Fixture{
+	// Slots with time information
+	Slots: []Slot{
 		{1, "08:50", "09:40"},
 		{2, "09:40", "10:30"},
 		{3, "10:40", "11:30"},
@@ -105,21 +29,9 @@ func insertTestData(t *testing.T, db *sql.DB) {
 		{6, "14:30", "15:20"},
 		{7, "15:20", "16:10"},
 		{8, "16:10", "17:00"},
-	}
-
-	for _, slot := range slotData {
-		_, err := db.Exec("INSERT INTO slot (id, stime, etime) VALUES (?, ?, ?)",
-			slot.id, slot.stime, slot.etime)
-		if err != nil {
-			t.Fatalf("Failed to insert slot: %v", err)
-		}
-	}
-
-	// Insert subjects (subset for testing)
-	subjectData := []struct {
-		id   string
-		name string
-	}{
+	},
+	// Subjects (subset for testing)
+	Subjects: []Subject{
 		{"19CSE311", "Computer Security"},
 		{"19CSE312", "Distributed Systems"},
 		{"19CSE313", "Principles of Programming Languages"},
@@ -132,21 +44,9 @@ func insertTestData(t *testing.T, db *sql.DB) {
 		{"19CSE356", "Social Network Analytics"},
 		{"19CSE456", "Neural Networks and Deep Learning"},
 		{"FREE", "Free Period"},
-	}
-
-	for _, subject := range subjectData {
-		_, err := db.Exec("INSERT INTO subject (id, name) VALUES (?, ?)",
-			subject.id, subject.name)
-		if err != nil {
-			t.Fatalf("Failed to insert subject: %v", err)
-		}
-	}
-
-	// Insert faculty (subset for testing)
-	facultyData := []struct {
-		id   string
-		name string
-	}{
+	},
+	// Faculty (subset for testing)
+	Faculty: []Faculty{
 		{"FREE", "No Faculty"},
 		{"s_padmavathi@cb.amrita.edu", "Padmavathi.S"},
 		{"n_harini@cb.amrita.edu", "Dr.Harini.N"},
@@ -157,24 +57,9 @@ func insertTestData(t *testing.T, db *sql.DB) {
 		{"t_gireeshkumar@cb.amrita.edu", "Dr.Gireesh Kumar T"},
 		{"d_bharathi@cb.amrita.edu", "Ms.Bharathi.D"},
 		{"test.faculty@test.com", "Test Faculty"},
-	}
-
-	for _, faculty := range facultyData {
-		_, err := db.Exec("INSERT INTO faculty (id, name) VALUES (?, ?)",
-			faculty.id, faculty.name)
-		if err != nil {
-			t.Fatalf("Failed to insert faculty: %v", err)
-		}
-	}
-
-	// Insert static timetable data (subset focusing on A104 and C203 for testing)
-	staticData := []struct {
-		classID   string
-		day       string
-		slotID    int
-		facultyID string
-		subjectID string
-	}{
+	},
+	// Static timetable data (subset focusing on A104 and C203 for testing)
+	Static: []StaticEntry{
 		// A104 Monday schedule
 		{"A104", "MON", 1, "s_padmavathi@cb.amrita.edu", "19CSE356"},
 		{"A104", "MON", 2, "d_bharathi@cb.amrita.edu", "19CSE312"},
@@ -214,36 +99,135 @@ func insertTestData(t *testing.T, db *sql.DB) {
 		{"C203", "TUE", 6, "FREE", "FREE"},
 		{"C203", "TUE", 7, "FREE", "FREE"},
 		{"C203", "TUE", 8, "FREE", "FREE"},
+	},
+}
+
+/*
+backends lists every Store implementation. Each test below is a conformance
+test: it runs once per backend against the same fixture, so all of them must
+behave identically. A backend whose database is not reachable is skipped.
+*/
+var backends = []struct {
+	name string
+	open func(tb testing.TB) Store
+}{
+	{DriverMemory, openMemoryTest},
+	{DriverSQLite, openSQLiteTest},
+	{DriverMySQL, openMySQLTest},
+}
+
+func forEachBackend(t *testing.T, fn func(t *testing.T, store Store)) {
+	for _, b := range backends {
+		b := b
+		t.Run(b.name, func(t *testing.T) {
+			fn(t, b.open(t))
+		})
 	}
+}
 
-	for _, data := range staticData {
-		_, err := db.Exec(
-			"INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES (?, ?, ?, ?, ?)",
-			data.classID, data.day, data.slotID, data.facultyID, data.subjectID,
-		)
-		if err != nil {
-			t.Fatalf("Failed to insert static data: %v", err)
-		}
+// loadFixture seeds store with testFixture and closes it when the test ends
+func loadFixture(tb testing.TB, store Store) Store {
+	tb.Cleanup(func() { store.Close() })
+	if err := store.(loader).load(ctx, &testFixture); err != nil {
+		tb.Fatalf("Failed to load test data: %v", err)
+	}
+	return store
+}
+
+func openMemoryTest(tb testing.TB) Store {
+	store, err := Open(Config{Driver: DriverMemory})
+	if err != nil {
+		tb.Fatalf("Failed to open store: %v", err)
+	}
+	return loadFixture(tb, store)
+}
+
+// migrateTest brings the database described by cfg up to the latest schema
+func migrateTest(tb testing.TB, cfg Config) {
+	m, err := NewMigrator(cfg)
+	if err != nil {
+		tb.Fatalf("Failed to open database: %v", err)
+	}
+	defer m.Close()
+	if _, err := m.Up(ctx); err != nil {
+		tb.Fatalf("Failed to migrate: %v", err)
 	}
 }
 
-func teardownTestDB(t *testing.T, db *sql.DB) {
-	// Clean up test data
+func sqliteTestConfig(tb testing.TB) Config {
+	return Config{Driver: DriverSQLite, DSN: filepath.Join(tb.TempDir(), "cora.db")}
+}
+
+// openSQLiteTest creates a fresh database file in a temporary directory
+func openSQLiteTest(tb testing.TB) Store {
+	cfg := sqliteTestConfig(tb)
+	migrateTest(tb, cfg)
+	store, err := Open(cfg)
+	if err != nil {
+		tb.Fatalf("Failed to open store: %v", err)
+	}
+	return loadFixture(tb, store)
+}
+
+/*
+mysqlTestConfig drops every table in the test database, skipping the test if
+MySQL is not reachable
+*/
+func mysqlTestConfig(tb testing.TB) Config {
+	db, err := sql.Open("mysql", testDSN)
+	if err != nil {
+		tb.Skip("MySQL test database not available:", err)
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		tb.Skip("MySQL test database not available:", err)
+	}
+
+	// Drop all tables to ensure clean state
 	dropTables := []string{
 		"SET FOREIGN_KEY_CHECKS = 0",
-		"DELETE FROM dynamic",
-		"DELETE FROM static",
-		"DELETE FROM faculty",
-		"DELETE FROM subject",
-		"DELETE FROM slot",
+		"DROP TABLE IF EXISTS api_token",
+		"DROP TABLE IF EXISTS series_skip",
+		"DROP TABLE IF EXISTS booking_series",
+		"DROP TABLE IF EXISTS day_order",
+		"DROP TABLE IF EXISTS holiday",
+		"DROP TABLE IF EXISTS term",
+		"DROP TABLE IF EXISTS dynamic",
+		"DROP TABLE IF EXISTS static",
+		"DROP TABLE IF EXISTS timetable_version",
+		"DROP TABLE IF EXISTS faculty",
+		"DROP TABLE IF EXISTS subject",
+		"DROP TABLE IF EXISTS slot",
+		"DROP TABLE IF EXISTS schema_version",
 		"SET FOREIGN_KEY_CHECKS = 1",
 	}
+
+	// SET only applies to the session, so stay on one connection
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		tb.Fatalf("Failed to get connection: %v", err)
+	}
+	defer conn.Close()
 	for _, query := range dropTables {
-		if _, err := db.Exec(query); err != nil {
-			t.Logf("Warning: Could not clean table: %v", err)
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			tb.Logf("Warning: Could not drop table: %v", err)
 		}
 	}
-	db.Close()
+	return Config{DSN: testDSN}
+}
+
+/*
+openMySQLTest recreates the tables in the test database. The Store has to be
+opened after the tables exist as it prepares its statements against them.
+*/
+func openMySQLTest(tb testing.TB) Store {
+	cfg := mysqlTestConfig(tb)
+	migrateTest(tb, cfg)
+	store, err := Open(cfg)
+	if err != nil {
+		tb.Fatalf("Failed to open store: %v", err)
+	}
+	return loadFixture(tb, store)
 }
 
 func TestMain(m *testing.M) {
@@ -252,470 +236,1438 @@ func TestMain(m *testing.M) {
 }
 
 func TestGetFreeClass(t *testing.T) {
-	db := setupTestDB(t)
-	defer teardownTestDB(t, db)
+	forEachBackend(t, func(t *testing.T, store Store) {
+		// Test date: Monday
+		testDate := time.Date(2023, 6, 12, 0, 0, 0, 0, time.UTC) // Monday
+
+		tests := []struct {
+			name     string
+			slot     int
+			date     time.Time
+			expected []string
+		}{
+			{
+				name:     "Get free classes for slot 4 on Monday",
+				slot:     4,
+				date:     testDate,
+				expected: []string{"A104"}, // A104 has FREE in slot 4, C203 has a class
+			},
+			{
+				name:     "Get free classes for slot 5 on Monday",
+				slot:     5,
+				date:     testDate,
+				expected: []string{"A104", "C203"}, // Both have FREE in slot 5
+			},
+			{
+				name:     "Get free classes for slot 8 on Monday",
+				slot:     8,
+				date:     testDate,
+				expected: []string{"A104", "C203"}, // Both have FREE in slot 8
+			},
+		}
 
-	// Test date: Monday
-	testDate := time.Date(2023, 6, 12, 0, 0, 0, 0, time.UTC) // Monday
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				result, err := store.GetFreeClass(ctx, tt.slot, tt.date)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
 
-	tests := []struct {
-		name     string
-		slot     int
-		date     time.Time
-		expected []string
-	}{
-		{
-			name:     "Get free classes for slot 4 on Monday",
-			slot:     4,
-			date:     testDate,
-			expected: []string{"A104"}, // A104 has FREE in slot 4, C203 has a class
-		},
-		{
-			name:     "Get free classes for slot 5 on Monday",
-			slot:     5,
-			date:     testDate,
-			expected: []string{"A104", "C203"}, // Both have FREE in slot 5
-		},
-		{
-			name:     "Get free classes for slot 8 on Monday",
-			slot:     8,
-			date:     testDate,
-			expected: []string{"A104", "C203"}, // Both have FREE in slot 8
-		},
-	}
+				if len(result) != len(tt.expected) {
+					t.Errorf("Expected %d classes, got %d. Expected: %v, Got: %v",
+						len(tt.expected), len(result), tt.expected, result)
+				}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := GetFreeClass(tt.slot, tt.date)
+				// Check if expected classes are present (order might vary)
+				for _, expected := range tt.expected {
+					found := false
+					for _, actual := range result {
+						if actual == expected {
+							found = true
+							break
+						}
+					}
+					if !found {
+						t.Errorf("Expected class %s not found in result %v", expected, result)
+					}
+				}
+			})
+		}
+	})
+}
 
-			if len(result) != len(tt.expected) {
-				t.Errorf("Expected %d classes, got %d. Expected: %v, Got: %v",
-					len(tt.expected), len(result), tt.expected, result)
-			}
+func TestGetFreeSlot(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		testDate := time.Date(2023, 6, 12, 0, 0, 0, 0, time.UTC) // Monday
+
+		tests := []struct {
+			name     string
+			class    string
+			date     time.Time
+			expected []int
+		}{
+			{
+				name:     "Get free slots for A104 on Monday",
+				class:    "A104",
+				date:     testDate,
+				expected: []int{4, 5, 8}, // slots 4, 5, 8 are FREE
+			},
+			{
+				name:     "Get free slots for C203 on Monday",
+				class:    "C203",
+				date:     testDate,
+				expected: []int{5, 8}, // slots 5, 8 are FREE
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				result, err := store.GetFreeSlot(ctx, tt.class, tt.date)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				if len(result) != len(tt.expected) {
+					t.Errorf("Expected %d slots, got %d: %v", len(tt.expected), len(result), result)
+				}
 
-			// Check if expected classes are present (order might vary)
-			for _, expected := range tt.expected {
-				found := false
-				for _, actual := range result {
-					if actual == expected {
-						found = true
-						break
+				for _, expected := range tt.expected {
+					found := false
+					for _, actual := range result {
+						if actual == expected {
+							found = true
+							break
+						}
+					}
+					if !found {
+						t.Errorf("Expected slot %d not found in result %v", expected, result)
 					}
 				}
-				if !found {
-					t.Errorf("Expected class %s not found in result %v", expected, result)
+			})
+		}
+	})
+}
+
+func TestMultiFreeSlot(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		testDate := time.Date(2023, 6, 13, 0, 0, 0, 0, time.UTC) // Tuesday
+
+		tests := []struct {
+			name      string
+			startSlot int
+			endSlot   int
+			date      time.Time
+			expected  []string
+		}{
+			{
+				name:      "Get classes free for slots 5-8 on Tuesday",
+				startSlot: 5,
+				endSlot:   8,
+				date:      testDate,
+				expected:  []string{"A104", "C203"}, // Both have slots 5-8 free on Tuesday
+			},
+			{
+				name:      "Get classes free for slot 1 on Tuesday",
+				startSlot: 1,
+				endSlot:   1,
+				date:      testDate,
+				expected:  []string{"A104"}, // Only A104 has slot 1 free on Tuesday
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				result, err := store.MultiFreeSlot(ctx, tt.startSlot, tt.endSlot, tt.date)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
 				}
+
+				if len(result) != len(tt.expected) {
+					t.Errorf("Expected %d classes, got %d: %v", len(tt.expected), len(result), result)
+				}
+
+				for _, expected := range tt.expected {
+					found := false
+					for _, actual := range result {
+						if actual == expected {
+							found = true
+							break
+						}
+					}
+					if !found {
+						t.Errorf("Expected class %s not found in result %v", expected, result)
+					}
+				}
+			})
+		}
+	})
+}
+
+func TestGetTimetableByDay(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		testDate := time.Date(2023, 6, 12, 0, 0, 0, 0, time.UTC) // Monday
+
+		tests := []struct {
+			name          string
+			class         string
+			date          time.Time
+			expectedCount int
+		}{
+			{
+				name:          "Get timetable for A104 on Monday",
+				class:         "A104",
+				date:          testDate,
+				expectedCount: 8, // Should return 8 subjects (one for each slot)
+			},
+			{
+				name:          "Get timetable for C203 on Monday",
+				class:         "C203",
+				date:          testDate,
+				expectedCount: 8, // Should return 8 subjects (one for each slot)
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				result, err := store.GetTimetableByDay(ctx, tt.class, tt.date)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				if len(result) != tt.expectedCount {
+					t.Errorf("Expected %d subjects, got %d: %v", tt.expectedCount, len(result), result)
+				}
+				for i, e := range result {
+					if e.Slot != i+1 {
+						t.Errorf("Expected slot %d at position %d, got %d", i+1, i, e.Slot)
+					}
+				}
+			})
+		}
+
+		t.Run("Entries", func(t *testing.T) {
+			result, err := store.GetTimetableByDay(ctx, "A104", testDate)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			want := TimetableEntry{
+				Room:        "A104",
+				Slot:        2,
+				Start:       "09:40",
+				End:         "10:30",
+				Subject:     "19CSE312",
+				SubjectName: "Distributed Systems",
+				Faculty:     "d_bharathi@cb.amrita.edu",
+				Source:      SourceStatic,
+			}
+			if len(result) < 2 || result[1] != want {
+				t.Errorf("Expected %+v, got %+v", want, result)
 			}
 		})
-	}
+
+		t.Run("Booking takes precedence", func(t *testing.T) {
+			_, err := store.Booking(ctx, "A104", testDate, 5, "test.faculty@test.com", "19CSE446")
+			if err != nil {
+				t.Fatalf("Failed to book: %v", err)
+			}
+			result, err := store.GetTimetableByDay(ctx, "A104", testDate)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			want := TimetableEntry{
+				Room:        "A104",
+				Slot:        5,
+				Start:       "13:40",
+				End:         "14:30",
+				Subject:     "19CSE446",
+				SubjectName: "Internet of Things",
+				Faculty:     "test.faculty@test.com",
+				Source:      SourceBooking,
+			}
+			if len(result) != 8 || result[4] != want {
+				t.Errorf("Expected %+v in slot 5, got %+v", want, result)
+			}
+		})
+	})
 }
 
-func TestGetFreeSlot(t *testing.T) {
-	db := setupTestDB(t)
-	defer teardownTestDB(t, db)
+func TestGetTimetableRange(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		monday := time.Date(2023, 6, 12, 0, 0, 0, 0, time.UTC)
+		tuesday := monday.AddDate(0, 0, 1)
+		_, err := store.Booking(ctx, "A104", tuesday, 1, "test.faculty@test.com", "19CSE446")
+		if err != nil {
+			t.Fatalf("Failed to book: %v", err)
+		}
 
-	testDate := time.Date(2023, 6, 12, 0, 0, 0, 0, time.UTC) // Monday
+		days, err := store.GetTimetableRange(ctx, "A104", monday, monday.AddDate(0, 0, 7))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(days) != 8 {
+			t.Fatalf("Expected 8 days, got %d", len(days))
+		}
+		for i, day := range days {
+			if !day.Date.Equal(monday.AddDate(0, 0, i)) {
+				t.Errorf("Expected %s as day %d, got %s", monday.AddDate(0, 0, i), i, day.Date)
+			}
+			// Every day must look the same as when asked for on its own
+			want, err := store.GetTimetableByDay(ctx, "A104", day.Date)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(want) != len(day.Entries) || len(want) > 0 && !reflect.DeepEqual(want, day.Entries) {
+				t.Errorf("%s differs from GetTimetableByDay:\n%+v\n%+v", day.Date.Format(dateLayout), day.Entries, want)
+			}
+		}
+		if len(days[0].Entries) != 8 || len(days[2].Entries) != 0 || len(days[7].Entries) != 8 {
+			t.Errorf("Expected the static timetable on Mondays only, got %+v", days)
+		}
+		if e := days[1].Entries[0]; e.Source != SourceBooking || e.Subject != "19CSE446" {
+			t.Errorf("Expected the booking in slot 1 on Tuesday, got %+v", e)
+		}
 
-	tests := []struct {
-		name     string
-		class    string
-		date     time.Time
-		expected []int
-	}{
-		{
-			name:     "Get free slots for A104 on Monday",
-			class:    "A104",
-			date:     testDate,
-			expected: []int{4, 5, 8}, // slots 4, 5, 8 are FREE
-		},
-		{
-			name:     "Get free slots for C203 on Monday",
-			class:    "C203",
-			date:     testDate,
-			expected: []int{5, 8}, // slots 5, 8 are FREE
-		},
-	}
+		for name, to := range map[string]time.Time{
+			"Reversed": monday.AddDate(0, 0, -1),
+			"Too long": monday.AddDate(0, 0, MaxRangeDays),
+		} {
+			if _, err := store.GetTimetableRange(ctx, "A104", monday, to); !errors.Is(err, ErrInvalid) {
+				t.Errorf("%s: expected ErrInvalid, got %v", name, err)
+			}
+		}
+	})
+}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := GetFreeSlot(tt.class, tt.date)
+func TestGetFacultySchedule(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		monday := time.Date(2023, 6, 12, 0, 0, 0, 0, time.UTC)
+		tuesday := monday.AddDate(0, 0, 1)
+		const faculty = "d_bharathi@cb.amrita.edu"
+		admin := Actor{ID: "admin@cb.amrita.edu", Admin: true}
+
+		// Somebody else's booking in slot 8, which then becomes one of
+		// the faculty's classes, takes its place on that date
+		if _, err := store.Booking(ctx, "A104", monday, 8, "test.faculty@test.com", "19CSE446"); err != nil {
+			t.Fatalf("Failed to book: %v", err)
+		}
+		if err := store.SetStatic(ctx, admin, StaticEntry{"A104", "MON", 8, faculty, "19CSE312"}); err != nil {
+			t.Fatalf("Failed to set the timetable: %v", err)
+		}
+		if _, err := store.Booking(ctx, "C203", tuesday, 5, faculty, "19CSE313"); err != nil {
+			t.Fatalf("Failed to book: %v", err)
+		}
 
-			if len(result) != len(tt.expected) {
-				t.Errorf("Expected %d slots, got %d: %v", len(tt.expected), len(result), result)
+		days, err := store.GetFacultySchedule(ctx, faculty, monday, monday.AddDate(0, 0, 7))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(days) != 8 {
+			t.Fatalf("Expected 8 days, got %d", len(days))
+		}
+		slots := func(entries []TimetableEntry) []int {
+			var slots []int
+			for _, e := range entries {
+				if e.Faculty != faculty {
+					t.Errorf("Entry of somebody else: %+v", e)
+				}
+				slots = append(slots, e.Slot)
 			}
+			return slots
+		}
+		if got := slots(days[0].Entries); !reflect.DeepEqual(got, []int{2, 3, 6, 7}) {
+			t.Errorf("Expected slots 2, 3, 6 and 7 on Monday, got %v", got)
+		}
+		if got := slots(days[7].Entries); !reflect.DeepEqual(got, []int{2, 3, 6, 7, 8}) {
+			t.Errorf("Expected slots 2, 3, 6, 7 and 8 the next Monday, got %v", got)
+		}
+		want := TimetableEntry{
+			Room:        "C203",
+			Slot:        5,
+			Start:       "13:40",
+			End:         "14:30",
+			Subject:     "19CSE313",
+			SubjectName: "Principles of Programming Languages",
+			Faculty:     faculty,
+			Source:      SourceBooking,
+		}
+		if len(days[1].Entries) != 1 || days[1].Entries[0] != want {
+			t.Errorf("Expected %+v on Tuesday, got %+v", want, days[1].Entries)
+		}
+		if len(days[2].Entries) != 0 {
+			t.Errorf("Expected nothing on Wednesday, got %+v", days[2].Entries)
+		}
+	})
+}
 
-			for _, expected := range tt.expected {
-				found := false
-				for _, actual := range result {
-					if actual == expected {
-						found = true
-						break
-					}
-				}
-				if !found {
-					t.Errorf("Expected slot %d not found in result %v", expected, result)
+func TestGetAllSlot(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		result, err := store.GetAllSlot(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := 8 // We inserted 8 slots
+
+		if len(result) != expected {
+			t.Errorf("Expected %d slots, got %d", expected, len(result))
+		}
+
+		// Check if all slots 1-8 are present
+		for i := 1; i <= 8; i++ {
+			found := false
+			for _, slot := range result {
+				if slot == i {
+					found = true
+					break
 				}
 			}
-		})
-	}
+			if !found {
+				t.Errorf("Expected slot %d not found in result", i)
+			}
+		}
+	})
 }
 
-func TestMultiFreeSlot(t *testing.T) {
-	db := setupTestDB(t)
-	defer teardownTestDB(t, db)
+func TestGetAllClass(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		result, err := store.GetAllClass(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expectedClasses := []string{"A104", "C203"}
 
-	testDate := time.Date(2023, 6, 13, 0, 0, 0, 0, time.UTC) // Tuesday
+		if len(result) != len(expectedClasses) {
+			t.Errorf("Expected %d classes, got %d", len(expectedClasses), len(result))
+		}
 
-	tests := []struct {
-		name      string
-		startSlot int
-		endSlot   int
-		date      time.Time
-		expected  []string
-	}{
-		{
-			name:      "Get classes free for slots 5-8 on Tuesday",
-			startSlot: 5,
-			endSlot:   8,
-			date:      testDate,
-			expected:  []string{"A104", "C203"}, // Both have slots 5-8 free on Tuesday
-		},
-		{
-			name:      "Get classes free for slot 1 on Tuesday",
-			startSlot: 1,
-			endSlot:   1,
-			date:      testDate,
-			expected:  []string{"A104"}, // Only A104 has slot 1 free on Tuesday
-		},
-	}
+		for _, expected := range expectedClasses {
+			found := false
+			for _, actual := range result {
+				if actual == expected {
+					found = true
+					break
+				}
+			}
+			if !found {
+				t.Errorf("Expected class %s not found in result %v", expected, result)
+			}
+		}
+	})
+}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := MultiFreeSlot(tt.startSlot, tt.endSlot, tt.date)
+func TestGetAllSubject(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		result, err := store.GetAllSubject(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		// Should return all subjects except "FREE"
+		expectedSubjects := []string{
+			"19CSE311", "19CSE312", "19CSE313", "19CSE314", "19CSE332",
+			"19CSE434", "19CSE352", "19CSE446", "19CSE435", "19CSE356", "19CSE456",
+		}
+
+		if len(result) != len(expectedSubjects) {
+			t.Errorf("Expected %d subjects, got %d", len(expectedSubjects), len(result))
+		}
 
-			if len(result) != len(tt.expected) {
-				t.Errorf("Expected %d classes, got %d: %v", len(tt.expected), len(result), result)
+		// Ensure "FREE" is not included
+		for _, subject := range result {
+			if subject == "FREE" {
+				t.Error("FREE subject should not be included in GetAllSubject result")
 			}
+		}
+	})
+}
 
-			for _, expected := range tt.expected {
-				found := false
-				for _, actual := range result {
-					if actual == expected {
-						found = true
-						break
-					}
+func TestBooking(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		testDate := time.Date(2023, 6, 12, 0, 0, 0, 0, time.UTC) // Monday
+
+		tests := []struct {
+			name          string
+			class         string
+			date          time.Time
+			slot          int
+			faculty       string
+			subject       string
+			expectedRows  int64
+			shouldSucceed bool
+		}{
+			{
+				name:          "Book free slot successfully",
+				class:         "A104",
+				date:          testDate,
+				slot:          4, // This is FREE in our test data for A104 Monday
+				faculty:       "test.faculty@test.com",
+				subject:       "19CSE311",
+				expectedRows:  1,
+				shouldSucceed: true,
+			},
+			{
+				name:          "Try to book occupied slot",
+				class:         "A104",
+				date:          testDate,
+				slot:          2, // This is occupied in our test data
+				faculty:       "test.faculty@test.com",
+				subject:       "19CSE312",
+				expectedRows:  0,
+				shouldSucceed: true, // Function succeeds but no rows affected
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				rowsAffected, err := store.Booking(ctx, tt.class, tt.date, tt.slot, tt.faculty, tt.subject)
+
+				if tt.shouldSucceed && err != nil {
+					t.Errorf("Expected booking to succeed, got error: %v", err)
 				}
-				if !found {
-					t.Errorf("Expected class %s not found in result %v", expected, result)
+
+				if rowsAffected != tt.expectedRows {
+					t.Errorf("Expected %d rows affected, got %d", tt.expectedRows, rowsAffected)
 				}
-			}
-		})
-	}
+			})
+		}
+	})
 }
 
-func TestGetTimetableByDay(t *testing.T) {
-	db := setupTestDB(t)
-	defer teardownTestDB(t, db)
+func TestBookingConflict(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		testDate := time.Date(2023, 6, 12, 0, 0, 0, 0, time.UTC) // Monday
 
-	testDate := time.Date(2023, 6, 12, 0, 0, 0, 0, time.UTC) // Monday
+		_, err := store.Booking(ctx, "A104", testDate, 4, "test.faculty@test.com", "19CSE311")
+		if err != nil {
+			t.Fatalf("Failed to create test booking: %v", err)
+		}
 
-	tests := []struct {
-		name          string
-		class         string
-		date          time.Time
-		expectedCount int
-	}{
-		{
-			name:          "Get timetable for A104 on Monday",
-			class:         "A104",
-			date:          testDate,
-			expectedCount: 8, // Should return 8 subjects (one for each slot)
-		},
-		{
-			name:          "Get timetable for C203 on Monday",
-			class:         "C203",
-			date:          testDate,
-			expectedCount: 8, // Should return 8 subjects (one for each slot)
-		},
-	}
+		// Booking the same room and slot again must be reported as a conflict
+		_, err = store.Booking(ctx, "A104", testDate, 4, "test.faculty@test.com", "19CSE312")
+		if !errors.Is(err, ErrConflict) {
+			t.Errorf("Expected ErrConflict, got %v", err)
+		}
+	})
+}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := GetTimetableByDay(tt.class, tt.date)
+func TestCancelledContext(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
 
-			if len(result) != tt.expectedCount {
-				t.Errorf("Expected %d subjects, got %d: %v", tt.expectedCount, len(result), result)
-			}
-		})
-	}
+		_, err := store.GetAllSlot(cancelled)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
 }
 
-func TestGetAllSlot(t *testing.T) {
-	db := setupTestDB(t)
-	defer teardownTestDB(t, db)
+func TestCancelBooking(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		testDate := time.Date(2023, 6, 12, 0, 0, 0, 0, time.UTC)
+
+		// First create a booking
+		_, err := store.Booking(ctx, "A104", testDate, 4, "test.faculty@test.com", "19CSE311")
+		if err != nil {
+			t.Fatalf("Failed to create test booking: %v", err)
+		}
 
-	result := GetAllSlot()
-	expected := 8 // We inserted 8 slots
+		// Somebody else may not cancel it
+		err = store.CancelBooking(ctx, Actor{ID: "n_harini@cb.amrita.edu"}, "A104", testDate, 4)
+		if !errors.Is(err, ErrForbidden) {
+			t.Errorf("Expected ErrForbidden, got %v", err)
+		}
 
-	if len(result) != expected {
-		t.Errorf("Expected %d slots, got %d", expected, len(result))
-	}
+		// Now test canceling it
+		err = store.CancelBooking(ctx, Actor{ID: "test.faculty@test.com"}, "A104", testDate, 4)
+		if err != nil {
+			t.Errorf("Failed to cancel booking: %v", err)
+		}
 
-	// Check if all slots 1-8 are present
-	for i := 1; i <= 8; i++ {
-		found := false
-		for _, slot := range result {
-			if slot == i {
-				found = true
-				break
+		// Test canceling non-existent booking
+		err = store.CancelBooking(ctx, Actor{ID: "test.faculty@test.com"}, "A104", testDate, 4)
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+
+		// An admin may cancel anybody's booking
+		_, err = store.Booking(ctx, "A104", testDate, 4, "test.faculty@test.com", "19CSE311")
+		if err != nil {
+			t.Fatalf("Failed to create test booking: %v", err)
+		}
+		err = store.CancelBooking(ctx, Actor{ID: "admin@test.com", Admin: true}, "A104", testDate, 4)
+		if err != nil {
+			t.Errorf("Admin failed to cancel booking: %v", err)
+		}
+	})
+}
+
+func TestSetStatic(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		monday := time.Date(2023, 6, 12, 0, 0, 0, 0, time.UTC)
+		admin := Actor{ID: "admin@test.com", Admin: true}
+		class := StaticEntry{"A104", "MON", 4, "test.faculty@test.com", "19CSE311"}
+		free := StaticEntry{"A104", "MON", 4, "FREE", "FREE"}
+		isFree := func() bool {
+			slots, err := store.GetFreeSlot(ctx, "A104", monday)
+			if err != nil {
+				t.Fatalf("GetFreeSlot failed: %v", err)
 			}
+			for _, slot := range slots {
+				if slot == 4 {
+					return true
+				}
+			}
+			return false
 		}
-		if !found {
-			t.Errorf("Expected slot %d not found in result", i)
+
+		err := store.SetStatic(ctx, Actor{ID: "test.faculty@test.com"}, class)
+		if !errors.Is(err, ErrForbidden) {
+			t.Errorf("Expected ErrForbidden, got %v", err)
 		}
-	}
+		if err := store.SetStatic(ctx, admin, class); err != nil {
+			t.Fatalf("Admin failed to set static entry: %v", err)
+		}
+		if isFree() {
+			t.Error("Slot 4 is still free after scheduling a class")
+		}
+
+		// A bad reference leaves the timetable as it was
+		err = store.SetStatic(ctx, admin, StaticEntry{"A104", "MON", 4, "FREE", "NOSUCH"})
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+		if isFree() {
+			t.Error("Failed SetStatic changed the timetable")
+		}
+		err = store.SetStatic(ctx, admin, StaticEntry{"A104", "SUN", 4, "FREE", "FREE"})
+		if !errors.Is(err, ErrInvalid) {
+			t.Errorf("Expected ErrInvalid for SUN, got %v", err)
+		}
+
+		if err := store.SetStatic(ctx, admin, free); err != nil {
+			t.Fatalf("Admin failed to free slot: %v", err)
+		}
+		if !isFree() {
+			t.Error("Slot 4 is not free after freeing it")
+		}
+	})
 }
 
-func TestGetAllClass(t *testing.T) {
-	db := setupTestDB(t)
-	defer teardownTestDB(t, db)
+func TestAPITokens(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		admin := Actor{ID: "admin@test.com", Admin: true}
+		created := time.Date(2023, 6, 12, 9, 30, 0, 0, time.UTC)
+		kiosk := APIToken{ID: "kiosk", Name: "Hallway kiosk", Scope: ScopeRead, CreatedBy: admin.ID, CreatedAt: created}
+		script := APIToken{ID: "script", Name: "Lab script", Scope: ScopeBook, Faculty: "test.faculty@test.com",
+			CreatedBy: admin.ID, CreatedAt: created.Add(time.Minute)}
+
+		err := store.CreateAPIToken(ctx, Actor{ID: "test.faculty@test.com"}, kiosk, "hash-kiosk")
+		if !errors.Is(err, ErrForbidden) {
+			t.Errorf("Expected ErrForbidden, got %v", err)
+		}
+		if err := store.CreateAPIToken(ctx, admin, kiosk, "hash-kiosk"); err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+		if err := store.CreateAPIToken(ctx, admin, script, "hash-script"); err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+		if err := store.CreateAPIToken(ctx, admin, kiosk, "hash-other"); !errors.Is(err, ErrConflict) {
+			t.Errorf("Expected ErrConflict for a duplicate id, got %v", err)
+		}
+		bad := script
+		bad.ID, bad.Faculty = "bad", "nobody@test.com"
+		if err := store.CreateAPIToken(ctx, admin, bad, "hash-bad"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for an unknown faculty, got %v", err)
+		}
+		bad.Faculty = ""
+		if err := store.CreateAPIToken(ctx, admin, bad, "hash-bad"); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Expected ErrInvalid for a book token without faculty, got %v", err)
+		}
+
+		// Faculty may only mint calendar tokens, and only for themselves
+		feed := APIToken{ID: "feed", Name: "Calendar", Scope: ScopeCalendar, Faculty: "test.faculty@test.com",
+			CreatedBy: "test.faculty@test.com", CreatedAt: created.Add(2 * time.Minute)}
+		if err := store.CreateAPIToken(ctx, Actor{ID: "d_bharathi@cb.amrita.edu"}, feed, "hash-feed"); !errors.Is(err, ErrForbidden) {
+			t.Errorf("Expected ErrForbidden for somebody else's calendar token, got %v", err)
+		}
+		if err := store.CreateAPIToken(ctx, Actor{ID: "test.faculty@test.com"}, feed, "hash-feed"); err != nil {
+			t.Fatalf("Failed to create calendar token: %v", err)
+		}
+		if err := store.RevokeAPIToken(ctx, admin, "feed"); err != nil {
+			t.Fatalf("Failed to revoke token: %v", err)
+		}
 
-	result := GetAllClass()
-	expectedClasses := []string{"A104", "C203"}
+		used := created.Add(time.Hour)
+		got, err := store.UseAPIToken(ctx, "hash-script", used)
+		if err != nil {
+			t.Fatalf("Failed to use token: %v", err)
+		}
+		if got.ID != "script" || got.Scope != ScopeBook || got.Faculty != script.Faculty {
+			t.Errorf("Unexpected token %+v", got)
+		}
+		if _, err := store.UseAPIToken(ctx, "hash-unknown", used); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
 
-	if len(result) != len(expectedClasses) {
-		t.Errorf("Expected %d classes, got %d", len(expectedClasses), len(result))
-	}
+		tokens, err := store.GetAPITokens(ctx, admin)
+		if err != nil {
+			t.Fatalf("Failed to list tokens: %v", err)
+		}
+		if len(tokens) != 2 || tokens[0].ID != "kiosk" || tokens[1].ID != "script" {
+			t.Fatalf("Unexpected tokens %+v", tokens)
+		}
+		if tokens[0].LastUsed != nil {
+			t.Errorf("Unused token has a last use %v", tokens[0].LastUsed)
+		}
+		if tokens[1].LastUsed == nil || !tokens[1].LastUsed.Equal(used) {
+			t.Errorf("Expected last use %v, got %v", used, tokens[1].LastUsed)
+		}
+		if !tokens[0].CreatedAt.Equal(created) || tokens[0].Faculty != "" {
+			t.Errorf("Unexpected token %+v", tokens[0])
+		}
+		if _, err := store.GetAPITokens(ctx, Actor{ID: "test.faculty@test.com"}); !errors.Is(err, ErrForbidden) {
+			t.Errorf("Expected ErrForbidden, got %v", err)
+		}
+
+		if err := store.RevokeAPIToken(ctx, admin, "script"); err != nil {
+			t.Fatalf("Failed to revoke token: %v", err)
+		}
+		if _, err := store.UseAPIToken(ctx, "hash-script", used); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Revoked token still usable: %v", err)
+		}
+		if err := store.RevokeAPIToken(ctx, admin, "script"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+}
 
-	for _, expected := range expectedClasses {
-		found := false
-		for _, actual := range result {
-			if actual == expected {
-				found = true
-				break
+func TestGetBooking(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		testDate := time.Date(2023, 6, 12, 0, 0, 0, 0, time.UTC)
+		faculty := "test.faculty@test.com"
+
+		// Create some test bookings
+		_, err := store.Booking(ctx, "A104", testDate, 4, faculty, "19CSE311")
+		if err != nil {
+			t.Fatalf("Failed to create test booking: %v", err)
+		}
+
+		result, err := store.GetBooking(ctx, faculty)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if len(result) != 1 {
+			t.Errorf("Expected 1 booking, got %d", len(result))
+		}
+
+		if len(result) > 0 {
+			booking := result[0]
+			if booking.Class != "A104" {
+				t.Errorf("Expected class A104, got %s", booking.Class)
+			}
+			if booking.Faculty != faculty {
+				t.Errorf("Expected faculty %s, got %s", faculty, booking.Faculty)
 			}
+			if booking.Subject != "19CSE311" {
+				t.Errorf("Expected subject 19CSE311, got %s", booking.Subject)
+			}
+			if booking.Slot != 4 {
+				t.Errorf("Expected slot 4, got %d", booking.Slot)
+			}
+			if !booking.Date.Equal(testDate) {
+				t.Errorf("Expected date %v, got %v", testDate, booking.Date)
+			}
+		}
+
+		// Bookings come by date, room and slot, whatever order they were
+		// made in
+		earlier := testDate.AddDate(0, 0, -7)
+		if _, err := store.Booking(ctx, "A104", testDate, 8, faculty, "19CSE311"); err != nil {
+			t.Fatalf("Failed to create test booking: %v", err)
 		}
-		if !found {
-			t.Errorf("Expected class %s not found in result %v", expected, result)
+		if _, err := store.Booking(ctx, "A104", earlier, 5, faculty, "19CSE311"); err != nil {
+			t.Fatalf("Failed to create test booking: %v", err)
 		}
-	}
+		result, err = store.GetBooking(ctx, faculty)
+		if err != nil || len(result) != 3 {
+			t.Fatalf("Expected 3 bookings, got %+v, %v", result, err)
+		}
+		if !result[0].Date.Equal(earlier) || result[1].Slot != 4 || result[2].Slot != 8 {
+			t.Errorf("Bookings out of order: %+v", result)
+		}
+	})
 }
 
-func TestGetAllSubject(t *testing.T) {
-	db := setupTestDB(t)
-	defer teardownTestDB(t, db)
-
-	result := GetAllSubject()
-	// Should return all subjects except "FREE"
-	expectedSubjects := []string{
-		"19CSE311", "19CSE312", "19CSE313", "19CSE314", "19CSE332",
-		"19CSE434", "19CSE352", "19CSE446", "19CSE435", "19CSE356", "19CSE456",
-	}
+func TestMultiBooking(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		testDate := time.Date(2023, 6, 13, 0, 0, 0, 0, time.UTC) // Tuesday
+
+		tests := []struct {
+			name         string
+			class        string
+			date         time.Time
+			startSlot    int
+			endSlot      int
+			faculty      string
+			subject      string
+			expectedRows int64
+			conflicts    []int
+		}{
+			{
+				name:         "Book multiple free slots",
+				class:        "A104",
+				date:         testDate,
+				startSlot:    5,
+				endSlot:      8, // Slots 5-8 are FREE for A104 on Tuesday
+				faculty:      "test.faculty@test.com",
+				subject:      "19CSE312",
+				expectedRows: 4,
+			},
+			{
+				name:         "Book range with some occupied slots",
+				class:        "C203",
+				date:         testDate,
+				startSlot:    4,
+				endSlot:      6, // Slot 4 is occupied, slots 5-6 are FREE
+				faculty:      "n_harini@cb.amrita.edu",
+				subject:      "19CSE313",
+				expectedRows: 0, // All or nothing, so slots 5-6 stay free
+				conflicts:    []int{4},
+			},
+			{
+				name:         "Book range overlapping an earlier booking",
+				class:        "A104",
+				date:         testDate,
+				startSlot:    3,
+				endSlot:      6, // Slots 5-6 were booked by the first case
+				faculty:      "test.faculty@test.com",
+				subject:      "19CSE313",
+				expectedRows: 0,
+				conflicts:    []int{5, 6},
+			},
+		}
 
-	if len(result) != len(expectedSubjects) {
-		t.Errorf("Expected %d subjects, got %d", len(expectedSubjects), len(result))
-	}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				rowsAffected, err := store.MultiBooking(ctx, tt.class, tt.date, tt.startSlot, tt.endSlot, tt.faculty, tt.subject)
+
+				if tt.conflicts == nil && err != nil {
+					t.Errorf("MultiBooking failed: %v", err)
+				}
+				if tt.conflicts != nil {
+					var conflictErr *ConflictError
+					if !errors.As(err, &conflictErr) || !errors.Is(err, ErrConflict) {
+						t.Fatalf("Expected a ConflictError, got %v", err)
+					}
+					var slots []int
+					for _, c := range conflictErr.Conflicts {
+						slots = append(slots, c.Slot)
+					}
+					if !reflect.DeepEqual(slots, tt.conflicts) {
+						t.Errorf("Expected conflicts in slots %v, got %v", tt.conflicts, slots)
+					}
+				}
 
-	// Ensure "FREE" is not included
-	for _, subject := range result {
-		if subject == "FREE" {
-			t.Error("FREE subject should not be included in GetAllSubject result")
+				if rowsAffected != tt.expectedRows {
+					t.Errorf("Expected %d rows affected, got %d", tt.expectedRows, rowsAffected)
+				}
+			})
 		}
-	}
+
+		// The rejected ranges must not have left any booking behind
+		slot, err := store.GetFreeSlot(ctx, "C203", testDate)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(slot, []int{5, 6, 7, 8}) {
+			t.Errorf("Expected C203 to be free in slots 5-8, got %v", slot)
+		}
+	})
 }
 
-func TestBooking(t *testing.T) {
-	db := setupTestDB(t)
-	defer teardownTestDB(t, db)
-
-	testDate := time.Date(2023, 6, 12, 0, 0, 0, 0, time.UTC) // Monday
-
-	tests := []struct {
-		name          string
-		class         string
-		date          time.Time
-		slot          int
-		faculty       string
-		subject       string
-		expectedRows  int64
-		shouldSucceed bool
-	}{
-		{
-			name:          "Book free slot successfully",
-			class:         "A104",
-			date:          testDate,
-			slot:          4, // This is FREE in our test data for A104 Monday
-			faculty:       "test.faculty@test.com",
-			subject:       "19CSE311",
-			expectedRows:  1,
-			shouldSucceed: true,
-		},
-		{
-			name:          "Try to book occupied slot",
-			class:         "A104",
-			date:          testDate,
-			slot:          2, // This is occupied in our test data
-			faculty:       "test.faculty@test.com",
-			subject:       "19CSE312",
-			expectedRows:  0,
-			shouldSucceed: true, // Function succeeds but no rows affected
-		},
-	}
+func TestFacultyDoubleBooking(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		testDate := time.Date(2023, 6, 13, 0, 0, 0, 0, time.UTC) // Tuesday
+		faculty := "test.faculty@test.com"
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			rowsAffected, err := Booking(tt.class, tt.date, tt.slot, tt.faculty, tt.subject)
+		_, err := store.Booking(ctx, "A104", testDate, 5, faculty, "19CSE311")
+		if err != nil {
+			t.Fatalf("Failed to create test booking: %v", err)
+		}
 
-			if tt.shouldSucceed && err != nil {
-				t.Errorf("Expected booking to succeed, got error: %v", err)
-			}
+		tests := []struct {
+			name      string
+			class     string
+			startSlot int
+			endSlot   int
+			faculty   string
+			expected  []Conflict
+		}{
+			{
+				name:      "Faculty teaches in another room",
+				class:     "A104",
+				startSlot: 1,
+				endSlot:   1, // tr_swapna teaches in C203 during slot 1
+				faculty:   "tr_swapna@cb.amrita.edu",
+				expected: []Conflict{
+					{Class: "A104", Date: testDate, Slot: 1, Reason: ReasonFacultyTeaching, OtherClass: "C203"},
+				},
+			},
+			{
+				name:      "Faculty teaches in another room for part of the range",
+				class:     "A104",
+				startSlot: 3,
+				endSlot:   5, // c_arunkumar teaches in C203 during slots 3 and 4
+				faculty:   "c_arunkumar@cb.amrita.edu",
+				expected: []Conflict{
+					{Class: "A104", Date: testDate, Slot: 3, Reason: ReasonFacultyTeaching, OtherClass: "C203"},
+					{Class: "A104", Date: testDate, Slot: 4, Reason: ReasonFacultyTeaching, OtherClass: "C203"},
+					{Class: "A104", Date: testDate, Slot: 5, Reason: ReasonBooked},
+				},
+			},
+			{
+				name:      "Faculty has booked another room",
+				class:     "C203",
+				startSlot: 5,
+				endSlot:   5,
+				faculty:   faculty,
+				expected: []Conflict{
+					{Class: "C203", Date: testDate, Slot: 5, Reason: ReasonFacultyBooked, OtherClass: "A104"},
+				},
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var rowsAffected int64
+				var err error
+				if tt.startSlot == tt.endSlot {
+					rowsAffected, err = store.Booking(ctx, tt.class, testDate, tt.startSlot, tt.faculty, "19CSE312")
+				} else {
+					rowsAffected, err = store.MultiBooking(ctx, tt.class, testDate, tt.startSlot, tt.endSlot, tt.faculty, "19CSE312")
+				}
 
-			if rowsAffected != tt.expectedRows {
-				t.Errorf("Expected %d rows affected, got %d", tt.expectedRows, rowsAffected)
+				var conflictErr *ConflictError
+				if !errors.As(err, &conflictErr) {
+					t.Fatalf("Expected a ConflictError, got %v", err)
+				}
+				if !reflect.DeepEqual(conflictErr.Conflicts, tt.expected) {
+					t.Errorf("Expected conflicts %+v, got %+v", tt.expected, conflictErr.Conflicts)
+				}
+				if rowsAffected != 0 {
+					t.Errorf("Expected 0 rows affected, got %d", rowsAffected)
+				}
+			})
+		}
+	})
+}
+
+func TestBookSeries(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		tuesday := func(day int) time.Time { return time.Date(2023, 6, day, 0, 0, 0, 0, time.UTC) }
+		faculty := "test.faculty@test.com"
+		// Four Tuesdays from June 13th, one of them skipped and another
+		// already booked during slot 6
+		series := Series{
+			Class: "A104", Faculty: faculty, Subject: "19CSE311",
+			StartSlot: 5, EndSlot: 6, Start: tuesday(13), Count: 4,
+			Skip: []time.Time{tuesday(20)},
+		}
+		_, err := store.Booking(ctx, "A104", tuesday(27), 6, "n_harini@cb.amrita.edu", "19CSE312")
+		if err != nil {
+			t.Fatalf("Failed to create test booking: %v", err)
+		}
+
+		_, _, err = store.BookSeries(ctx, series, false)
+		var conflictErr *ConflictError
+		if !errors.As(err, &conflictErr) {
+			t.Fatalf("Expected a ConflictError, got %v", err)
+		}
+		expected := []Conflict{{Class: "A104", Date: tuesday(27), Slot: 6, Reason: ReasonBooked}}
+		if !reflect.DeepEqual(conflictErr.Conflicts, expected) {
+			t.Errorf("Expected conflicts %+v, got %+v", expected, conflictErr.Conflicts)
+		}
+		if bookings, _ := store.GetBooking(ctx, faculty); len(bookings) != 0 {
+			t.Errorf("Expected nothing booked, got %+v", bookings)
+		}
+
+		booked, conflicts, err := store.BookSeries(ctx, series, true)
+		if err != nil {
+			t.Fatalf("Failed to book the series: %v", err)
+		}
+		if !reflect.DeepEqual(conflicts, expected) {
+			t.Errorf("Expected conflicts %+v, got %+v", expected, conflicts)
+		}
+		july4 := time.Date(2023, 7, 4, 0, 0, 0, 0, time.UTC)
+		want := Series{
+			ID: booked.ID, Class: "A104", Faculty: faculty, Subject: "19CSE311",
+			StartSlot: 5, EndSlot: 6, Start: tuesday(13), Until: july4, Interval: 1,
+			Skip:        []time.Time{tuesday(20), tuesday(27)},
+			Occurrences: []time.Time{tuesday(13), july4},
+		}
+		if !reflect.DeepEqual(booked, want) {
+			t.Errorf("Expected %+v, got %+v", want, booked)
+		}
+		got, err := store.GetSeries(ctx, Actor{ID: faculty}, booked.ID)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %+v, got %+v, %v", want, got, err)
+		}
+		if _, err := store.GetSeries(ctx, Actor{ID: "n_harini@cb.amrita.edu"}, booked.ID); !errors.Is(err, ErrForbidden) {
+			t.Errorf("Expected ErrForbidden, got %v", err)
+		}
+		if _, err := store.GetSeries(ctx, Actor{ID: "admin@test.com", Admin: true}, booked.ID); err != nil {
+			t.Errorf("Admin could not read the series: %v", err)
+		}
+
+		bookings, err := store.GetBooking(ctx, faculty)
+		if err != nil || len(bookings) != 4 {
+			t.Fatalf("Expected 4 bookings, got %+v, %v", bookings, err)
+		}
+		for _, b := range bookings {
+			if b.Series != booked.ID {
+				t.Errorf("Expected booking %+v to be part of series %d", b, booked.ID)
 			}
-		})
-	}
+		}
+	})
 }
 
-func TestCancelBooking(t *testing.T) {
-	db := setupTestDB(t)
-	defer teardownTestDB(t, db)
+func TestBookSeriesInvalid(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		start := time.Date(2023, 6, 13, 0, 0, 0, 0, time.UTC)
+		valid := Series{
+			Class: "A104", Faculty: "test.faculty@test.com", Subject: "19CSE311",
+			StartSlot: 5, EndSlot: 5, Start: start, Count: 2,
+		}
+		tests := []struct {
+			name   string
+			change func(s *Series)
+		}{
+			{"No end", func(s *Series) { s.Count = 0 }},
+			{"Count and until", func(s *Series) { s.Until = start.AddDate(0, 0, 7) }},
+			{"Ends before it starts", func(s *Series) { s.Count, s.Until = 0, start.AddDate(0, 0, -1) }},
+			{"Too many dates", func(s *Series) { s.Count = MaxSeriesDates + 1 }},
+			{"Skipped date not in series", func(s *Series) { s.Skip = []time.Time{start.AddDate(0, 0, 1)} }},
+			{"Everything skipped", func(s *Series) { s.Count, s.Skip = 1, []time.Time{start} }},
+			{"Slots reversed", func(s *Series) { s.EndSlot = 4 }},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				series := valid
+				tt.change(&series)
+				if _, _, err := store.BookSeries(ctx, series, false); !errors.Is(err, ErrInvalid) {
+					t.Errorf("Expected ErrInvalid, got %v", err)
+				}
+			})
+		}
+	})
+}
 
-	testDate := time.Date(2023, 6, 12, 0, 0, 0, 0, time.UTC)
+func TestCancelSeries(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		tuesday := func(day int) time.Time { return time.Date(2023, 6, day, 0, 0, 0, 0, time.UTC) }
+		owner := Actor{ID: "test.faculty@test.com"}
+		series, _, err := store.BookSeries(ctx, Series{
+			Class: "A104", Faculty: owner.ID, Subject: "19CSE311",
+			StartSlot: 4, EndSlot: 4, Start: tuesday(13), Until: tuesday(27),
+		}, false)
+		if err != nil {
+			t.Fatalf("Failed to book the series: %v", err)
+		}
 
-	// First create a booking
-	_, err := Booking("A104", testDate, 4, "test.faculty@test.com", "19CSE311")
-	if err != nil {
-		t.Fatalf("Failed to create test booking: %v", err)
-	}
+		err = store.CancelOccurrence(ctx, Actor{ID: "n_harini@cb.amrita.edu"}, series.ID, tuesday(20))
+		if !errors.Is(err, ErrForbidden) {
+			t.Errorf("Expected ErrForbidden, got %v", err)
+		}
+		if err := store.CancelOccurrence(ctx, owner, series.ID, tuesday(20)); err != nil {
+			t.Fatalf("Failed to cancel an occurrence: %v", err)
+		}
+		err = store.CancelOccurrence(ctx, owner, series.ID, tuesday(20))
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+		slots, _ := store.GetFreeSlot(ctx, "A104", tuesday(20))
+		if !reflect.DeepEqual(slots, []int{1, 3, 4, 5, 6, 7, 8}) {
+			t.Errorf("Expected slot 4 to be free again, got %v", slots)
+		}
+		got, err := store.GetSeries(ctx, owner, series.ID)
+		if err != nil {
+			t.Fatalf("Failed to get the series: %v", err)
+		}
+		if !reflect.DeepEqual(got.Skip, []time.Time{tuesday(20)}) ||
+			!reflect.DeepEqual(got.Occurrences, []time.Time{tuesday(13), tuesday(27)}) {
+			t.Errorf("Expected June 20th skipped, got %+v", got)
+		}
 
-	// Now test canceling it
-	err = CancelBooking("A104", testDate, 4)
-	if err != nil {
-		t.Errorf("Failed to cancel booking: %v", err)
-	}
+		err = store.CancelSeries(ctx, Actor{ID: "n_harini@cb.amrita.edu"}, series.ID)
+		if !errors.Is(err, ErrForbidden) {
+			t.Errorf("Expected ErrForbidden, got %v", err)
+		}
+		if err := store.CancelSeries(ctx, owner, series.ID); err != nil {
+			t.Fatalf("Failed to cancel the series: %v", err)
+		}
+		if _, err := store.GetSeries(ctx, owner, series.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+		if bookings, _ := store.GetBooking(ctx, owner.ID); len(bookings) != 0 {
+			t.Errorf("Expected no bookings left, got %+v", bookings)
+		}
+		if err := store.CancelSeries(ctx, owner, series.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+}
 
-	// Test canceling non-existent booking (should not error)
-	err = CancelBooking("A104", testDate, 4)
-	if err != nil {
-		t.Errorf("Canceling non-existent booking should not error: %v", err)
-	}
+func TestCalendar(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		date := func(month time.Month, day int) time.Time { return time.Date(2023, month, day, 0, 0, 0, 0, time.UTC) }
+		admin := Actor{ID: "admin@test.com", Admin: true}
+		// June is a term with an exam week from the 19th, and Saturday the
+		// 17th makes up for it with Monday's classes
+		calendar := Calendar{
+			Terms:     []Term{{Name: "2023 even", Start: date(6, 1), End: date(6, 30)}},
+			Holidays:  []Holiday{{Name: "Exam week", Start: date(6, 19), End: date(6, 23)}, {Name: "Founders Day", Start: date(6, 28)}},
+			DayOrders: []DayOrder{{Date: date(6, 17), Day: "MON"}},
+		}
+
+		if err := store.SetCalendar(ctx, Actor{ID: "test.faculty@test.com"}, calendar); !errors.Is(err, ErrForbidden) {
+			t.Errorf("Expected ErrForbidden, got %v", err)
+		}
+		invalid := calendar
+		invalid.DayOrders = []DayOrder{{Date: date(6, 17), Day: "SAT"}}
+		if err := store.SetCalendar(ctx, admin, invalid); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Expected ErrInvalid, got %v", err)
+		}
+		if err := store.SetCalendar(ctx, admin, calendar); err != nil {
+			t.Fatalf("Failed to set the calendar: %v", err)
+		}
+		got, err := store.GetCalendar(ctx)
+		calendar.Holidays[1].End = date(6, 28)
+		if err != nil || !reflect.DeepEqual(got, calendar) {
+			t.Errorf("Expected %+v, got %+v, %v", calendar, got, err)
+		}
+
+		tests := []struct {
+			name  string
+			date  time.Time
+			slots []int
+			rooms []string
+		}{
+			{"Monday", date(6, 12), []int{4, 5, 8}, []string{"A104", "C203"}},
+			{"Saturday following Monday", date(6, 17), []int{4, 5, 8}, []string{"A104", "C203"}},
+			{"Sunday", date(6, 18), nil, nil},
+			{"Exam week", date(6, 20), nil, nil},
+			{"Holiday", date(6, 28), nil, nil},
+			{"Outside the term", date(7, 3), nil, nil},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				slots, err := store.GetFreeSlot(ctx, "A104", tt.date)
+				if err != nil || !reflect.DeepEqual(slots, tt.slots) {
+					t.Errorf("Expected free slots %v, got %v, %v", tt.slots, slots, err)
+				}
+				rooms, err := store.GetFreeClass(ctx, 5, tt.date)
+				if err != nil || !reflect.DeepEqual(rooms, tt.rooms) {
+					t.Errorf("Expected free rooms %v, got %v, %v", tt.rooms, rooms, err)
+				}
+				rooms, err = store.MultiFreeSlot(ctx, 5, 5, tt.date)
+				if err != nil || !reflect.DeepEqual(rooms, tt.rooms) {
+					t.Errorf("Expected free rooms %v, got %v, %v", tt.rooms, rooms, err)
+				}
+				entries, err := store.GetTimetableByDay(ctx, "A104", tt.date)
+				if err != nil || (len(entries) == 8) != (tt.slots != nil) {
+					t.Errorf("Expected a timetable only when there are classes, got %+v, %v", entries, err)
+				}
+			})
+		}
+
+		// Booking reports the closed day rather than skipping it like a slot
+		// that is not free
+		rowsAffected, err := store.Booking(ctx, "A104", date(6, 20), 4, "test.faculty@test.com", "19CSE311")
+		var conflictErr *ConflictError
+		closed := []Conflict{{Class: "A104", Date: date(6, 20), Slot: 4, Reason: ReasonClosed}}
+		if !errors.As(err, &conflictErr) || !reflect.DeepEqual(conflictErr.Conflicts, closed) || rowsAffected != 0 {
+			t.Errorf("Expected conflicts %+v, got %d, %v", closed, rowsAffected, err)
+		}
+		_, err = store.MultiBooking(ctx, "A104", date(6, 20), 4, 5, "test.faculty@test.com", "19CSE311")
+		expected := []Conflict{
+			{Class: "A104", Date: date(6, 20), Slot: 4, Reason: ReasonClosed},
+			{Class: "A104", Date: date(6, 20), Slot: 5, Reason: ReasonClosed},
+		}
+		if !errors.As(err, &conflictErr) || !reflect.DeepEqual(conflictErr.Conflicts, expected) {
+			t.Errorf("Expected conflicts %+v, got %v", expected, err)
+		}
+		if _, err := store.MultiBooking(ctx, "A104", date(6, 17), 4, 5, "test.faculty@test.com", "19CSE311"); err != nil {
+			t.Errorf("Failed to book on the Saturday: %v", err)
+		}
+
+		days, err := store.GetTimetableRange(ctx, "A104", date(6, 16), date(6, 19))
+		if err != nil || len(days) != 4 {
+			t.Fatalf("Expected 4 days, got %+v, %v", days, err)
+		}
+		for i, want := range []struct {
+			day     string
+			entries int
+		}{{"FRI", 0}, {"MON", 8}, {"SUN", 0}, {"", 0}} {
+			if days[i].Day != want.day || len(days[i].Entries) != want.entries {
+				t.Errorf("Expected %s with %d entries on %s, got %q with %+v", want.day, want.entries,
+					days[i].Date.Format(dateLayout), days[i].Day, days[i].Entries)
+			}
+		}
+		if e := days[1].Entries[3]; e.Source != SourceBooking || e.Slot != 4 {
+			t.Errorf("Expected the Saturday booking in slot 4, got %+v", e)
+		}
+		schedule, err := store.GetFacultySchedule(ctx, "d_bharathi@cb.amrita.edu", date(6, 17), date(6, 19))
+		if err != nil || len(schedule[0].Entries) != 4 || len(schedule[2].Entries) != 0 {
+			t.Errorf("Expected Monday's classes on the Saturday only, got %+v, %v", schedule, err)
+		}
+	})
 }
 
-func TestGetBooking(t *testing.T) {
-	db := setupTestDB(t)
-	defer teardownTestDB(t, db)
+func TestTimetableVersions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		date := func(month time.Month, day int) time.Time { return time.Date(2023, month, day, 0, 0, 0, 0, time.UTC) }
+		admin := Actor{ID: "admin@test.com", Admin: true}
+		// The odd semester starts on Monday, July 3rd with a class in A104
+		// during slot 4, which is free in the default timetable
+		odd := TimetableVersion{Name: "2023 odd", Start: date(7, 1), End: date(11, 30)}
+		class := StaticEntry{"A104", "MON", 4, "test.faculty@test.com", "19CSE311"}
+
+		if err := store.SetTimetableVersion(ctx, Actor{ID: "test.faculty@test.com"}, odd); !errors.Is(err, ErrForbidden) {
+			t.Errorf("Expected ErrForbidden, got %v", err)
+		}
+		if err := store.SetTimetableVersion(ctx, admin, TimetableVersion{Name: "x", Start: date(7, 1), End: date(6, 1)}); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Expected ErrInvalid, got %v", err)
+		}
+		// The default version stays published on every date
+		for _, v := range []TimetableVersion{
+			{Name: DefaultVersion},
+			{Name: DefaultVersion, Published: true, Start: date(7, 1)},
+			{Name: DefaultVersion, Published: true, End: date(11, 30)},
+		} {
+			if err := store.SetTimetableVersion(ctx, admin, v); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Expected ErrInvalid for %+v, got %v", v, err)
+			}
+		}
+		if err := store.DeleteTimetableVersion(ctx, admin, DefaultVersion); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Expected ErrInvalid, got %v", err)
+		}
+		if err := store.SetVersionStatic(ctx, admin, odd.Name, class); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+		if err := store.SetTimetableVersion(ctx, admin, odd); err != nil {
+			t.Fatalf("Failed to add the version: %v", err)
+		}
+		if err := store.CopyTimetableVersion(ctx, admin, DefaultVersion, odd.Name); err != nil {
+			t.Fatalf("Failed to copy the default timetable: %v", err)
+		}
+		if err := store.CopyTimetableVersion(ctx, admin, odd.Name, odd.Name); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Expected ErrInvalid, got %v", err)
+		}
+		if err := store.SetVersionStatic(ctx, admin, odd.Name, class); err != nil {
+			t.Fatalf("Failed to set the class: %v", err)
+		}
+		if err := store.SetVersionStatic(ctx, admin, odd.Name, StaticEntry{"Z999", "MON", 1, "FREE", "FREE"}); err != nil {
+			t.Fatalf("Failed to add a room: %v", err)
+		}
 
-	testDate := time.Date(2023, 6, 12, 0, 0, 0, 0, time.UTC)
-	faculty := "test.faculty@test.com"
+		versions, err := store.GetTimetableVersions(ctx, admin)
+		expected := []TimetableVersion{{Name: DefaultVersion, Published: true}, odd}
+		if err != nil || !reflect.DeepEqual(versions, expected) {
+			t.Errorf("Expected %+v, got %+v, %v", expected, versions, err)
+		}
 
-	// Create some test bookings
-	_, err := Booking("A104", testDate, 4, faculty, "19CSE311")
-	if err != nil {
-		t.Fatalf("Failed to create test booking: %v", err)
-	}
+		// A staged version changes nothing but its preview
+		if slots, err := store.GetFreeSlot(ctx, "A104", date(7, 3)); err != nil || !reflect.DeepEqual(slots, []int{4, 5, 8}) {
+			t.Errorf("Expected the default timetable while staged, got %v, %v", slots, err)
+		}
+		if rooms, err := store.GetAllClass(ctx); err != nil || !reflect.DeepEqual(rooms, []string{"A104", "C203"}) {
+			t.Errorf("Expected the rooms of the default timetable, got %v, %v", rooms, err)
+		}
+		days, err := store.PreviewTimetableRange(ctx, admin, odd.Name, "A104", date(6, 26), date(6, 26))
+		if err != nil || len(days) != 1 || len(days[0].Entries) != 8 || days[0].Entries[3].Subject != "19CSE311" {
+			t.Errorf("Expected the staged class in the preview, got %+v, %v", days, err)
+		}
+		if _, err := store.PreviewTimetableRange(ctx, admin, "nosuch", "A104", date(6, 26), date(6, 26)); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
 
-	result := GetBooking(faculty)
+		odd.Published = true
+		if err := store.SetTimetableVersion(ctx, admin, odd); err != nil {
+			t.Fatalf("Failed to publish the version: %v", err)
+		}
+		tests := []struct {
+			name  string
+			date  time.Time
+			slots []int
+		}{
+			{"Before the version", date(6, 26), []int{4, 5, 8}},
+			{"In force", date(7, 3), []int{5, 8}},
+			{"After the version", date(12, 4), []int{4, 5, 8}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				slots, err := store.GetFreeSlot(ctx, "A104", tt.date)
+				if err != nil || !reflect.DeepEqual(slots, tt.slots) {
+					t.Errorf("Expected free slots %v, got %v, %v", tt.slots, slots, err)
+				}
+			})
+		}
+		days, err = store.GetTimetableRange(ctx, "A104", date(6, 26), date(7, 3))
+		if err != nil || len(days) != 8 || days[0].Entries[3].Subject != "FREE" || days[7].Entries[3].Subject != "19CSE311" {
+			t.Errorf("Expected the version to take over on July 3rd, got %+v, %v", days, err)
+		}
+		_, err = store.MultiBooking(ctx, "A104", date(7, 10), 4, 5, "n_harini@cb.amrita.edu", "19CSE312")
+		var conflictErr *ConflictError
+		conflict := []Conflict{{Class: "A104", Date: date(7, 10), Slot: 4, Reason: ReasonNotFree}}
+		if !errors.As(err, &conflictErr) || !reflect.DeepEqual(conflictErr.Conflicts, conflict) {
+			t.Errorf("Expected conflicts %+v, got %v", conflict, err)
+		}
 
-	if len(result) != 1 {
-		t.Errorf("Expected 1 booking, got %d", len(result))
-	}
+		// Published versions may not overlap, staged ones and the default may
+		even := TimetableVersion{Name: "2024 even", Start: date(11, 30), Published: true}
+		if err := store.SetTimetableVersion(ctx, admin, even); !errors.Is(err, ErrConflict) {
+			t.Errorf("Expected ErrConflict, got %v", err)
+		}
+		even.Published = false
+		if err := store.SetTimetableVersion(ctx, admin, even); err != nil {
+			t.Errorf("Failed to stage an overlapping version: %v", err)
+		}
+		even.Start, even.Published = date(12, 1), true
+		if err := store.SetTimetableVersion(ctx, admin, even); err != nil {
+			t.Errorf("Failed to publish the next version: %v", err)
+		}
+		odd.End = time.Time{}
+		if err := store.SetTimetableVersion(ctx, admin, odd); !errors.Is(err, ErrConflict) {
+			t.Errorf("Expected ErrConflict, got %v", err)
+		}
+		odd.End = date(11, 30)
 
-	if len(result) > 0 {
-		booking := result[0]
-		if booking.Class != "A104" {
-			t.Errorf("Expected class A104, got %s", booking.Class)
+		if err := store.DeleteTimetableVersion(ctx, admin, odd.Name); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Expected ErrInvalid, got %v", err)
 		}
-		if booking.Faculty != faculty {
-			t.Errorf("Expected faculty %s, got %s", faculty, booking.Faculty)
+		odd.Published = false
+		if err := store.SetTimetableVersion(ctx, admin, odd); err != nil {
+			t.Fatalf("Failed to unpublish the version: %v", err)
 		}
-		if booking.Subject != "19CSE311" {
-			t.Errorf("Expected subject 19CSE311, got %s", booking.Subject)
+		if err := store.DeleteTimetableVersion(ctx, admin, odd.Name); err != nil {
+			t.Fatalf("Failed to delete the version: %v", err)
 		}
-		if booking.Slot != 4 {
-			t.Errorf("Expected slot 4, got %d", booking.Slot)
+		if err := store.DeleteTimetableVersion(ctx, admin, odd.Name); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
 		}
-	}
+		if slots, err := store.GetFreeSlot(ctx, "A104", date(7, 3)); err != nil || !reflect.DeepEqual(slots, []int{4, 5, 8}) {
+			t.Errorf("Expected the default timetable back, got %v, %v", slots, err)
+		}
+	})
 }
 
-func TestMultiBooking(t *testing.T) {
-	db := setupTestDB(t)
-	defer teardownTestDB(t, db)
-
-	testDate := time.Date(2023, 6, 13, 0, 0, 0, 0, time.UTC) // Tuesday
-
-	tests := []struct {
-		name         string
-		class        string
-		date         time.Time
-		startSlot    int
-		endSlot      int
-		faculty      string
-		subject      string
-		expectedRows int64
+func TestMigrations(t *testing.T) {
+	configs := []struct {
+		name string
+		cfg  func(tb testing.TB) Config
 	}{
-		{
-			name:         "Book multiple free slots",
-			class:        "A104",
-			date:         testDate,
-			startSlot:    5,
-			endSlot:      8, // Slots 5-8 are FREE for A104 on Tuesday
-			faculty:      "test.faculty@test.com",
-			subject:      "19CSE312",
-			expectedRows: 4,
-		},
-		{
-			name:         "Book range with some occupied slots",
-			class:        "C203",
-			date:         testDate,
-			startSlot:    4,
-			endSlot:      6, // Slot 4 is occupied, slots 5-6 are FREE
-			faculty:      "test.faculty@test.com",
-			subject:      "19CSE313",
-			expectedRows: 2, // Only slots 5-6 should be booked
-		},
+		{DriverSQLite, sqliteTestConfig},
+		{DriverMySQL, mysqlTestConfig},
 	}
+	for _, c := range configs {
+		t.Run(c.name, func(t *testing.T) {
+			m, err := NewMigrator(c.cfg(t))
+			if err != nil {
+				t.Fatalf("Failed to open database: %v", err)
+			}
+			defer m.Close()
+
+			applied, err := m.Up(ctx)
+			if err != nil {
+				t.Fatalf("Up failed: %v", err)
+			}
+			if len(applied) != len(m.migrations) {
+				t.Errorf("Expected %d migrations applied, got %v", len(m.migrations), applied)
+			}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			rowsAffected, err := MultiBooking(tt.class, tt.date, tt.startSlot, tt.endSlot, tt.faculty, tt.subject)
+			// A second run has nothing left to do
+			applied, err = m.Up(ctx)
+			if err != nil || len(applied) != 0 {
+				t.Errorf("Expected no pending migrations, got %v, %v", applied, err)
+			}
 
+			status, err := m.Status(ctx)
 			if err != nil {
-				t.Errorf("MultiBooking failed: %v", err)
+				t.Fatalf("Status failed: %v", err)
+			}
+			for _, s := range status {
+				if !s.Applied() {
+					t.Errorf("Migration %d_%s not applied", s.Version, s.Name)
+				}
 			}
 
-			if rowsAffected != tt.expectedRows {
-				t.Errorf("Expected %d rows affected, got %d", tt.expectedRows, rowsAffected)
+			// Every down migration must undo its up migration
+			for i := len(m.migrations) - 1; i >= 0; i-- {
+				version, err := m.Down(ctx)
+				if err != nil {
+					t.Fatalf("Down failed: %v", err)
+				}
+				if version != m.migrations[i].version {
+					t.Errorf("Expected to revert %d, reverted %d", m.migrations[i].version, version)
+				}
+			}
+			version, err := m.Version(ctx)
+			if err != nil || version != 0 {
+				t.Errorf("Expected version 0, got %d, %v", version, err)
+			}
+
+			if _, err := m.Up(ctx); err != nil {
+				t.Errorf("Up after Down failed: %v", err)
 			}
 		})
 	}
 }
 
+// The sample data shipped for the memory backend must stay loadable
+func TestMemoryFixture(t *testing.T) {
+	store, err := Open(Config{Driver: DriverMemory, Fixture: filepath.Join("scripts", "fixture.json")})
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	slot, err := store.GetAllSlot(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(slot) != 8 {
+		t.Errorf("Expected 8 slots, got %d", len(slot))
+	}
+}
+
 // Benchmark tests
 func BenchmarkGetFreeClass(b *testing.B) {
-	// Create a dummy testing.T for setup
-	dummyT := &testing.T{}
-	db := setupTestDB(dummyT)
-	defer teardownTestDB(dummyT, db)
-
 	testDate := time.Date(2023, 6, 12, 0, 0, 0, 0, time.UTC)
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		GetFreeClass(4, testDate)
+	for _, backend := range backends {
+		b.Run(backend.name, func(b *testing.B) {
+			store := backend.open(b)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				store.GetFreeClass(ctx, 4, testDate)
+			}
+		})
 	}
 }
 
 func BenchmarkBooking(b *testing.B) {
-	dummyT := &testing.T{}
-	db := setupTestDB(dummyT)
-	defer teardownTestDB(dummyT, db)
-
 	testDate := time.Date(2023, 6, 12, 0, 0, 0, 0, time.UTC)
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		Booking("A104", testDate, 4, "test.faculty@test.com", "19CSE311")
-		CancelBooking("A104", testDate, 4) // Clean up for next iteration
+	for _, backend := range backends {
+		b.Run(backend.name, func(b *testing.B) {
+			store := backend.open(b)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				store.Booking(ctx, "A104", testDate, 4, "test.faculty@test.com", "19CSE311")
+				store.CancelBooking(ctx, Actor{ID: "test.faculty@test.com"}, "A104", testDate, 4) // Clean up for next iteration
+			}
+		})
 	}
 }
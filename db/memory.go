@@ -0,0 +1,925 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+)
+
+type staticKey struct {
+	version string
+	class   string
+	day     string
+	slot    int
+}
+
+type bookingKey struct {
+	class string
+	date  string
+	slot  int
+}
+
+/*
+memoryStore keeps the whole timetable in maps guarded by a single lock. It
+needs no database server, which makes it handy for local development and for
+tests, but nothing survives a restart. It enforces the same keys and
+references as the SQL schema so that it behaves like the other backends.
+*/
+type memoryStore struct {
+	mu       sync.RWMutex
+	slots    map[int]Slot
+	subjects map[string]Subject
+	faculty  map[string]Faculty
+	static   map[staticKey]StaticEntry
+	dynamic  map[bookingKey]BookingRecord
+	// series are by id and carry their skipped dates, not their
+	// occurrences
+	series     map[int64]Series
+	lastSeries int64
+	calendar   Calendar
+	versions   map[string]TimetableVersion
+	// apiTokens are by id, apiTokenIDs by hash
+	apiTokens   map[string]APIToken
+	apiTokenIDs map[string]string
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		slots:    make(map[int]Slot),
+		subjects: make(map[string]Subject),
+		faculty:  make(map[string]Faculty),
+		static:   make(map[staticKey]StaticEntry),
+		dynamic:  make(map[bookingKey]BookingRecord),
+		series:   make(map[int64]Series),
+		versions: map[string]TimetableVersion{DefaultVersion: {Name: DefaultVersion, Published: true}},
+
+		apiTokens:   make(map[string]APIToken),
+		apiTokenIDs: make(map[string]string),
+	}
+}
+
+func openMemory(cfg Config) (*memoryStore, error) {
+	s := newMemoryStore()
+	if cfg.Fixture == "" {
+		return s, nil
+	}
+	file, err := ioutil.ReadFile(cfg.Fixture)
+	if err != nil {
+		return nil, err
+	}
+	var f Fixture
+	if err := json.Unmarshal(file, &f); err != nil {
+		return nil, err
+	}
+	if err := s.load(context.Background(), &f); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
+
+func (s *memoryStore) load(ctx context.Context, f *Fixture) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, slot := range f.Slots {
+		if _, ok := s.slots[slot.ID]; ok {
+			return fmt.Errorf("%w: duplicate slot %d", ErrConflict, slot.ID)
+		}
+		s.slots[slot.ID] = slot
+	}
+	for _, subject := range f.Subjects {
+		if _, ok := s.subjects[subject.ID]; ok {
+			return fmt.Errorf("%w: duplicate subject %s", ErrConflict, subject.ID)
+		}
+		s.subjects[subject.ID] = subject
+	}
+	for _, faculty := range f.Faculty {
+		if _, ok := s.faculty[faculty.ID]; ok {
+			return fmt.Errorf("%w: duplicate faculty %s", ErrConflict, faculty.ID)
+		}
+		s.faculty[faculty.ID] = faculty
+	}
+	for _, v := range f.Versions {
+		if _, ok := s.versions[v.Name]; ok && v.Name != DefaultVersion {
+			return fmt.Errorf("%w: duplicate timetable version %s", ErrConflict, v.Name)
+		}
+		s.versions[v.Name] = v
+	}
+	for _, e := range f.Static {
+		if err := s.checkRefs(e.Slot, e.Faculty, e.Subject); err != nil {
+			return err
+		}
+		key := staticKey{DefaultVersion, e.Class, e.Day, e.Slot}
+		if _, ok := s.static[key]; ok {
+			return fmt.Errorf("%w: duplicate static entry %v", ErrConflict, key)
+		}
+		s.static[key] = e
+	}
+	for _, b := range f.Bookings {
+		if err := s.insertBooking(b); err != nil {
+			return err
+		}
+	}
+	calendar, err := checkCalendar(Actor{Admin: true}, f.Calendar)
+	if err != nil {
+		return err
+	}
+	s.calendar = calendar
+	return nil
+}
+
+// checkRefs stands in for the foreign keys of the SQL schema
+func (s *memoryStore) checkRefs(slot int, faculty string, subject string) error {
+	if _, ok := s.slots[slot]; !ok {
+		return fmt.Errorf("%w: slot %d", ErrNotFound, slot)
+	}
+	if _, ok := s.faculty[faculty]; !ok {
+		return fmt.Errorf("%w: faculty %s", ErrNotFound, faculty)
+	}
+	if _, ok := s.subjects[subject]; !ok {
+		return fmt.Errorf("%w: subject %s", ErrNotFound, subject)
+	}
+	return nil
+}
+
+// insertBooking adds b to the dynamic table. The caller must hold the lock.
+func (s *memoryStore) insertBooking(b BookingRecord) error {
+	if err := s.checkRefs(b.Slot, b.Faculty, b.Subject); err != nil {
+		return err
+	}
+	// Only the date is kept, as in a DATE column
+	b.Date = dateOnly(b.Date)
+	key := bookingKey{b.Class, b.Date.Format(dateLayout), b.Slot}
+	if _, ok := s.dynamic[key]; ok {
+		return fmt.Errorf("%w: %s is already booked on %s for slot %d",
+			ErrConflict, b.Class, key.date, b.Slot)
+	}
+	s.dynamic[key] = b
+	return nil
+}
+
+/*
+dayOf is the version of the static timetable in force on date and the day of
+it that date follows, see versionOn and Calendar.Day. The caller must hold
+the lock.
+*/
+func (s *memoryStore) dayOf(date time.Time) (version string, day string) {
+	return versionOn(s.versionList(), date), s.calendar.Day(date)
+}
+
+// versionList sorts the versions like the SQL backends list them. The
+// caller must hold the lock.
+func (s *memoryStore) versionList() []TimetableVersion {
+	var versions []TimetableVersion
+	for _, v := range s.versions {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		if !versions[i].Start.Equal(versions[j].Start) {
+			return versions[i].Start.Before(versions[j].Start)
+		}
+		return versions[i].Name < versions[j].Name
+	})
+	return versions
+}
+
+// isFree reports whether class is free during slot on date. The caller must
+// hold the lock.
+func (s *memoryStore) isFree(class string, date time.Time, slot int) bool {
+	version, day := s.dayOf(date)
+	e, ok := s.static[staticKey{version, class, day, slot}]
+	if !ok || e.Subject != "FREE" {
+		return false
+	}
+	_, booked := s.dynamic[bookingKey{class, date.Format(dateLayout), slot}]
+	return !booked
+}
+
+func (s *memoryStore) GetFreeClass(ctx context.Context, slot int, date time.Time) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	version, day := s.dayOf(date)
+	var classroom []string
+	for key := range s.static {
+		if key.slot == slot && key.version == version && key.day == day && s.isFree(key.class, date, slot) {
+			classroom = append(classroom, key.class)
+		}
+	}
+	sort.Strings(classroom)
+	return classroom, nil
+}
+
+func (s *memoryStore) GetFreeSlot(ctx context.Context, class string, date time.Time) ([]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	version, day := s.dayOf(date)
+	var slot []int
+	for key := range s.static {
+		if key.class == class && key.version == version && key.day == day && s.isFree(class, date, key.slot) {
+			slot = append(slot, key.slot)
+		}
+	}
+	sort.Ints(slot)
+	return slot, nil
+}
+
+func (s *memoryStore) MultiFreeSlot(ctx context.Context, startSlot int, endSlot int, date time.Time) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	version, day := s.dayOf(date)
+	numFree := make(map[string]int)
+	for key := range s.static {
+		if key.slot >= startSlot && key.slot <= endSlot && key.version == version && key.day == day &&
+			s.isFree(key.class, date, key.slot) {
+			numFree[key.class]++
+		}
+	}
+	var class []string
+	for c, n := range numFree {
+		if n == endSlot-startSlot+1 {
+			class = append(class, c)
+		}
+	}
+	sort.Strings(class)
+	return class, nil
+}
+
+func (s *memoryStore) GetTimetableByDay(ctx context.Context, class string, date time.Time) ([]TimetableEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	version, day := s.dayOf(date)
+	return s.dayEntries(version, day, class, date), nil
+}
+
+func (s *memoryStore) GetTimetableRange(ctx context.Context, class string, from time.Time, to time.Time) ([]DayTimetable, error) {
+	from, to, err := checkRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var days []DayTimetable
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		version, day := s.dayOf(date)
+		days = append(days, DayTimetable{Date: date, Day: day, Entries: s.dayEntries(version, day, class, date)})
+	}
+	return days, nil
+}
+
+func (s *memoryStore) PreviewTimetableRange(ctx context.Context, actor Actor, version string, class string, from time.Time, to time.Time) ([]DayTimetable, error) {
+	if !actor.Admin {
+		return nil, fmt.Errorf("%w: only admins may preview a timetable", ErrForbidden)
+	}
+	from, to, err := checkRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.versions[version]; !ok {
+		return nil, fmt.Errorf("%w: timetable version %s", ErrNotFound, version)
+	}
+	var days []DayTimetable
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		day := s.calendar.Day(date)
+		days = append(days, DayTimetable{Date: date, Day: day, Entries: s.dayEntries(version, day, class, date)})
+	}
+	return days, nil
+}
+
+func (s *memoryStore) GetFacultySchedule(ctx context.Context, faculty string, from time.Time, to time.Time) ([]DayTimetable, error) {
+	from, to, err := checkRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var days []DayTimetable
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		version, day := s.dayOf(date)
+		var entries []TimetableEntry
+		for key, e := range s.static {
+			_, booked := s.dynamic[bookingKey{key.class, date.Format(dateLayout), key.slot}]
+			if e.Faculty == faculty && key.version == version && key.day == day && !booked {
+				entries = append(entries, s.timetableEntry(key.class, key.slot, e.Subject, e.Faculty, SourceStatic))
+			}
+		}
+		for key, b := range s.dynamic {
+			if b.Faculty == faculty && key.date == date.Format(dateLayout) {
+				entries = append(entries, s.timetableEntry(key.class, key.slot, b.Subject, b.Faculty, SourceBooking))
+			}
+		}
+		sortEntries(entries)
+		days = append(days, DayTimetable{Date: date, Day: day, Entries: entries})
+	}
+	return days, nil
+}
+
+// dayEntries is the timetable of class on date, which follows day of
+// version. The caller must hold the lock.
+func (s *memoryStore) dayEntries(version string, day string, class string, date time.Time) []TimetableEntry {
+	bySlot := make(map[int]TimetableEntry)
+	for key, e := range s.static {
+		if key.class == class && key.version == version && key.day == day {
+			bySlot[key.slot] = s.timetableEntry(key.class, key.slot, e.Subject, e.Faculty, SourceStatic)
+		}
+	}
+	// Bookings take precedence over the static timetable
+	for key, b := range s.dynamic {
+		if key.class == class && key.date == date.Format(dateLayout) {
+			bySlot[key.slot] = s.timetableEntry(key.class, key.slot, b.Subject, b.Faculty, SourceBooking)
+		}
+	}
+	var entries []TimetableEntry
+	for _, e := range bySlot {
+		entries = append(entries, e)
+	}
+	sortEntries(entries)
+	return entries
+}
+
+// timetableEntry fills in the slot times and subject name. The caller must
+// hold the lock.
+func (s *memoryStore) timetableEntry(class string, slot int, subject string, faculty string, source string) TimetableEntry {
+	return TimetableEntry{
+		Room:        class,
+		Slot:        slot,
+		Start:       s.slots[slot].Start,
+		End:         s.slots[slot].End,
+		Subject:     subject,
+		SubjectName: s.subjects[subject].Name,
+		Faculty:     faculty,
+		Source:      source,
+	}
+}
+
+func (s *memoryStore) GetAllSlot(ctx context.Context) ([]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var slot []int
+	for id := range s.slots {
+		slot = append(slot, id)
+	}
+	sort.Ints(slot)
+	return slot, nil
+}
+
+func (s *memoryStore) GetAllClass(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	seen := make(map[string]bool)
+	var class []string
+	for key := range s.static {
+		// Rooms that are only in a staged timetable are left out
+		if !seen[key.class] && s.versions[key.version].Published {
+			seen[key.class] = true
+			class = append(class, key.class)
+		}
+	}
+	sort.Strings(class)
+	return class, nil
+}
+
+func (s *memoryStore) GetAllSubject(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var subject []string
+	for id := range s.subjects {
+		if id != "FREE" {
+			subject = append(subject, id)
+		}
+	}
+	sort.Strings(subject)
+	return subject, nil
+}
+
+func (s *memoryStore) GetBooking(ctx context.Context, faculty string) ([]BookingRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var booking []BookingRecord
+	for _, b := range s.dynamic {
+		if b.Faculty == faculty {
+			booking = append(booking, b)
+		}
+	}
+	sortBookings(booking)
+	return booking, nil
+}
+
+func (s *memoryStore) Booking(ctx context.Context, class string, date time.Time, slot int, faculty string, subject string) (int64, error) {
+	return s.book(ctx, class, date, slot, slot, faculty, subject, true)
+}
+
+// MultiBooking books every slot from startSlot to endSlot or none of them
+func (s *memoryStore) MultiBooking(ctx context.Context, class string, date time.Time, startSlot int, endSlot int, faculty string, subject string) (int64, error) {
+	return s.book(ctx, class, date, startSlot, endSlot, faculty, subject, false)
+}
+
+// book follows the same rules as sqlStore.book
+func (s *memoryStore) book(ctx context.Context, class string, date time.Time, startSlot int, endSlot int, faculty string, subject string, skipNotFree bool) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := s.slotStates(class, date, startSlot, endSlot, faculty)
+	if skipNotFree && !state[startSlot].closed {
+		for slot := startSlot; slot <= endSlot; slot++ {
+			if !state[slot].free {
+				return 0, nil
+			}
+		}
+	}
+	if err := rangeConflicts(class, date, startSlot, endSlot, state); err != nil {
+		return 0, err
+	}
+	// Check every reference before inserting anything so that a failure
+	// leaves no partial booking behind
+	for slot := startSlot; slot <= endSlot; slot++ {
+		if err := s.checkRefs(slot, faculty, subject); err != nil {
+			return 0, err
+		}
+	}
+	var rowsAffected int64
+	for slot := startSlot; slot <= endSlot; slot++ {
+		err := s.insertBooking(BookingRecord{
+			Class:   class,
+			Date:    date,
+			Slot:    slot,
+			Faculty: faculty,
+			Subject: subject,
+		})
+		if err != nil {
+			return rowsAffected, err
+		}
+		rowsAffected++
+	}
+	return rowsAffected, nil
+}
+
+// slotStates gathers what rangeConflicts needs. The caller must hold the lock.
+func (s *memoryStore) slotStates(class string, date time.Time, startSlot int, endSlot int, faculty string) map[int]slotState {
+	state := make(map[int]slotState)
+	version, day := s.dayOf(date)
+	if day == "" {
+		for slot := startSlot; slot <= endSlot; slot++ {
+			state[slot] = slotState{closed: true}
+		}
+		return state
+	}
+	for slot := startSlot; slot <= endSlot; slot++ {
+		e, ok := s.static[staticKey{version, class, day, slot}]
+		_, booked := s.dynamic[bookingKey{class, date.Format(dateLayout), slot}]
+		state[slot] = slotState{free: ok && e.Subject == "FREE", booked: booked}
+	}
+	for key, e := range s.static {
+		if e.Faculty == faculty && key.version == version && key.day == day && key.class != class &&
+			key.slot >= startSlot && key.slot <= endSlot {
+			st := state[key.slot]
+			st.teaching = key.class
+			state[key.slot] = st
+		}
+	}
+	for key, b := range s.dynamic {
+		if b.Faculty == faculty && key.date == date.Format(dateLayout) && key.class != class &&
+			key.slot >= startSlot && key.slot <= endSlot {
+			st := state[key.slot]
+			st.facultyBooked = key.class
+			state[key.slot] = st
+		}
+	}
+	return state
+}
+
+// BookSeries follows the same rules as sqlStore.BookSeries
+func (s *memoryStore) BookSeries(ctx context.Context, series Series, partial bool) (Series, []Conflict, error) {
+	series, err := checkSeries(series)
+	if err != nil {
+		return series, nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return series, nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dates, conflicts, _ := seriesConflicts(series, func(date time.Time) (map[int]slotState, error) {
+		return s.slotStates(series.Class, date, series.StartSlot, series.EndSlot, series.Faculty), nil
+	})
+	if len(conflicts) > 0 && (!partial || len(dates) == 0) {
+		return series, nil, &ConflictError{Conflicts: conflicts}
+	}
+	for slot := series.StartSlot; slot <= series.EndSlot; slot++ {
+		if err := s.checkRefs(slot, series.Faculty, series.Subject); err != nil {
+			return series, nil, err
+		}
+	}
+	s.lastSeries++
+	series.ID = s.lastSeries
+	for _, c := range conflicts {
+		series.Skip = addDate(series.Skip, c.Date)
+	}
+	s.series[series.ID] = series
+	for _, date := range dates {
+		for slot := series.StartSlot; slot <= series.EndSlot; slot++ {
+			err := s.insertBooking(BookingRecord{
+				Class:   series.Class,
+				Date:    date,
+				Slot:    slot,
+				Faculty: series.Faculty,
+				Subject: series.Subject,
+				Series:  series.ID,
+			})
+			if err != nil {
+				return series, nil, err
+			}
+		}
+	}
+	series.Occurrences = dates
+	return series, conflicts, nil
+}
+
+func (s *memoryStore) GetSeries(ctx context.Context, actor Actor, id int64) (Series, error) {
+	if err := ctx.Err(); err != nil {
+		return Series{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	series, err := s.seriesFor(actor, id)
+	if err != nil {
+		return Series{}, err
+	}
+	series.Skip = append([]time.Time(nil), series.Skip...)
+	for _, b := range s.dynamic {
+		if b.Series == id {
+			series.Occurrences = addDate(series.Occurrences, b.Date)
+		}
+	}
+	return series, nil
+}
+
+func (s *memoryStore) CancelSeries(ctx context.Context, actor Actor, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.seriesFor(actor, id); err != nil {
+		return err
+	}
+	for key, b := range s.dynamic {
+		if b.Series == id {
+			delete(s.dynamic, key)
+		}
+	}
+	delete(s.series, id)
+	return nil
+}
+
+func (s *memoryStore) CancelOccurrence(ctx context.Context, actor Actor, id int64, date time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	series, err := s.seriesFor(actor, id)
+	if err != nil {
+		return err
+	}
+	date = dateOnly(date)
+	found := false
+	for key, b := range s.dynamic {
+		if b.Series == id && b.Date.Equal(date) {
+			delete(s.dynamic, key)
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("%w: series %d has no booking on %s", ErrNotFound, id, date.Format(dateLayout))
+	}
+	series.Skip = addDate(append([]time.Time(nil), series.Skip...), date)
+	s.series[id] = series
+	return nil
+}
+
+// seriesFor returns the series if actor may change it. The caller must hold
+// the lock.
+func (s *memoryStore) seriesFor(actor Actor, id int64) (Series, error) {
+	series, ok := s.series[id]
+	if !ok {
+		return series, fmt.Errorf("%w: series %d", ErrNotFound, id)
+	}
+	if !actor.mayModify(series.Faculty) {
+		return series, fmt.Errorf("%w: the series belongs to %s", ErrForbidden, series.Faculty)
+	}
+	return series, nil
+}
+
+func (s *memoryStore) CancelBooking(ctx context.Context, actor Actor, class string, date time.Time, slot int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := bookingKey{class, date.Format(dateLayout), slot}
+	b, ok := s.dynamic[key]
+	if !ok {
+		return fmt.Errorf("%w: no booking of %s on %s for slot %d", ErrNotFound, class, key.date, slot)
+	}
+	if !actor.mayModify(b.Faculty) {
+		return fmt.Errorf("%w: the booking belongs to %s", ErrForbidden, b.Faculty)
+	}
+	delete(s.dynamic, key)
+	return nil
+}
+
+func (s *memoryStore) SetStatic(ctx context.Context, actor Actor, e StaticEntry) error {
+	return s.SetVersionStatic(ctx, actor, DefaultVersion, e)
+}
+
+func (s *memoryStore) SetVersionStatic(ctx context.Context, actor Actor, version string, e StaticEntry) error {
+	if err := checkStatic(actor, e); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkRefs(e.Slot, e.Faculty, e.Subject); err != nil {
+		return err
+	}
+	if _, ok := s.versions[version]; !ok {
+		return fmt.Errorf("%w: timetable version %s", ErrNotFound, version)
+	}
+	s.static[staticKey{version, e.Class, e.Day, e.Slot}] = e
+	return nil
+}
+
+func (s *memoryStore) GetTimetableVersions(ctx context.Context, actor Actor) ([]TimetableVersion, error) {
+	if !actor.Admin {
+		return nil, fmt.Errorf("%w: only admins may list timetable versions", ErrForbidden)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.versionList(), nil
+}
+
+func (s *memoryStore) SetTimetableVersion(ctx context.Context, actor Actor, v TimetableVersion) error {
+	v, err := checkVersion(actor, v)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var versions []TimetableVersion
+	for _, w := range s.versions {
+		versions = append(versions, w)
+	}
+	if err := checkOverlap(versions, v); err != nil {
+		return err
+	}
+	s.versions[v.Name] = v
+	return nil
+}
+
+func (s *memoryStore) DeleteTimetableVersion(ctx context.Context, actor Actor, name string) error {
+	if !actor.Admin {
+		return fmt.Errorf("%w: only admins may change the timetable", ErrForbidden)
+	}
+	if name == DefaultVersion {
+		return fmt.Errorf("%w: version %s can't be deleted", ErrInvalid, name)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.versions[name]
+	if !ok {
+		return fmt.Errorf("%w: timetable version %s", ErrNotFound, name)
+	}
+	if v.Published {
+		return fmt.Errorf("%w: version %s is published", ErrInvalid, name)
+	}
+	for key := range s.static {
+		if key.version == name {
+			delete(s.static, key)
+		}
+	}
+	delete(s.versions, name)
+	return nil
+}
+
+func (s *memoryStore) CopyTimetableVersion(ctx context.Context, actor Actor, from string, to string) error {
+	if !actor.Admin {
+		return fmt.Errorf("%w: only admins may change the timetable", ErrForbidden)
+	}
+	if from == to {
+		return fmt.Errorf("%w: cannot copy version %s onto itself", ErrInvalid, from)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range []string{from, to} {
+		if _, ok := s.versions[name]; !ok {
+			return fmt.Errorf("%w: timetable version %s", ErrNotFound, name)
+		}
+	}
+	for key := range s.static {
+		if key.version == to {
+			delete(s.static, key)
+		}
+	}
+	for key, e := range s.static {
+		if key.version == from {
+			key.version = to
+			s.static[key] = e
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) GetCalendar(ctx context.Context) (Calendar, error) {
+	if err := ctx.Err(); err != nil {
+		return Calendar{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Calendar{
+		Terms:     append([]Term(nil), s.calendar.Terms...),
+		Holidays:  append([]Holiday(nil), s.calendar.Holidays...),
+		DayOrders: append([]DayOrder(nil), s.calendar.DayOrders...),
+	}, nil
+}
+
+// SetCalendar keeps the calendar sorted like the SQL backends return it
+func (s *memoryStore) SetCalendar(ctx context.Context, actor Actor, c Calendar) error {
+	c, err := checkCalendar(actor, c)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	sort.Slice(c.Terms, func(i, j int) bool {
+		if !c.Terms[i].Start.Equal(c.Terms[j].Start) {
+			return c.Terms[i].Start.Before(c.Terms[j].Start)
+		}
+		return c.Terms[i].Name < c.Terms[j].Name
+	})
+	sort.Slice(c.Holidays, func(i, j int) bool { return c.Holidays[i].Start.Before(c.Holidays[j].Start) })
+	sort.Slice(c.DayOrders, func(i, j int) bool { return c.DayOrders[i].Date.Before(c.DayOrders[j].Date) })
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calendar = c
+	return nil
+}
+
+func (s *memoryStore) CreateAPIToken(ctx context.Context, actor Actor, t APIToken, hash string) error {
+	if err := checkAPIToken(actor, t); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.faculty[t.Faculty]; t.Faculty != "" && !ok {
+		return fmt.Errorf("%w: faculty %s", ErrNotFound, t.Faculty)
+	}
+	if _, ok := s.apiTokens[t.ID]; ok {
+		return fmt.Errorf("%w: duplicate API token %s", ErrConflict, t.ID)
+	}
+	if _, ok := s.apiTokenIDs[hash]; ok {
+		return fmt.Errorf("%w: duplicate API token hash", ErrConflict)
+	}
+	// Only whole seconds are kept, as in a DATETIME column
+	t.CreatedAt = t.CreatedAt.UTC().Truncate(time.Second)
+	t.LastUsed = nil
+	s.apiTokens[t.ID] = t
+	s.apiTokenIDs[hash] = t.ID
+	return nil
+}
+
+func (s *memoryStore) GetAPITokens(ctx context.Context, actor Actor) ([]APIToken, error) {
+	if !actor.Admin {
+		return nil, fmt.Errorf("%w: only admins may list API tokens", ErrForbidden)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var tokens []APIToken
+	for _, t := range s.apiTokens {
+		tokens = append(tokens, t)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if !tokens[i].CreatedAt.Equal(tokens[j].CreatedAt) {
+			return tokens[i].CreatedAt.Before(tokens[j].CreatedAt)
+		}
+		return tokens[i].ID < tokens[j].ID
+	})
+	return tokens, nil
+}
+
+func (s *memoryStore) RevokeAPIToken(ctx context.Context, actor Actor, id string) error {
+	if !actor.Admin {
+		return fmt.Errorf("%w: only admins may revoke API tokens", ErrForbidden)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.apiTokens[id]; !ok {
+		return fmt.Errorf("%w: API token %s", ErrNotFound, id)
+	}
+	delete(s.apiTokens, id)
+	for hash, tokenID := range s.apiTokenIDs {
+		if tokenID == id {
+			delete(s.apiTokenIDs, hash)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) UseAPIToken(ctx context.Context, hash string, now time.Time) (APIToken, error) {
+	if err := ctx.Err(); err != nil {
+		return APIToken{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.apiTokens[s.apiTokenIDs[hash]]
+	if !ok {
+		return t, fmt.Errorf("%w: API token", ErrNotFound)
+	}
+	now = now.UTC().Truncate(time.Second)
+	t.LastUsed = &now
+	s.apiTokens[t.ID] = t
+	return t, nil
+}
+
+// sortBookings orders bookings by date, room and slot
+func sortBookings(booking []BookingRecord) {
+	sort.Slice(booking, func(i, j int) bool {
+		a, b := booking[i], booking[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		if a.Class != b.Class {
+			return a.Class < b.Class
+		}
+		return a.Slot < b.Slot
+	})
+}
@@ -0,0 +1,307 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+Every schema change is a numbered pair of files under migrations/<driver>,
+NNNN_name.up.sql and NNNN_name.down.sql, compiled into the binary. The version
+of the schema is the highest number recorded in the schema_version table, so
+a database is upgraded in place by applying only the migrations it has not
+seen yet.
+*/
+//go:embed migrations
+var migrationFS embed.FS
+
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+}
+
+// MigrationStatus describes one migration known to the binary
+type MigrationStatus struct {
+	Version int
+	Name    string
+	// AppliedAt is the zero time for a pending migration
+	AppliedAt time.Time
+}
+
+func (m MigrationStatus) Applied() bool {
+	return !m.AppliedAt.IsZero()
+}
+
+/*
+Migrator applies the embedded migrations to the database described by a
+Config. It uses its own connection rather than a Store because a Store
+prepares its statements against the tables the migrations create.
+*/
+type Migrator struct {
+	db         *sql.DB
+	translate  func(error) error
+	migrations []migration
+}
+
+func NewMigrator(cfg Config) (*Migrator, error) {
+	var db *sql.DB
+	var d dialect
+	var err error
+	switch cfg.Driver {
+	case "", DriverMySQL:
+		db, err = connectMySQL(cfg)
+		d = mysqlDialect
+	case DriverSQLite:
+		db, err = connectSQLite(cfg)
+		d = sqliteDialect
+	case DriverMemory:
+		return nil, fmt.Errorf("db: the %s driver has no schema to migrate", cfg.Driver)
+	default:
+		return nil, fmt.Errorf("db: unknown driver %q", cfg.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+	driver := cfg.Driver
+	if driver == "" {
+		driver = DriverMySQL
+	}
+	migrations, err := loadMigrations(driver)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Migrator{db: db, translate: d.translate, migrations: migrations}, nil
+}
+
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
+
+// loadMigrations reads the embedded migrations for driver in version order
+func loadMigrations(driver string) ([]migration, error) {
+	dir := path.Join("migrations", driver)
+	entries, err := fs.ReadDir(migrationFS, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		var up bool
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			up = true
+		case strings.HasSuffix(name, ".down.sql"):
+		default:
+			continue
+		}
+		sep := strings.IndexByte(name, '_')
+		if sep < 0 {
+			return nil, fmt.Errorf("db: migration %s has no version", name)
+		}
+		version, err := strconv.Atoi(name[:sep])
+		if err != nil {
+			return nil, fmt.Errorf("db: migration %s has no version", name)
+		}
+		body, err := migrationFS.ReadFile(path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{version: version}
+			byVersion[version] = m
+		}
+		m.name = strings.TrimSuffix(strings.TrimSuffix(name[sep+1:], ".up.sql"), ".down.sql")
+		if up {
+			m.up = string(body)
+		} else {
+			m.down = string(body)
+		}
+	}
+	var migrations []migration
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("db: migration %d is missing its up or down file", m.version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+	return migrations, nil
+}
+
+func (m *Migrator) ensureVersionTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_version (
+        version INT,
+        name VARCHAR(255) NOT NULL,
+        applied_at DATETIME NOT NULL,
+        PRIMARY KEY (version)
+    )`)
+	return m.translate(err)
+}
+
+// applied returns when each applied migration was run, keyed by version
+func (m *Migrator) applied(ctx context.Context) (map[int]time.Time, error) {
+	if err := m.ensureVersionTable(ctx); err != nil {
+		return nil, err
+	}
+	rows, err := m.db.QueryContext(ctx, `SELECT version, applied_at FROM schema_version`)
+	if err != nil {
+		return nil, m.translate(err)
+	}
+	defer rows.Close()
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, m.translate(err)
+		}
+		applied[version] = appliedAt
+	}
+	return applied, m.translate(rows.Err())
+}
+
+// Status lists every embedded migration and whether it has been applied
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var status []MigrationStatus
+	for _, mig := range m.migrations {
+		status = append(status, MigrationStatus{
+			Version:   mig.version,
+			Name:      mig.name,
+			AppliedAt: applied[mig.version],
+		})
+	}
+	return status, nil
+}
+
+// Version is the highest applied migration, 0 for an empty database
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	version := 0
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+	return version, nil
+}
+
+// Up applies every pending migration in order and returns their versions
+func (m *Migrator) Up(ctx context.Context) ([]int, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var done []int
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.version]; ok {
+			continue
+		}
+		err := m.run(ctx, mig.up, func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, `INSERT INTO schema_version
+                (version, name, applied_at) VALUES (?, ?, ?)`,
+				mig.version, mig.name, time.Now().UTC().Format("2006-01-02 15:04:05"))
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d_%s: %w", mig.version, mig.name, err)
+		}
+		done = append(done, mig.version)
+	}
+	return done, nil
+}
+
+/*
+Down reverts the most recently applied migration and returns its version, or
+0 when there was nothing to revert.
+*/
+func (m *Migrator) Down(ctx context.Context) (int, error) {
+	version, err := m.Version(ctx)
+	if err != nil || version == 0 {
+		return 0, err
+	}
+	for _, mig := range m.migrations {
+		if mig.version != version {
+			continue
+		}
+		err := m.run(ctx, mig.down, func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, `DELETE FROM schema_version WHERE version = ?`, mig.version)
+			return err
+		})
+		if err != nil {
+			return 0, fmt.Errorf("migration %d_%s: %w", mig.version, mig.name, err)
+		}
+		return version, nil
+	}
+	return 0, fmt.Errorf("db: applied migration %d is not known to this binary", version)
+}
+
+/*
+run executes the statements of a migration followed by record, which updates
+schema_version. SQLite runs them in one transaction; MySQL commits DDL
+implicitly, so a failing MySQL migration can leave its earlier statements
+applied.
+*/
+func (m *Migrator) run(ctx context.Context, script string, record func(tx *sql.Tx) error) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return m.translate(err)
+	}
+	defer tx.Rollback()
+	for _, stmt := range splitStatements(script) {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return m.translate(err)
+		}
+	}
+	if err := record(tx); err != nil {
+		return m.translate(err)
+	}
+	return m.translate(tx.Commit())
+}
+
+/*
+splitStatements breaks a script into statements on the semicolons that end a
+line, dropping "--" comment lines. The MySQL driver refuses to run more than
+one statement per Exec unless multiStatements is set on the DSN.
+*/
+func splitStatements(script string) []string {
+	var stmts []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		stmts = append(stmts, rest)
+	}
+	return stmts
+}
@@ -0,0 +1,5 @@
+DROP TABLE dynamic;
+DROP TABLE static;
+DROP TABLE faculty;
+DROP TABLE subject;
+DROP TABLE slot;
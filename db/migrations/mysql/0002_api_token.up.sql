@@ -0,0 +1,14 @@
+-- Tokens for scripts and kiosks. Only the SHA-256 of a token is kept.
+CREATE TABLE api_token (
+    id CHAR(16),
+    hash CHAR(64) NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    scope ENUM ("read", "book", "admin") NOT NULL,
+    faculty_id CHAR(254),
+    created_by CHAR(254) NOT NULL,
+    created_at DATETIME NOT NULL,
+    last_used_at DATETIME,
+    FOREIGN KEY (faculty_id) REFERENCES faculty (id),
+    UNIQUE (hash),
+    PRIMARY KEY (id)
+);
@@ -0,0 +1,2 @@
+DELETE FROM api_token WHERE scope = "calendar";
+ALTER TABLE api_token MODIFY scope ENUM ("read", "book", "admin") NOT NULL;
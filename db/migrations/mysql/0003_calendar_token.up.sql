@@ -0,0 +1,2 @@
+-- Calendar tokens only read the feed of their faculty
+ALTER TABLE api_token MODIFY scope ENUM ("read", "book", "admin", "calendar") NOT NULL;
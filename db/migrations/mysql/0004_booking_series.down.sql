@@ -0,0 +1,3 @@
+ALTER TABLE dynamic DROP FOREIGN KEY dynamic_series, DROP COLUMN series_id;
+DROP TABLE series_skip;
+DROP TABLE booking_series;
@@ -0,0 +1,24 @@
+-- Recurring bookings. Every occurrence is an ordinary row of dynamic that
+-- points back at its series; skipped dates are kept in series_skip.
+CREATE TABLE booking_series (
+    id INT AUTO_INCREMENT,
+    class_id CHAR(4) NOT NULL,
+    start_date DATE NOT NULL,
+    until_date DATE NOT NULL,
+    interval_weeks INT NOT NULL,
+    start_slot INT NOT NULL,
+    end_slot INT NOT NULL,
+    faculty_id CHAR(254) NOT NULL,
+    subject_id CHAR(8) NOT NULL,
+    FOREIGN KEY (faculty_id) REFERENCES faculty (id),
+    FOREIGN KEY (subject_id) REFERENCES subject (id),
+    PRIMARY KEY (id)
+);
+CREATE TABLE series_skip (
+    series_id INT,
+    date DATE,
+    FOREIGN KEY (series_id) REFERENCES booking_series (id),
+    PRIMARY KEY (series_id, date)
+);
+ALTER TABLE dynamic ADD COLUMN series_id INT,
+    ADD CONSTRAINT dynamic_series FOREIGN KEY (series_id) REFERENCES booking_series (id);
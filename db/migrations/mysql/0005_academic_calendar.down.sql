@@ -0,0 +1,3 @@
+DROP TABLE day_order;
+DROP TABLE holiday;
+DROP TABLE term;
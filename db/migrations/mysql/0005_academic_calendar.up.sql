@@ -0,0 +1,19 @@
+-- The academic calendar: terms, holidays and days that follow the timetable
+-- of another day
+CREATE TABLE term (
+    name VARCHAR(64),
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    PRIMARY KEY (name)
+);
+CREATE TABLE holiday (
+    start_date DATE,
+    end_date DATE NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    PRIMARY KEY (start_date)
+);
+CREATE TABLE day_order (
+    date DATE,
+    day ENUM ("MON", "TUE", "WED", "THU", "FRI") NOT NULL,
+    PRIMARY KEY (date)
+);
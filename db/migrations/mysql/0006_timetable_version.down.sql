@@ -0,0 +1,6 @@
+-- Only the default version fits the old key
+DELETE FROM static WHERE version != "default";
+ALTER TABLE static DROP FOREIGN KEY static_version;
+ALTER TABLE static DROP PRIMARY KEY, DROP COLUMN version,
+    ADD PRIMARY KEY (class_id, day, slot_id);
+DROP TABLE timetable_version;
@@ -0,0 +1,14 @@
+-- Named versions of the static timetable, e.g. one per semester. The
+-- timetable so far becomes the "default" version, in force on every date.
+CREATE TABLE timetable_version (
+    name VARCHAR(64),
+    start_date DATE,
+    end_date DATE,
+    published BOOLEAN NOT NULL,
+    PRIMARY KEY (name)
+);
+INSERT INTO timetable_version (name, published) VALUES ("default", TRUE);
+ALTER TABLE static ADD COLUMN version VARCHAR(64) NOT NULL DEFAULT "default" FIRST,
+    DROP PRIMARY KEY,
+    ADD PRIMARY KEY (version, class_id, day, slot_id),
+    ADD CONSTRAINT static_version FOREIGN KEY (version) REFERENCES timetable_version (name);
@@ -0,0 +1,39 @@
+-- SQLite version of the MySQL 0001_init.up.sql
+CREATE TABLE IF NOT EXISTS slot (
+    id INTEGER,
+    stime TEXT NOT NULL,
+    etime TEXT NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE TABLE IF NOT EXISTS subject (
+    id TEXT,
+    name TEXT NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE TABLE IF NOT EXISTS faculty (
+    id TEXT,
+    name TEXT NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE TABLE IF NOT EXISTS static (
+    class_id TEXT,
+    day TEXT CHECK (day IN ('MON', 'TUE', 'WED', 'THU', 'FRI')),
+    slot_id INTEGER,
+    faculty_id TEXT,
+    subject_id TEXT,
+    FOREIGN KEY (slot_id) REFERENCES slot (id),
+    FOREIGN KEY (faculty_id) REFERENCES faculty (id),
+    FOREIGN KEY (subject_id) REFERENCES subject (id),
+    PRIMARY KEY (class_id, day, slot_id)
+);
+CREATE TABLE IF NOT EXISTS dynamic (
+    class_id TEXT,
+    date DATE,
+    slot_id INTEGER,
+    faculty_id TEXT NOT NULL,
+    subject_id TEXT NOT NULL,
+    FOREIGN KEY (faculty_id) REFERENCES faculty (id),
+    FOREIGN KEY (slot_id) REFERENCES slot (id),
+    FOREIGN KEY (subject_id) REFERENCES subject (id),
+    PRIMARY KEY (class_id, date, slot_id)
+);
@@ -0,0 +1,14 @@
+-- SQLite version of the MySQL 0002_api_token.up.sql
+CREATE TABLE api_token (
+    id TEXT,
+    hash TEXT NOT NULL,
+    name TEXT NOT NULL,
+    scope TEXT NOT NULL CHECK (scope IN ('read', 'book', 'admin')),
+    faculty_id TEXT,
+    created_by TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    last_used_at DATETIME,
+    FOREIGN KEY (faculty_id) REFERENCES faculty (id),
+    UNIQUE (hash),
+    PRIMARY KEY (id)
+);
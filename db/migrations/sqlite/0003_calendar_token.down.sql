@@ -0,0 +1,16 @@
+CREATE TABLE api_token_old (
+    id TEXT,
+    hash TEXT NOT NULL,
+    name TEXT NOT NULL,
+    scope TEXT NOT NULL CHECK (scope IN ('read', 'book', 'admin')),
+    faculty_id TEXT,
+    created_by TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    last_used_at DATETIME,
+    FOREIGN KEY (faculty_id) REFERENCES faculty (id),
+    UNIQUE (hash),
+    PRIMARY KEY (id)
+);
+INSERT INTO api_token_old SELECT * FROM api_token WHERE scope != 'calendar';
+DROP TABLE api_token;
+ALTER TABLE api_token_old RENAME TO api_token;
@@ -0,0 +1,18 @@
+-- SQLite version of the MySQL 0003_calendar_token.up.sql. A CHECK constraint
+-- can't be altered, so the table is copied.
+CREATE TABLE api_token_new (
+    id TEXT,
+    hash TEXT NOT NULL,
+    name TEXT NOT NULL,
+    scope TEXT NOT NULL CHECK (scope IN ('read', 'book', 'admin', 'calendar')),
+    faculty_id TEXT,
+    created_by TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    last_used_at DATETIME,
+    FOREIGN KEY (faculty_id) REFERENCES faculty (id),
+    UNIQUE (hash),
+    PRIMARY KEY (id)
+);
+INSERT INTO api_token_new SELECT * FROM api_token;
+DROP TABLE api_token;
+ALTER TABLE api_token_new RENAME TO api_token;
@@ -0,0 +1,17 @@
+-- A column used by a foreign key can't be dropped, so the table is copied
+CREATE TABLE dynamic_old (
+    class_id TEXT,
+    date DATE,
+    slot_id INTEGER,
+    faculty_id TEXT NOT NULL,
+    subject_id TEXT NOT NULL,
+    FOREIGN KEY (faculty_id) REFERENCES faculty (id),
+    FOREIGN KEY (slot_id) REFERENCES slot (id),
+    FOREIGN KEY (subject_id) REFERENCES subject (id),
+    PRIMARY KEY (class_id, date, slot_id)
+);
+INSERT INTO dynamic_old SELECT class_id, date, slot_id, faculty_id, subject_id FROM dynamic;
+DROP TABLE dynamic;
+ALTER TABLE dynamic_old RENAME TO dynamic;
+DROP TABLE series_skip;
+DROP TABLE booking_series;
@@ -0,0 +1,22 @@
+-- SQLite version of the MySQL 0004_booking_series.up.sql
+CREATE TABLE booking_series (
+    id INTEGER,
+    class_id TEXT NOT NULL,
+    start_date DATE NOT NULL,
+    until_date DATE NOT NULL,
+    interval_weeks INTEGER NOT NULL,
+    start_slot INTEGER NOT NULL,
+    end_slot INTEGER NOT NULL,
+    faculty_id TEXT NOT NULL,
+    subject_id TEXT NOT NULL,
+    FOREIGN KEY (faculty_id) REFERENCES faculty (id),
+    FOREIGN KEY (subject_id) REFERENCES subject (id),
+    PRIMARY KEY (id)
+);
+CREATE TABLE series_skip (
+    series_id INTEGER,
+    date DATE,
+    FOREIGN KEY (series_id) REFERENCES booking_series (id),
+    PRIMARY KEY (series_id, date)
+);
+ALTER TABLE dynamic ADD COLUMN series_id INTEGER REFERENCES booking_series (id);
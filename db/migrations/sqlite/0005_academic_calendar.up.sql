@@ -0,0 +1,18 @@
+-- SQLite version of the MySQL 0005_academic_calendar.up.sql
+CREATE TABLE term (
+    name TEXT,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    PRIMARY KEY (name)
+);
+CREATE TABLE holiday (
+    start_date DATE,
+    end_date DATE NOT NULL,
+    name TEXT NOT NULL,
+    PRIMARY KEY (start_date)
+);
+CREATE TABLE day_order (
+    date DATE,
+    day TEXT NOT NULL CHECK (day IN ('MON', 'TUE', 'WED', 'THU', 'FRI')),
+    PRIMARY KEY (date)
+);
@@ -0,0 +1,17 @@
+-- Only the default version fits the old key
+CREATE TABLE static_old (
+    class_id TEXT,
+    day TEXT CHECK (day IN ('MON', 'TUE', 'WED', 'THU', 'FRI')),
+    slot_id INTEGER,
+    faculty_id TEXT,
+    subject_id TEXT,
+    FOREIGN KEY (slot_id) REFERENCES slot (id),
+    FOREIGN KEY (faculty_id) REFERENCES faculty (id),
+    FOREIGN KEY (subject_id) REFERENCES subject (id),
+    PRIMARY KEY (class_id, day, slot_id)
+);
+INSERT INTO static_old SELECT class_id, day, slot_id, faculty_id, subject_id
+    FROM static WHERE version = 'default';
+DROP TABLE static;
+ALTER TABLE static_old RENAME TO static;
+DROP TABLE timetable_version;
@@ -0,0 +1,27 @@
+-- SQLite version of the MySQL 0006_timetable_version.up.sql. A primary key
+-- can't be altered, so the static table is copied.
+CREATE TABLE timetable_version (
+    name TEXT,
+    start_date DATE,
+    end_date DATE,
+    published INTEGER NOT NULL,
+    PRIMARY KEY (name)
+);
+INSERT INTO timetable_version (name, published) VALUES ('default', 1);
+CREATE TABLE static_new (
+    version TEXT NOT NULL DEFAULT 'default',
+    class_id TEXT,
+    day TEXT CHECK (day IN ('MON', 'TUE', 'WED', 'THU', 'FRI')),
+    slot_id INTEGER,
+    faculty_id TEXT,
+    subject_id TEXT,
+    FOREIGN KEY (version) REFERENCES timetable_version (name),
+    FOREIGN KEY (slot_id) REFERENCES slot (id),
+    FOREIGN KEY (faculty_id) REFERENCES faculty (id),
+    FOREIGN KEY (subject_id) REFERENCES subject (id),
+    PRIMARY KEY (version, class_id, day, slot_id)
+);
+INSERT INTO static_new (class_id, day, slot_id, faculty_id, subject_id)
+    SELECT class_id, day, slot_id, faculty_id, subject_id FROM static;
+DROP TABLE static;
+ALTER TABLE static_new RENAME TO static;
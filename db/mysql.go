@@ -0,0 +1,83 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// DefaultDSN is the MySQL data source name used when the configuration does
+// not provide one. It matches the account the server has always connected
+// with.
+const DefaultDSN = "cora:@/cora_db?parseTime=true"
+
+var mysqlDialect = dialect{translate: mysqlError, forUpdate: " FOR UPDATE"}
+
+func openMySQL(cfg Config) (*sqlStore, error) {
+	db, err := connectMySQL(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newSQLStore(db, mysqlDialect)
+}
+
+// connectMySQL sets up the connection pool described by cfg
+func connectMySQL(cfg Config) (*sql.DB, error) {
+	dsn := cfg.DSN
+	if dsn == "" {
+		dsn = DefaultDSN
+	}
+	// Round trip through the driver's parser so that a malformed DSN is
+	// reported here rather than on the first query
+	mysqlCfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	// BookingRecord.Date is scanned into a time.Time
+	mysqlCfg.ParseTime = true
+	db, err := sql.Open("mysql", mysqlCfg.FormatDSN())
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetime) * time.Second)
+	}
+	return db, nil
+}
+
+/*
+mysqlError translates a driver error into one of the package sentinels.
+Errors reported by the server itself are classified by their error number;
+anything that never reached the server (a refused or dropped connection) is
+treated as the database being unavailable.
+*/
+func mysqlError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		if errors.Is(err, mysql.ErrInvalidConn) {
+			return fmt.Errorf("%w: %v", ErrUnavailable, err)
+		}
+		return commonError(err)
+	}
+	switch mysqlErr.Number {
+	// ER_DUP_ENTRY
+	case 1062:
+		return fmt.Errorf("%w: %v", ErrConflict, err)
+	// ER_NO_REFERENCED_ROW, ER_NO_REFERENCED_ROW_2
+	case 1216, 1452:
+		return fmt.Errorf("%w: %v", ErrNotFound, err)
+	// ER_LOCK_WAIT_TIMEOUT, ER_LOCK_DEADLOCK
+	case 1205, 1213:
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return err
+}
@@ -68,280 +68,280 @@ INSERT INTO faculty VALUES ("v_ananthanarayanan@cb.amrita.edu","Dr.Anantha Naray
 INSERT INTO faculty VALUES ("v_dayanand@cb.amrita.edu","Dayanand.V");
 
 --CSEA;
-INSERT INTO static VALUES ( "C203", "MON", 1, "s_padmavathi@cb.amrita.edu", "19CSE435");
-INSERT INTO static VALUES ( "C203", "MON", 2, "n_harini@cb.amrita.edu", "19CSE311");
-INSERT INTO static VALUES ( "C203", "MON", 3, "g_jeyakumar@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "C203", "MON", 4, "r_aarthi@cb.amrita.edu", "19CSE434");
-INSERT INTO static VALUES ( "C203", "MON", 5,"FREE", "FREE");
-INSERT INTO static VALUES ( "C203", "MON", 6, "g_jeyakumar@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "C203", "MON", 7, "g_jeyakumar@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "C203", "MON", 8,"FREE", "FREE");
-
-INSERT INTO static VALUES ( "C203", "TUE", 1, "tr_swapna@cb.amrita.edu", "19CSE313");
-INSERT INTO static VALUES ( "C203", "TUE", 2, "g_jeyakumar@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "C203", "TUE", 3, "c_arunkumar@cb.amrita.edu", "19CSE314");
-INSERT INTO static VALUES ( "C203", "TUE", 4, "c_arunkumar@cb.amrita.edu", "19CSE314");
-INSERT INTO static VALUES ( "C203", "TUE", 5,"FREE", "FREE");
-INSERT INTO static VALUES ( "C203", "TUE", 6,"FREE", "FREE");
-INSERT INTO static VALUES ( "C203", "TUE", 7,"FREE", "FREE");
-INSERT INTO static VALUES ( "C203", "TUE", 8,"FREE", "FREE");
-
-INSERT INTO static VALUES ( "C203", "WED", 1,"FREE", "FREE");
-INSERT INTO static VALUES ( "C203", "WED", 2,"FREE", "FREE");
-INSERT INTO static VALUES ( "C203", "WED", 3,"FREE", "FREE");
-INSERT INTO static VALUES ( "C203", "WED", 4, "s_padmavathi@cb.amrita.edu", "19CSE435");
-INSERT INTO static VALUES ( "C203", "WED", 5, "c_arunkumar@cb.amrita.edu", "19CSE314");
-INSERT INTO static VALUES ( "C203", "WED", 6,"FREE", "FREE");
-INSERT INTO static VALUES ( "C203", "WED", 7, "r_aarthi@cb.amrita.edu", "19CSE434");
-INSERT INTO static VALUES ( "C203", "WED", 8, "r_aarthi@cb.amrita.edu", "19CSE434");
-
-INSERT INTO static VALUES ( "C203", "THU", 1, "n_harini@cb.amrita.edu", "19CSE311");
-INSERT INTO static VALUES ( "C203", "THU", 2, "g_jeyakumar@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "C203", "THU", 3, "tr_swapna@cb.amrita.edu", "19CSE313");
-INSERT INTO static VALUES ( "C203", "THU", 4, "tr_swapna@cb.amrita.edu", "19CSE313");
-INSERT INTO static VALUES ( "C203", "THU", 5,"FREE", "FREE");
-INSERT INTO static VALUES ( "C203", "THU", 6,"FREE", "FREE");
-INSERT INTO static VALUES ( "C203", "THU", 7, "s_padmavathi@cb.amrita.edu", "19CSE435");
-INSERT INTO static VALUES ( "C203", "THU", 8, "s_padmavathi@cb.amrita.edu", "19CSE435");
-
-INSERT INTO static VALUES ( "C203", "FRI", 1, "n_harini@cb.amrita.edu", "19CSE311");
-INSERT INTO static VALUES ( "C203", "FRI", 2,"FREE", "FREE");
-INSERT INTO static VALUES ( "C203", "FRI", 3, "r_aarthi@cb.amrita.edu", "19CSE434");
-INSERT INTO static VALUES ( "C203", "FRI", 4, "c_arunkumar@cb.amrita.edu", "19CSE314");
-INSERT INTO static VALUES ( "C203", "FRI", 5, "tr_swapna@cb.amrita.edu", "19CSE313");
-INSERT INTO static VALUES ( "C203", "FRI", 6,"FREE", "FREE");
-INSERT INTO static VALUES ( "C203", "FRI", 7,"FREE", "FREE");
-INSERT INTO static VALUES ( "C203", "FRI", 8,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "MON", 1, "s_padmavathi@cb.amrita.edu", "19CSE435");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "MON", 2, "n_harini@cb.amrita.edu", "19CSE311");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "MON", 3, "g_jeyakumar@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "MON", 4, "r_aarthi@cb.amrita.edu", "19CSE434");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "MON", 5,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "MON", 6, "g_jeyakumar@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "MON", 7, "g_jeyakumar@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "MON", 8,"FREE", "FREE");
+
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "TUE", 1, "tr_swapna@cb.amrita.edu", "19CSE313");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "TUE", 2, "g_jeyakumar@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "TUE", 3, "c_arunkumar@cb.amrita.edu", "19CSE314");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "TUE", 4, "c_arunkumar@cb.amrita.edu", "19CSE314");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "TUE", 5,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "TUE", 6,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "TUE", 7,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "TUE", 8,"FREE", "FREE");
+
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "WED", 1,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "WED", 2,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "WED", 3,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "WED", 4, "s_padmavathi@cb.amrita.edu", "19CSE435");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "WED", 5, "c_arunkumar@cb.amrita.edu", "19CSE314");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "WED", 6,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "WED", 7, "r_aarthi@cb.amrita.edu", "19CSE434");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "WED", 8, "r_aarthi@cb.amrita.edu", "19CSE434");
+
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "THU", 1, "n_harini@cb.amrita.edu", "19CSE311");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "THU", 2, "g_jeyakumar@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "THU", 3, "tr_swapna@cb.amrita.edu", "19CSE313");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "THU", 4, "tr_swapna@cb.amrita.edu", "19CSE313");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "THU", 5,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "THU", 6,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "THU", 7, "s_padmavathi@cb.amrita.edu", "19CSE435");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "THU", 8, "s_padmavathi@cb.amrita.edu", "19CSE435");
+
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "FRI", 1, "n_harini@cb.amrita.edu", "19CSE311");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "FRI", 2,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "FRI", 3, "r_aarthi@cb.amrita.edu", "19CSE434");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "FRI", 4, "c_arunkumar@cb.amrita.edu", "19CSE314");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "FRI", 5, "tr_swapna@cb.amrita.edu", "19CSE313");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "FRI", 6,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "FRI", 7,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C203", "FRI", 8,"FREE", "FREE");
 
 
 --CSEB;
-INSERT INTO static VALUES ( "C103", "MON", 1, "v_dayanand@cb.amrita.edu", "19CSE356");
-INSERT INTO static VALUES ( "C103", "MON", 2, "k_raghesh@cb.amrita.edu", "19CSE313");
-INSERT INTO static VALUES ( "C103", "MON", 3, "n_lalitha@cb.amrita.edu", "19CSE314");
-INSERT INTO static VALUES ( "C103", "MON", 4, "mr_neethu@cb.amrita.edu", "19CSE332");
-INSERT INTO static VALUES ( "C103", "MON", 5,"FREE", "FREE");
-INSERT INTO static VALUES ( "C103", "MON", 6, "p_remyakrishnan@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "C103", "MON", 7, "p_remyakrishnan@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "C103", "MON", 8,"FREE", "FREE");
-
-INSERT INTO static VALUES ( "C103", "TUE", 1, "p_remyakrishnan@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "C103", "TUE", 2, "m_senthil@cb.amrita.edu", "19CSE311");
-INSERT INTO static VALUES ( "C103", "TUE", 3, "n_lalitha@cb.amrita.edu", "19CSE314");
-INSERT INTO static VALUES ( "C103", "TUE", 4, "k_raghesh@cb.amrita.edu", "19CSE313");
-INSERT INTO static VALUES ( "C103", "TUE", 5,"FREE", "FREE");
-INSERT INTO static VALUES ( "C103", "TUE", 6, "n_lalitha@cb.amrita.edu", "19CSE314");
-INSERT INTO static VALUES ( "C103", "TUE", 7, "n_lalitha@cb.amrita.edu", "19CSE314");
-INSERT INTO static VALUES ( "C103", "TUE", 8,"FREE", "FREE");
-
-INSERT INTO static VALUES ( "C103", "WED", 1,"FREE", "FREE");
-INSERT INTO static VALUES ( "C103", "WED", 2,"FREE", "FREE");
-INSERT INTO static VALUES ( "C103", "WED", 3,"FREE", "FREE");
-INSERT INTO static VALUES ( "C103", "WED", 4, "v_dayanand@cb.amrita.edu", "19CSE356");
-INSERT INTO static VALUES ( "C103", "WED", 5,"FREE", "FREE");
-INSERT INTO static VALUES ( "C103", "WED", 6,"FREE", "FREE");
-INSERT INTO static VALUES ( "C103", "WED", 7, "mr_neethu@cb.amrita.edu", "19CSE332");
-INSERT INTO static VALUES ( "C103", "WED", 8, "mr_neethu@cb.amrita.edu", "19CSE332");
-
-INSERT INTO static VALUES ( "C103", "THU", 1, "k_raghesh@cb.amrita.edu", "19CSE313");
-INSERT INTO static VALUES ( "C103", "THU", 2, "k_raghesh@cb.amrita.edu", "19CSE313");
-INSERT INTO static VALUES ( "C103", "THU", 3, "p_remyakrishnan@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "C103", "THU", 4, "m_senthil@cb.amrita.edu", "19CSE311");
-INSERT INTO static VALUES ( "C103", "THU", 5,"FREE", "FREE");
-INSERT INTO static VALUES ( "C103", "THU", 6,"FREE", "FREE");
-INSERT INTO static VALUES ( "C103", "THU", 7, "v_dayanand@cb.amrita.edu", "19CSE356");
-INSERT INTO static VALUES ( "C103", "THU", 8, "v_dayanand@cb.amrita.edu", "19CSE356");
-
-INSERT INTO static VALUES ( "C103", "FRI", 1,"FREE", "FREE");
-INSERT INTO static VALUES ( "C103", "FRI", 2, "m_senthil@cb.amrita.edu", "19CSE311");
-INSERT INTO static VALUES ( "C103", "FRI", 3, "mr_neethu@cb.amrita.edu", "19CSE332");
-INSERT INTO static VALUES ( "C103", "FRI", 4, "p_remyakrishnan@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "C103", "FRI", 5,"FREE", "FREE");
-INSERT INTO static VALUES ( "C103", "FRI", 6,"FREE", "FREE");
-INSERT INTO static VALUES ( "C103", "FRI", 7,"FREE", "FREE");
-INSERT INTO static VALUES ( "C103", "FRI", 8,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "MON", 1, "v_dayanand@cb.amrita.edu", "19CSE356");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "MON", 2, "k_raghesh@cb.amrita.edu", "19CSE313");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "MON", 3, "n_lalitha@cb.amrita.edu", "19CSE314");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "MON", 4, "mr_neethu@cb.amrita.edu", "19CSE332");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "MON", 5,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "MON", 6, "p_remyakrishnan@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "MON", 7, "p_remyakrishnan@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "MON", 8,"FREE", "FREE");
+
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "TUE", 1, "p_remyakrishnan@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "TUE", 2, "m_senthil@cb.amrita.edu", "19CSE311");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "TUE", 3, "n_lalitha@cb.amrita.edu", "19CSE314");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "TUE", 4, "k_raghesh@cb.amrita.edu", "19CSE313");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "TUE", 5,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "TUE", 6, "n_lalitha@cb.amrita.edu", "19CSE314");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "TUE", 7, "n_lalitha@cb.amrita.edu", "19CSE314");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "TUE", 8,"FREE", "FREE");
+
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "WED", 1,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "WED", 2,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "WED", 3,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "WED", 4, "v_dayanand@cb.amrita.edu", "19CSE356");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "WED", 5,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "WED", 6,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "WED", 7, "mr_neethu@cb.amrita.edu", "19CSE332");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "WED", 8, "mr_neethu@cb.amrita.edu", "19CSE332");
+
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "THU", 1, "k_raghesh@cb.amrita.edu", "19CSE313");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "THU", 2, "k_raghesh@cb.amrita.edu", "19CSE313");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "THU", 3, "p_remyakrishnan@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "THU", 4, "m_senthil@cb.amrita.edu", "19CSE311");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "THU", 5,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "THU", 6,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "THU", 7, "v_dayanand@cb.amrita.edu", "19CSE356");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "THU", 8, "v_dayanand@cb.amrita.edu", "19CSE356");
+
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "FRI", 1,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "FRI", 2, "m_senthil@cb.amrita.edu", "19CSE311");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "FRI", 3, "mr_neethu@cb.amrita.edu", "19CSE332");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "FRI", 4, "p_remyakrishnan@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "FRI", 5,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "FRI", 6,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "FRI", 7,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C103", "FRI", 8,"FREE", "FREE");
 --CSEC;
-INSERT INTO static VALUES ( "C104", "MON", 1, "m_anbazhagan@cb.amrita.edu", "19CSE456");
-INSERT INTO static VALUES ( "C104", "MON", 2, "kp_jevitha@cb.amrita.edu", "19CSE311");
-INSERT INTO static VALUES ( "C104", "MON", 3,"FREE", "FREE");
-INSERT INTO static VALUES ( "C104", "MON", 4, "pn_kumar@cb.amrita.edu", "19CSE352");
-INSERT INTO static VALUES ( "C104", "MON", 5,"FREE", "FREE");
-INSERT INTO static VALUES ( "C104", "MON", 6, "g_radhika@cb.amrita.edu", "19CSE313");
-INSERT INTO static VALUES ( "C104", "MON", 7, "g_radhika@cb.amrita.edu", "19CSE313");
-INSERT INTO static VALUES ( "C104", "MON", 8,"FREE", "FREE");
-
-INSERT INTO static VALUES ( "C104", "TUE", 1,"FREE", "FREE");
-INSERT INTO static VALUES ( "C104", "TUE", 2, "n_radhika@cb.amrita.edu", "19CSE314");
-INSERT INTO static VALUES ( "C104", "TUE", 3, "g_radhika@cb.amrita.edu", "19CSE313");
-INSERT INTO static VALUES ( "C104", "TUE", 4, "ss_priya@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "C104", "TUE", 5, "kp_jevitha@cb.amrita.edu", "19CSE311");
-INSERT INTO static VALUES ( "C104", "TUE", 6, "ss_priya@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "C104", "TUE", 7, "ss_priya@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "C104", "TUE", 8,"FREE", "FREE");
-
-INSERT INTO static VALUES ( "C104", "WED", 1, "g_radhika@cb.amrita.edu", "19CSE313");
-INSERT INTO static VALUES ( "C104", "WED", 2,"FREE", "FREE");
-INSERT INTO static VALUES ( "C104", "WED", 3, "kp_jevitha@cb.amrita.edu", "19CSE311");
-INSERT INTO static VALUES ( "C104", "WED", 4, "m_anbazhagan@cb.amrita.edu", "19CSE456");
-INSERT INTO static VALUES ( "C104", "WED", 5,"FREE", "FREE");
-INSERT INTO static VALUES ( "C104", "WED", 6,"FREE", "FREE");
-INSERT INTO static VALUES ( "C104", "WED", 7, "pn_kumar@cb.amrita.edu", "19CSE352");
-INSERT INTO static VALUES ( "C104", "WED", 8, "pn_kumar@cb.amrita.edu", "19CSE352");
-
-INSERT INTO static VALUES ( "C104", "THU", 1,"FREE", "FREE");
-INSERT INTO static VALUES ( "C104", "THU", 2,"FREE", "FREE");
-INSERT INTO static VALUES ( "C104", "THU", 3,"FREE", "FREE");
-INSERT INTO static VALUES ( "C104", "THU", 4,"FREE", "FREE");
-INSERT INTO static VALUES ( "C104", "THU", 5,"FREE", "FREE");
-INSERT INTO static VALUES ( "C104", "THU", 6, "ss_priya@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "C104", "THU", 7, "m_anbazhagan@cb.amrita.edu", "19CSE456");
-INSERT INTO static VALUES ( "C104", "THU", 8, "m_anbazhagan@cb.amrita.edu", "19CSE456");
-
-INSERT INTO static VALUES ( "C104", "FRI", 1,"FREE", "FREE");
-INSERT INTO static VALUES ( "C104", "FRI", 2, "ss_priya@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "C104", "FRI", 3, "pn_kumar@cb.amrita.edu", "19CSE352");
-INSERT INTO static VALUES ( "C104", "FRI", 4, "n_radhika@cb.amrita.edu", "19CSE314");
-INSERT INTO static VALUES ( "C104", "FRI", 5,"FREE", "FREE");
-INSERT INTO static VALUES ( "C104", "FRI", 6, "n_radhika@cb.amrita.edu", "19CSE314");
-INSERT INTO static VALUES ( "C104", "FRI", 7, "n_radhika@cb.amrita.edu", "19CSE314");
-INSERT INTO static VALUES ( "C104", "FRI", 8,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "MON", 1, "m_anbazhagan@cb.amrita.edu", "19CSE456");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "MON", 2, "kp_jevitha@cb.amrita.edu", "19CSE311");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "MON", 3,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "MON", 4, "pn_kumar@cb.amrita.edu", "19CSE352");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "MON", 5,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "MON", 6, "g_radhika@cb.amrita.edu", "19CSE313");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "MON", 7, "g_radhika@cb.amrita.edu", "19CSE313");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "MON", 8,"FREE", "FREE");
+
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "TUE", 1,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "TUE", 2, "n_radhika@cb.amrita.edu", "19CSE314");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "TUE", 3, "g_radhika@cb.amrita.edu", "19CSE313");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "TUE", 4, "ss_priya@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "TUE", 5, "kp_jevitha@cb.amrita.edu", "19CSE311");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "TUE", 6, "ss_priya@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "TUE", 7, "ss_priya@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "TUE", 8,"FREE", "FREE");
+
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "WED", 1, "g_radhika@cb.amrita.edu", "19CSE313");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "WED", 2,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "WED", 3, "kp_jevitha@cb.amrita.edu", "19CSE311");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "WED", 4, "m_anbazhagan@cb.amrita.edu", "19CSE456");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "WED", 5,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "WED", 6,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "WED", 7, "pn_kumar@cb.amrita.edu", "19CSE352");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "WED", 8, "pn_kumar@cb.amrita.edu", "19CSE352");
+
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "THU", 1,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "THU", 2,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "THU", 3,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "THU", 4,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "THU", 5,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "THU", 6, "ss_priya@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "THU", 7, "m_anbazhagan@cb.amrita.edu", "19CSE456");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "THU", 8, "m_anbazhagan@cb.amrita.edu", "19CSE456");
+
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "FRI", 1,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "FRI", 2, "ss_priya@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "FRI", 3, "pn_kumar@cb.amrita.edu", "19CSE352");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "FRI", 4, "n_radhika@cb.amrita.edu", "19CSE314");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "FRI", 5,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "FRI", 6, "n_radhika@cb.amrita.edu", "19CSE314");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "FRI", 7, "n_radhika@cb.amrita.edu", "19CSE314");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C104", "FRI", 8,"FREE", "FREE");
 
 
 --CSED;
-INSERT INTO static VALUES ( "A102", "MON", 1, "b_vidhya@cb.amrita.edu", "19CSE441");
-INSERT INTO static VALUES ( "A102", "MON", 2, "cs_velayutham@cb.amrita.edu", "19CSE313");
-INSERT INTO static VALUES ( "A102", "MON", 3, "ss_priya@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "A102", "MON", 4, "k_nalinadevi@cb.amrita.edu", "19CSE446");
-INSERT INTO static VALUES ( "A102", "MON", 5, "n_radhika@cb.amrita.edu", "19CSE314");
-INSERT INTO static VALUES ( "A102", "MON", 6, "ss_priya@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "A102", "MON", 7, "ss_priya@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "A102", "MON", 8,"FREE", "FREE");
-
-INSERT INTO static VALUES ( "A102", "TUE", 1, "cs_velayutham@cb.amrita.edu", "19CSE313");
-INSERT INTO static VALUES ( "A102", "TUE", 2, "cs_velayutham@cb.amrita.edu", "19CSE313");
-INSERT INTO static VALUES ( "A102", "TUE", 3,"FREE", "FREE");
-INSERT INTO static VALUES ( "A102", "TUE", 4, "m_ritwik@cb.amrita.edu", "19CSE311");
-INSERT INTO static VALUES ( "A102", "TUE", 5,"FREE", "FREE");
-INSERT INTO static VALUES ( "A102", "TUE", 6,"FREE", "FREE");
-INSERT INTO static VALUES ( "A102", "TUE", 7,"FREE", "FREE");
-INSERT INTO static VALUES ( "A102", "TUE", 8,"FREE", "FREE");
-
-INSERT INTO static VALUES ( "A102", "WED", 1, "n_radhika@cb.amrita.edu", "19CSE314");
-INSERT INTO static VALUES ( "A102", "WED", 2, "n_radhika@cb.amrita.edu", "19CSE314");
-INSERT INTO static VALUES ( "A102", "WED", 3,"FREE", "FREE");
-INSERT INTO static VALUES ( "A102", "WED", 4, "k_nalinadevi@cb.amrita.edu", "19CSE446");
-INSERT INTO static VALUES ( "A102", "WED", 5,"FREE", "FREE");
-INSERT INTO static VALUES ( "A102", "WED", 6, "ss_priya@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "A102", "WED", 7,"FREE", "FREE");
-INSERT INTO static VALUES ( "A102", "WED", 8,"FREE", "FREE");
-
-INSERT INTO static VALUES ( "A102", "THU", 1,"FREE", "FREE");
-INSERT INTO static VALUES ( "A102", "THU", 2,"FREE", "FREE");
-INSERT INTO static VALUES ( "A102", "THU", 3,"FREE", "FREE");
-INSERT INTO static VALUES ( "A102", "THU", 4, "m_ritwik@cb.amrita.edu", "19CSE311");
-INSERT INTO static VALUES ( "A102", "THU", 5,"FREE", "FREE");
-INSERT INTO static VALUES ( "A102", "THU", 6, "ss_priya@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "A102", "THU", 7, "m_anbazhagan@cb.amrita.edu", "19CSE456");
-INSERT INTO static VALUES ( "A102", "THU", 8, "m_anbazhagan@cb.amrita.edu", "19CSE456");
-
-INSERT INTO static VALUES ( "A102", "FRI", 1, "cs_velayutham@cb.amrita.edu", "19CSE313");
-INSERT INTO static VALUES ( "A102", "FRI", 2, "m_ritwik@cb.amrita.edu", "19CSE311");
-INSERT INTO static VALUES ( "A102", "FRI", 3, "k_nalinadevi@cb.amrita.edu", "19CSE446");
-INSERT INTO static VALUES ( "A102", "FRI", 4, "ss_priya@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "A102", "FRI", 5,"FREE", "FREE");
-INSERT INTO static VALUES ( "A102", "FRI", 6,"FREE", "FREE");
-INSERT INTO static VALUES ( "A102", "FRI", 7,"FREE", "FREE");
-INSERT INTO static VALUES ( "A102", "FRI", 8,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "MON", 1, "b_vidhya@cb.amrita.edu", "19CSE441");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "MON", 2, "cs_velayutham@cb.amrita.edu", "19CSE313");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "MON", 3, "ss_priya@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "MON", 4, "k_nalinadevi@cb.amrita.edu", "19CSE446");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "MON", 5, "n_radhika@cb.amrita.edu", "19CSE314");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "MON", 6, "ss_priya@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "MON", 7, "ss_priya@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "MON", 8,"FREE", "FREE");
+
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "TUE", 1, "cs_velayutham@cb.amrita.edu", "19CSE313");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "TUE", 2, "cs_velayutham@cb.amrita.edu", "19CSE313");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "TUE", 3,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "TUE", 4, "m_ritwik@cb.amrita.edu", "19CSE311");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "TUE", 5,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "TUE", 6,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "TUE", 7,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "TUE", 8,"FREE", "FREE");
+
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "WED", 1, "n_radhika@cb.amrita.edu", "19CSE314");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "WED", 2, "n_radhika@cb.amrita.edu", "19CSE314");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "WED", 3,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "WED", 4, "k_nalinadevi@cb.amrita.edu", "19CSE446");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "WED", 5,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "WED", 6, "ss_priya@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "WED", 7,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "WED", 8,"FREE", "FREE");
+
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "THU", 1,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "THU", 2,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "THU", 3,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "THU", 4, "m_ritwik@cb.amrita.edu", "19CSE311");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "THU", 5,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "THU", 6, "ss_priya@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "THU", 7, "m_anbazhagan@cb.amrita.edu", "19CSE456");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "THU", 8, "m_anbazhagan@cb.amrita.edu", "19CSE456");
+
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "FRI", 1, "cs_velayutham@cb.amrita.edu", "19CSE313");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "FRI", 2, "m_ritwik@cb.amrita.edu", "19CSE311");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "FRI", 3, "k_nalinadevi@cb.amrita.edu", "19CSE446");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "FRI", 4, "ss_priya@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "FRI", 5,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "FRI", 6,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "FRI", 7,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A102", "FRI", 8,"FREE", "FREE");
 
 
 --CSEE;
-INSERT INTO static VALUES ( "C102", "MON", 1, "v_dayanand@cb.amrita.edu", "19CSE356");
-INSERT INTO static VALUES ( "C102", "MON", 2, "m_neethu@cb.amrita.edu", "19CSE311");
-INSERT INTO static VALUES ( "C102", "MON", 3, "r_karthi@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "C102", "MON", 4, "m_pooja@cb.amrita.edu", "19CSE332");
-INSERT INTO static VALUES ( "C102", "MON", 5,"FREE", "FREE");
-INSERT INTO static VALUES ( "C102", "MON", 6, "d_bharathi@cb.amrita.edu", "19CSE313");
-INSERT INTO static VALUES ( "C102", "MON", 7, "d_bharathi@cb.amrita.edu", "19CSE313");
-INSERT INTO static VALUES ( "C102", "MON", 8,"FREE", "FREE");
-
-INSERT INTO static VALUES ( "C102", "TUE", 1, "ba_sabarish@cb.amrita.edu", "19CSE314");
-INSERT INTO static VALUES ( "C102", "TUE", 2, "d_bharathi@cb.amrita.edu", "19CSE313");
-INSERT INTO static VALUES ( "C102", "TUE", 3, "m_neethu@cb.amrita.edu", "19CSE311");
-INSERT INTO static VALUES ( "C102", "TUE", 4, "FREE", "FREE");
-INSERT INTO static VALUES ( "C102", "TUE", 5, "FREE", "FREE");
-INSERT INTO static VALUES ( "C102", "TUE", 6, "r_karthi@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "C102", "TUE", 7, "r_karthi@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "C102", "TUE", 8, "FREE", "FREE");
-
-INSERT INTO static VALUES ( "C102", "WED", 1, "m_neethu@cb.amrita.edu", "19CSE311");
-INSERT INTO static VALUES ( "C102", "WED", 2, "ba_sabarish@cb.amrita.edu", "19CSE314");
-INSERT INTO static VALUES ( "C102", "WED", 3, "d_bharathi@cb.amrita.edu", "19CSE313");
-INSERT INTO static VALUES ( "C102", "WED", 4, "v_dayanand@cb.amrita.edu", "19CSE356");
-INSERT INTO static VALUES ( "C102", "WED", 5, "FREE", "FREE");
-INSERT INTO static VALUES ( "C102", "WED", 6, "FREE", "FREE");
-INSERT INTO static VALUES ( "C102", "WED", 7, "m_pooja@cb.amrita.edu", "19CSE332");
-INSERT INTO static VALUES ( "C102", "WED", 8, "m_pooja@cb.amrita.edu", "19CSE332");
-
-INSERT INTO static VALUES ( "C102", "THU", 1, "ba_sabarish@cb.amrita.edu", "19CSE314");
-INSERT INTO static VALUES ( "C102", "THU", 2, "ba_sabarish@cb.amrita.edu", "19CSE314");
-INSERT INTO static VALUES ( "C102", "THU", 3, "FREE", "FREE");
-INSERT INTO static VALUES ( "C102", "THU", 4, "r_karthi@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "C102", "THU", 5, "FREE", "FREE");
-INSERT INTO static VALUES ( "C102", "THU", 6, "FREE", "FREE");
-INSERT INTO static VALUES ( "C102", "THU", 7, "v_dayanand@cb.amrita.edu", "19CSE356");
-INSERT INTO static VALUES ( "C102", "THU", 8, "v_dayanand@cb.amrita.edu", "19CSE356");
-
-INSERT INTO static VALUES ( "C102", "FRI", 1, "FREE", "FREE");
-INSERT INTO static VALUES ( "C102", "FRI", 2, "r_karthi@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "C102", "FRI", 3, "m_pooja@cb.amrita.edu", "19CSE332");
-INSERT INTO static VALUES ( "C102", "FRI", 4, "FREE", "FREE");
-INSERT INTO static VALUES ( "C102", "FRI", 5, "FREE", "FREE");
-INSERT INTO static VALUES ( "C102", "FRI", 6, "FREE", "FREE");
-INSERT INTO static VALUES ( "C102", "FRI", 7, "FREE", "FREE");
-INSERT INTO static VALUES ( "C102", "FRI", 8, "FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "MON", 1, "v_dayanand@cb.amrita.edu", "19CSE356");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "MON", 2, "m_neethu@cb.amrita.edu", "19CSE311");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "MON", 3, "r_karthi@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "MON", 4, "m_pooja@cb.amrita.edu", "19CSE332");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "MON", 5,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "MON", 6, "d_bharathi@cb.amrita.edu", "19CSE313");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "MON", 7, "d_bharathi@cb.amrita.edu", "19CSE313");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "MON", 8,"FREE", "FREE");
+
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "TUE", 1, "ba_sabarish@cb.amrita.edu", "19CSE314");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "TUE", 2, "d_bharathi@cb.amrita.edu", "19CSE313");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "TUE", 3, "m_neethu@cb.amrita.edu", "19CSE311");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "TUE", 4, "FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "TUE", 5, "FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "TUE", 6, "r_karthi@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "TUE", 7, "r_karthi@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "TUE", 8, "FREE", "FREE");
+
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "WED", 1, "m_neethu@cb.amrita.edu", "19CSE311");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "WED", 2, "ba_sabarish@cb.amrita.edu", "19CSE314");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "WED", 3, "d_bharathi@cb.amrita.edu", "19CSE313");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "WED", 4, "v_dayanand@cb.amrita.edu", "19CSE356");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "WED", 5, "FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "WED", 6, "FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "WED", 7, "m_pooja@cb.amrita.edu", "19CSE332");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "WED", 8, "m_pooja@cb.amrita.edu", "19CSE332");
+
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "THU", 1, "ba_sabarish@cb.amrita.edu", "19CSE314");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "THU", 2, "ba_sabarish@cb.amrita.edu", "19CSE314");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "THU", 3, "FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "THU", 4, "r_karthi@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "THU", 5, "FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "THU", 6, "FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "THU", 7, "v_dayanand@cb.amrita.edu", "19CSE356");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "THU", 8, "v_dayanand@cb.amrita.edu", "19CSE356");
+
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "FRI", 1, "FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "FRI", 2, "r_karthi@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "FRI", 3, "m_pooja@cb.amrita.edu", "19CSE332");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "FRI", 4, "FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "FRI", 5, "FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "FRI", 6, "FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "FRI", 7, "FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "C102", "FRI", 8, "FREE", "FREE");
 
 
 --CSEF;
-INSERT INTO static VALUES ( "A104", "MON", 1, "gr_ramya@cb.amrita.edu", "19CSE356");
-INSERT INTO static VALUES ( "A104", "MON", 2, "s_vidhya@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "A104", "MON", 3, "d_bharathi@cb.amrita.edu", "19CSE313");
-INSERT INTO static VALUES ( "A104", "MON", 4, "k_nalinadevi@cb.amrita.edu", "19CSE446");
-INSERT INTO static VALUES ( "A104", "MON", 5,"FREE", "FREE");
-INSERT INTO static VALUES ( "A104", "MON", 6, "s_vidhya@cb.amrita.edu","19CSE312");
-INSERT INTO static VALUES ( "A104", "MON", 7, "s_vidhya@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "A104", "MON", 8,"FREE", "FREE");
-
-INSERT INTO static VALUES ( "A104", "TUE", 1, "s_vidhya@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "A104", "TUE", 2, "t_gireeshkumar@cb.amrita.edu", "19CSE311");
-INSERT INTO static VALUES ( "A104", "TUE", 3, "j_guruprakash@cb.amrita.edu", "19CSE314");
-INSERT INTO static VALUES ( "A104", "TUE", 4, "j_guruprakash@cb.amrita.edu", "19CSE314");
-INSERT INTO static VALUES ( "A104", "TUE", 5,"FREE", "FREE");
-INSERT INTO static VALUES ( "A104", "TUE", 6,"FREE", "FREE");
-INSERT INTO static VALUES ( "A104", "TUE", 7,"FREE", "FREE");
-INSERT INTO static VALUES ( "A104", "TUE", 8,"FREE", "FREE");
-
-INSERT INTO static VALUES ( "A104", "WED", 1, "d_bharathi@cb.amrita.edu", "19CSE313");
-INSERT INTO static VALUES ( "A104", "WED", 2,"FREE", "FREE");
-INSERT INTO static VALUES ( "A104", "WED", 3, "t_gireeshkumar@cb.amrita.edu", "19CSE311");
-INSERT INTO static VALUES ( "A104", "WED", 4, "gr_ramya@cb.amrita.edu", "19CSE356");
-INSERT INTO static VALUES ( "A104", "WED", 5, "j_guruprakash@cb.amrita.edu", "19CSE314");
-INSERT INTO static VALUES ( "A104", "WED", 6,"FREE", "FREE");
-INSERT INTO static VALUES ( "A104", "WED", 7,"FREE", "FREE");
-INSERT INTO static VALUES ( "A104", "WED", 8,"FREE", "FREE");
-
-INSERT INTO static VALUES ( "A104", "THU", 1, "d_bharathi@cb.amrita.edu", "19CSE313");
-INSERT INTO static VALUES ( "A104", "THU", 2, "d_bharathi@cb.amrita.edu", "19CSE313");
-INSERT INTO static VALUES ( "A104", "THU", 3,"FREE", "FREE");
-INSERT INTO static VALUES ( "A104", "THU", 4, "t_gireeshkumar@cb.amrita.edu", "19CSE311");
-INSERT INTO static VALUES ( "A104", "THU", 5,"FREE", "FREE");
-INSERT INTO static VALUES ( "A104", "THU", 6,"FREE", "FREE");
-INSERT INTO static VALUES ( "A104", "THU", 7, "gr_ramya@cb.amrita.edu", "19CSE356");
-INSERT INTO static VALUES ( "A104", "THU", 8, "gr_ramya@cb.amrita.edu", "19CSE356");
-
-INSERT INTO static VALUES ( "A104", "FRI", 1, "v_dayanand@cb.amrita.edu", "19CSE314");
-INSERT INTO static VALUES ( "A104", "FRI", 2, "v_dayanand@cb.amrita.edu", "19CSE312");
-INSERT INTO static VALUES ( "A104", "FRI", 3, "k_nalinadevi@cb.amrita.edu", "19CSE446");
-INSERT INTO static VALUES ( "A104", "FRI", 4,"FREE", "FREE");
-INSERT INTO static VALUES ( "A104", "FRI", 5,"FREE", "FREE");
-INSERT INTO static VALUES ( "A104", "FRI", 6,"FREE", "FREE");
-INSERT INTO static VALUES ( "A104", "FRI", 7,"FREE", "FREE");
-INSERT INTO static VALUES ( "A104", "FRI", 8,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "MON", 1, "gr_ramya@cb.amrita.edu", "19CSE356");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "MON", 2, "s_vidhya@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "MON", 3, "d_bharathi@cb.amrita.edu", "19CSE313");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "MON", 4, "k_nalinadevi@cb.amrita.edu", "19CSE446");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "MON", 5,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "MON", 6, "s_vidhya@cb.amrita.edu","19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "MON", 7, "s_vidhya@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "MON", 8,"FREE", "FREE");
+
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "TUE", 1, "s_vidhya@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "TUE", 2, "t_gireeshkumar@cb.amrita.edu", "19CSE311");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "TUE", 3, "j_guruprakash@cb.amrita.edu", "19CSE314");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "TUE", 4, "j_guruprakash@cb.amrita.edu", "19CSE314");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "TUE", 5,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "TUE", 6,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "TUE", 7,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "TUE", 8,"FREE", "FREE");
+
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "WED", 1, "d_bharathi@cb.amrita.edu", "19CSE313");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "WED", 2,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "WED", 3, "t_gireeshkumar@cb.amrita.edu", "19CSE311");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "WED", 4, "gr_ramya@cb.amrita.edu", "19CSE356");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "WED", 5, "j_guruprakash@cb.amrita.edu", "19CSE314");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "WED", 6,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "WED", 7,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "WED", 8,"FREE", "FREE");
+
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "THU", 1, "d_bharathi@cb.amrita.edu", "19CSE313");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "THU", 2, "d_bharathi@cb.amrita.edu", "19CSE313");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "THU", 3,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "THU", 4, "t_gireeshkumar@cb.amrita.edu", "19CSE311");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "THU", 5,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "THU", 6,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "THU", 7, "gr_ramya@cb.amrita.edu", "19CSE356");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "THU", 8, "gr_ramya@cb.amrita.edu", "19CSE356");
+
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "FRI", 1, "v_dayanand@cb.amrita.edu", "19CSE314");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "FRI", 2, "v_dayanand@cb.amrita.edu", "19CSE312");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "FRI", 3, "k_nalinadevi@cb.amrita.edu", "19CSE446");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "FRI", 4,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "FRI", 5,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "FRI", 6,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "FRI", 7,"FREE", "FREE");
+INSERT INTO static (class_id, day, slot_id, faculty_id, subject_id) VALUES ( "A104", "FRI", 8,"FREE", "FREE");
@@ -0,0 +1,1322 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// dateLayout is how dates are bound to query parameters. Both MySQL and
+// SQLite compare and store a DATE given in this form, which keeps the queries
+// independent of how each driver encodes a time.Time.
+const dateLayout = "2006-01-02"
+
+// datetimeLayout is the same for DATETIME columns, which are always in UTC
+const datetimeLayout = "2006-01-02 15:04:05"
+
+// dialect holds what differs between the SQL backends
+type dialect struct {
+	// translate maps a driver error onto the package sentinels
+	translate func(error) error
+	// forUpdate is appended to the SELECTs that read rows a transaction is
+	// about to depend on. SQLite has no row locks; it takes the write lock
+	// when the transaction begins instead (see connectSQLite).
+	forUpdate string
+}
+
+/*
+sqlStore is the Store shared by every database/sql backend. The queries are
+written in the subset of SQL understood by both MySQL and SQLite; what differs
+between the two is how to connect and how their errors are classified, which
+is left to openMySQL and openSQLite. Every statement is prepared once in
+newSQLStore and reused for the lifetime of the pool.
+*/
+type sqlStore struct {
+	db *sql.DB
+	dialect
+
+	freeClass      *sql.Stmt
+	freeSlot       *sql.Stmt
+	multiFreeSlot  *sql.Stmt
+	timetable      *sql.Stmt
+	timetableRange *sql.Stmt
+	facultyRange   *sql.Stmt
+	allSlot        *sql.Stmt
+	allClass       *sql.Stmt
+	allSubject     *sql.Stmt
+	getBooking     *sql.Stmt
+	bookingOwner   *sql.Stmt
+	cancelBooking  *sql.Stmt
+	staticRange    *sql.Stmt
+	bookedRange    *sql.Stmt
+	facultyStatic  *sql.Stmt
+	facultyBooked  *sql.Stmt
+	insertBooking  *sql.Stmt
+	deleteStatic   *sql.Stmt
+	insertStatic   *sql.Stmt
+
+	versions            *sql.Stmt
+	lockVersions        *sql.Stmt
+	versionPublished    *sql.Stmt
+	insertVersion       *sql.Stmt
+	updateVersion       *sql.Stmt
+	deleteVersion       *sql.Stmt
+	deleteVersionStatic *sql.Stmt
+	copyVersionStatic   *sql.Stmt
+
+	insertSeries      *sql.Stmt
+	insertSeriesSkip  *sql.Stmt
+	seriesByID        *sql.Stmt
+	seriesOwner       *sql.Stmt
+	seriesSkips       *sql.Stmt
+	seriesDates       *sql.Stmt
+	cancelSeriesDate  *sql.Stmt
+	cancelSeries      *sql.Stmt
+	deleteSeriesSkips *sql.Stmt
+	deleteSeries      *sql.Stmt
+
+	calendarDay     *sql.Stmt
+	terms           *sql.Stmt
+	holidays        *sql.Stmt
+	dayOrders       *sql.Stmt
+	deleteTerms     *sql.Stmt
+	deleteHolidays  *sql.Stmt
+	deleteDayOrders *sql.Stmt
+	insertTerm      *sql.Stmt
+	insertHoliday   *sql.Stmt
+	insertDayOrder  *sql.Stmt
+
+	insertAPIToken *sql.Stmt
+	apiTokens      *sql.Stmt
+	apiTokenByHash *sql.Stmt
+	touchAPIToken  *sql.Stmt
+	deleteAPIToken *sql.Stmt
+}
+
+func newSQLStore(db *sql.DB, d dialect) (*sqlStore, error) {
+	s := &sqlStore{db: db, dialect: d}
+	queries := []struct {
+		stmt  **sql.Stmt
+		query string
+	}{
+		{&s.freeClass, `SELECT class_id FROM static s WHERE
+        slot_id = ? AND
+        version = ? AND
+        day = ? AND
+        subject_id = 'FREE' AND
+        NOT EXISTS (SELECT 1 FROM dynamic WHERE
+        slot_id=s.slot_id AND
+    date=? AND class_id=s.class_id)
+        ORDER BY class_id`},
+		{&s.freeSlot, `SELECT slot_id FROM static s WHERE
+        class_id = ? AND
+        version = ? AND
+        day = ? AND
+        subject_id = 'FREE' AND NOT EXISTS (SELECT 1 FROM dynamic WHERE
+        class_id=s.class_id AND date=? AND slot_id=s.slot_id)
+        ORDER BY slot_id`},
+		/*
+		   SELECT class_id FROM (SELECT class_id, COUNT(class_id) as num_free FROM
+		   static s WHERE slot_id BETWEEN 5 AND 8 AND subject_id="FREE" AND
+		   day="TUE" AND NOT EXISTS (SELECT 1 FROM DYNAMIC WHERE slot_id=s.slot_id
+		   AND date="2023-06-13" AND class_id=s.class_id) GROUP BY class_id) as
+		   tmp WHERE num_free=(8-5)+1;
+		*/
+		{&s.multiFreeSlot, `
+    SELECT class_id FROM (SELECT class_id, COUNT(class_id) as num_free FROM
+    static s WHERE slot_id BETWEEN ? AND ? AND subject_id='FREE' AND version=? AND
+    day=? AND NOT EXISTS (SELECT 1 FROM dynamic WHERE slot_id=s.slot_id AND date=? AND
+    class_id=s.class_id) GROUP BY class_id) as tmp WHERE num_free=(?-?)+1
+    ORDER BY class_id;
+    `},
+		/*
+		   A booking hides the static entry of its slot, so every slot
+		   appears once whichever way the database orders a UNION
+		*/
+		{&s.timetable, `
+    SELECT t.slot_id, sl.stime, sl.etime, t.subject_id, COALESCE(su.name, ''),
+    COALESCE(t.faculty_id, ''), t.source FROM
+    (SELECT slot_id, subject_id, faculty_id, 'booking' AS source FROM dynamic
+    WHERE date=? AND class_id=?
+    UNION ALL
+    SELECT slot_id, subject_id, faculty_id, 'static' AS source FROM static s
+    WHERE version=? AND day=? AND class_id=? AND NOT EXISTS (SELECT 1 FROM dynamic WHERE
+    class_id=s.class_id AND date=? AND slot_id=s.slot_id)) AS t
+    JOIN slot sl ON sl.id=t.slot_id
+    LEFT JOIN subject su ON su.id=t.subject_id
+    ORDER BY t.slot_id;
+    `},
+		/*
+		   The static rows come once per version and weekday and are spread
+		   over the dates of the range by spreadRange, the bookings come
+		   with their date
+		*/
+		{&s.timetableRange, `
+    SELECT t.version, t.day, t.date, t.class_id, t.slot_id, sl.stime, sl.etime, t.subject_id,
+    COALESCE(su.name, ''), COALESCE(t.faculty_id, ''), t.source FROM
+    (SELECT '' AS version, '' AS day, date, class_id, slot_id, subject_id, faculty_id, 'booking' AS source
+    FROM dynamic WHERE class_id=? AND date BETWEEN ? AND ?
+    UNION ALL
+    SELECT version, day, NULL AS date, class_id, slot_id, subject_id, faculty_id, 'static' AS source
+    FROM static WHERE class_id=?) AS t
+    JOIN slot sl ON sl.id=t.slot_id
+    LEFT JOIN subject su ON su.id=t.subject_id
+    ORDER BY t.slot_id;
+    `},
+		/*
+		   Besides the faculty's own bookings this reads the bookings of
+		   others that take the place of one of their classes
+		*/
+		{&s.facultyRange, `
+    SELECT t.version, t.day, t.date, t.class_id, t.slot_id, sl.stime, sl.etime, t.subject_id,
+    COALESCE(su.name, ''), COALESCE(t.faculty_id, ''), t.source FROM
+    (SELECT '' AS version, '' AS day, date, class_id, slot_id, subject_id, faculty_id, 'booking' AS source
+    FROM dynamic d WHERE date BETWEEN ? AND ? AND (faculty_id=? OR EXISTS
+    (SELECT 1 FROM static WHERE faculty_id=? AND class_id=d.class_id AND slot_id=d.slot_id))
+    UNION ALL
+    SELECT version, day, NULL AS date, class_id, slot_id, subject_id, faculty_id, 'static' AS source
+    FROM static WHERE faculty_id=?) AS t
+    JOIN slot sl ON sl.id=t.slot_id
+    LEFT JOIN subject su ON su.id=t.subject_id
+    ORDER BY t.slot_id, t.class_id;
+    `},
+		{&s.allSlot, `SELECT id FROM slot ORDER BY id;`},
+		// Rooms that are only in a staged timetable are left out
+		{&s.allClass, `SELECT DISTINCT class_id FROM static WHERE version IN
+    (SELECT name FROM timetable_version WHERE published=1) ORDER BY class_id;`},
+		{&s.allSubject, `SELECT id FROM subject WHERE id!='FREE' ORDER BY id;`},
+		{&s.getBooking, `SELECT class_id, date, slot_id, faculty_id, subject_id, series_id
+    FROM dynamic WHERE faculty_id=? ORDER BY date, class_id, slot_id`},
+		{&s.bookingOwner, `SELECT faculty_id FROM dynamic WHERE class_id=? AND date=? AND
+    slot_id=?` + d.forUpdate},
+		{&s.cancelBooking, `DELETE FROM dynamic WHERE class_id=? AND date=? AND slot_id=?`},
+		{&s.staticRange, `SELECT slot_id, subject_id FROM static WHERE class_id=? AND
+    version=? AND day=? AND slot_id BETWEEN ? AND ?` + d.forUpdate},
+		{&s.bookedRange, `SELECT slot_id FROM dynamic WHERE class_id=? AND date=? AND
+    slot_id BETWEEN ? AND ?` + d.forUpdate},
+		{&s.facultyStatic, `SELECT slot_id, class_id FROM static WHERE faculty_id=? AND
+    version=? AND day=? AND slot_id BETWEEN ? AND ?` + d.forUpdate},
+		{&s.facultyBooked, `SELECT slot_id, class_id FROM dynamic WHERE faculty_id=? AND
+    date=? AND slot_id BETWEEN ? AND ?` + d.forUpdate},
+		{&s.insertBooking, `INSERT INTO dynamic
+    (class_id, date, slot_id, faculty_id, subject_id, series_id) VALUES (?, ?, ?, ?, ?, ?)`},
+		{&s.deleteStatic, `DELETE FROM static WHERE version=? AND class_id=? AND day=? AND slot_id=?`},
+		{&s.insertStatic, `INSERT INTO static
+    (version, class_id, day, slot_id, faculty_id, subject_id) VALUES (?, ?, ?, ?, ?, ?)`},
+		{&s.versions, `SELECT name, start_date, end_date, published FROM timetable_version
+    ORDER BY start_date, name`},
+		{&s.lockVersions, `SELECT name, start_date, end_date, published FROM timetable_version
+    ORDER BY start_date, name` + d.forUpdate},
+		{&s.versionPublished, `SELECT published FROM timetable_version WHERE name=?` + d.forUpdate},
+		{&s.insertVersion, `INSERT INTO timetable_version (name, start_date, end_date, published)
+    VALUES (?, ?, ?, ?)`},
+		{&s.updateVersion, `UPDATE timetable_version SET start_date=?, end_date=?, published=?
+    WHERE name=?`},
+		{&s.deleteVersion, `DELETE FROM timetable_version WHERE name=?`},
+		{&s.deleteVersionStatic, `DELETE FROM static WHERE version=?`},
+		{&s.copyVersionStatic, `INSERT INTO static
+    (version, class_id, day, slot_id, faculty_id, subject_id)
+    SELECT ?, class_id, day, slot_id, faculty_id, subject_id FROM static WHERE version=?`},
+		{&s.insertSeries, `INSERT INTO booking_series
+    (class_id, start_date, until_date, interval_weeks, start_slot, end_slot, faculty_id, subject_id)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?)`},
+		{&s.insertSeriesSkip, `INSERT INTO series_skip (series_id, date) VALUES (?, ?)`},
+		{&s.seriesByID, `SELECT class_id, start_date, until_date, interval_weeks, start_slot,
+    end_slot, faculty_id, subject_id FROM booking_series WHERE id=?`},
+		{&s.seriesOwner, `SELECT faculty_id FROM booking_series WHERE id=?` + d.forUpdate},
+		{&s.seriesSkips, `SELECT date FROM series_skip WHERE series_id=? ORDER BY date`},
+		{&s.seriesDates, `SELECT DISTINCT date FROM dynamic WHERE series_id=? ORDER BY date`},
+		{&s.cancelSeriesDate, `DELETE FROM dynamic WHERE series_id=? AND date=?`},
+		{&s.cancelSeries, `DELETE FROM dynamic WHERE series_id=?`},
+		{&s.deleteSeriesSkips, `DELETE FROM series_skip WHERE series_id=?`},
+		{&s.deleteSeries, `DELETE FROM booking_series WHERE id=?`},
+		/*
+		   What calendarDay and versionOn need to know about a date. Both
+		   MySQL and SQLite sort NULL before any date, so a version without
+		   a start comes last.
+		*/
+		{&s.calendarDay, `SELECT (SELECT COUNT(*) FROM term),
+    (SELECT COUNT(*) FROM term WHERE start_date <= ? AND end_date >= ?),
+    (SELECT COUNT(*) FROM holiday WHERE start_date <= ? AND end_date >= ?),
+    COALESCE((SELECT day FROM day_order WHERE date = ?), ''),
+    COALESCE((SELECT name FROM timetable_version WHERE published=1 AND
+    (start_date IS NULL OR start_date <= ?) AND (end_date IS NULL OR end_date >= ?)
+    ORDER BY start_date DESC, name LIMIT 1), '')`},
+		{&s.terms, `SELECT name, start_date, end_date FROM term ORDER BY start_date, name`},
+		{&s.holidays, `SELECT name, start_date, end_date FROM holiday ORDER BY start_date`},
+		{&s.dayOrders, `SELECT date, day FROM day_order ORDER BY date`},
+		{&s.deleteTerms, `DELETE FROM term`},
+		{&s.deleteHolidays, `DELETE FROM holiday`},
+		{&s.deleteDayOrders, `DELETE FROM day_order`},
+		{&s.insertTerm, `INSERT INTO term (name, start_date, end_date) VALUES (?, ?, ?)`},
+		{&s.insertHoliday, `INSERT INTO holiday (name, start_date, end_date) VALUES (?, ?, ?)`},
+		{&s.insertDayOrder, `INSERT INTO day_order (date, day) VALUES (?, ?)`},
+		{&s.insertAPIToken, `INSERT INTO api_token
+    (id, hash, name, scope, faculty_id, created_by, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`},
+		{&s.apiTokens, `SELECT ` + apiTokenColumns + ` FROM api_token ORDER BY created_at, id`},
+		{&s.apiTokenByHash, `SELECT ` + apiTokenColumns + ` FROM api_token WHERE hash=?`},
+		{&s.touchAPIToken, `UPDATE api_token SET last_used_at=? WHERE id=?`},
+		{&s.deleteAPIToken, `DELETE FROM api_token WHERE id=?`},
+	}
+	for _, q := range queries {
+		var err error
+		*q.stmt, err = db.Prepare(q.query)
+		if err != nil {
+			s.Close()
+			return nil, d.translate(err)
+		}
+	}
+	return s, nil
+}
+
+func (s *sqlStore) Close() error {
+	for _, stmt := range []*sql.Stmt{
+		s.freeClass, s.freeSlot, s.multiFreeSlot, s.timetable, s.timetableRange, s.facultyRange, s.allSlot,
+		s.allClass, s.allSubject, s.getBooking, s.bookingOwner, s.cancelBooking,
+		s.staticRange, s.bookedRange, s.facultyStatic, s.facultyBooked,
+		s.insertBooking, s.deleteStatic, s.insertStatic,
+		s.versions, s.lockVersions, s.versionPublished, s.insertVersion, s.updateVersion,
+		s.deleteVersion, s.deleteVersionStatic, s.copyVersionStatic,
+		s.insertSeries, s.insertSeriesSkip, s.seriesByID, s.seriesOwner,
+		s.seriesSkips, s.seriesDates, s.cancelSeriesDate, s.cancelSeries,
+		s.deleteSeriesSkips, s.deleteSeries,
+		s.calendarDay, s.terms, s.holidays, s.dayOrders, s.deleteTerms,
+		s.deleteHolidays, s.deleteDayOrders, s.insertTerm, s.insertHoliday,
+		s.insertDayOrder,
+		s.insertAPIToken, s.apiTokens, s.apiTokenByHash, s.touchAPIToken,
+		s.deleteAPIToken,
+	} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+	return s.db.Close()
+}
+
+func (s *sqlStore) GetFreeClass(ctx context.Context, slot int, date time.Time) ([]string, error) {
+	version, day, err := s.day(ctx, s.calendarDay, date)
+	if err != nil || day == "" {
+		return nil, err
+	}
+	return s.queryStrings(ctx, s.freeClass, slot, version, day, date.Format(dateLayout))
+}
+
+func (s *sqlStore) GetFreeSlot(ctx context.Context, class string, date time.Time) ([]int, error) {
+	version, day, err := s.day(ctx, s.calendarDay, date)
+	if err != nil || day == "" {
+		return nil, err
+	}
+	return s.queryInts(ctx, s.freeSlot, class, version, day, date.Format(dateLayout))
+}
+
+func (s *sqlStore) MultiFreeSlot(ctx context.Context, startSlot int, endSlot int, date time.Time) ([]string, error) {
+	version, day, err := s.day(ctx, s.calendarDay, date)
+	if err != nil || day == "" {
+		return nil, err
+	}
+	return s.queryStrings(ctx, s.multiFreeSlot, startSlot, endSlot, version, day, date.Format(dateLayout), endSlot, startSlot)
+}
+
+/*
+day looks up the version of the static timetable in force on date and the day
+of it that date follows, see versionOn and Calendar.Day
+*/
+func (s *sqlStore) day(ctx context.Context, stmt *sql.Stmt, date time.Time) (version string, day string, err error) {
+	d := date.Format(dateLayout)
+	var terms, inTerm, holidays int
+	var order string
+	err = stmt.QueryRowContext(ctx, d, d, d, d, d, d, d).Scan(&terms, &inTerm, &holidays, &order, &version)
+	if err != nil {
+		return "", "", s.translate(err)
+	}
+	return version, calendarDay(date, terms > 0, inTerm > 0, holidays > 0, order), nil
+}
+
+// GetTimetableByDay lists only the bookings of a date without classes
+func (s *sqlStore) GetTimetableByDay(ctx context.Context, class string, date time.Time) ([]TimetableEntry, error) {
+	version, day, err := s.day(ctx, s.calendarDay, date)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.timetable.QueryContext(ctx, date.Format(dateLayout), class, version, day, class, date.Format(dateLayout))
+	if err != nil {
+		return nil, s.translate(err)
+	}
+	defer rows.Close()
+	var entries []TimetableEntry
+	for rows.Next() {
+		var e TimetableEntry
+		err := rows.Scan(&e.Slot, &e.Start, &e.End, &e.Subject, &e.SubjectName, &e.Faculty, &e.Source)
+		if err != nil {
+			return nil, s.translate(err)
+		}
+		e.Room = class
+		e.Start, e.End = clockTime(e.Start), clockTime(e.End)
+		entries = append(entries, e)
+	}
+	return entries, s.translate(rows.Err())
+}
+
+func (s *sqlStore) GetTimetableRange(ctx context.Context, class string, from time.Time, to time.Time) ([]DayTimetable, error) {
+	from, to, err := checkRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := s.timetableVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.spreadRange(ctx, s.timetableRange, from, to, func(date time.Time) string { return versionOn(versions, date) },
+		func(TimetableEntry) bool { return true }, class, from.Format(dateLayout), to.Format(dateLayout), class)
+}
+
+func (s *sqlStore) PreviewTimetableRange(ctx context.Context, actor Actor, version string, class string, from time.Time, to time.Time) ([]DayTimetable, error) {
+	if !actor.Admin {
+		return nil, fmt.Errorf("%w: only admins may preview a timetable", ErrForbidden)
+	}
+	from, to, err := checkRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.versionPublished.QueryRowContext(ctx, version).Scan(new(bool)); err != nil {
+		return nil, s.translate(err)
+	}
+	return s.spreadRange(ctx, s.timetableRange, from, to, func(time.Time) string { return version },
+		func(TimetableEntry) bool { return true }, class, from.Format(dateLayout), to.Format(dateLayout), class)
+}
+
+func (s *sqlStore) GetFacultySchedule(ctx context.Context, faculty string, from time.Time, to time.Time) ([]DayTimetable, error) {
+	from, to, err := checkRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := s.timetableVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// The bookings of others only hide the faculty's classes
+	return s.spreadRange(ctx, s.facultyRange, from, to, func(date time.Time) string { return versionOn(versions, date) },
+		func(e TimetableEntry) bool { return e.Faculty == faculty },
+		from.Format(dateLayout), to.Format(dateLayout), faculty, faculty, faculty)
+}
+
+/*
+spreadRange runs timetableRange or facultyRange and lays the static rows out
+over the dates from from to to, each date taking the version of the timetable
+given by version and the day the academic calendar gives it. A booking replaces the static row of its room
+and slot on its date; only the bookings for which show is true are listed.
+*/
+func (s *sqlStore) spreadRange(ctx context.Context, stmt *sql.Stmt, from time.Time, to time.Time, version func(time.Time) string, show func(TimetableEntry) bool, args ...interface{}) ([]DayTimetable, error) {
+	calendar, err := s.GetCalendar(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, s.translate(err)
+	}
+	defer rows.Close()
+	type roomSlot struct {
+		room string
+		slot int
+	}
+	type versionDay struct {
+		version string
+		day     string
+	}
+	static := make(map[versionDay][]TimetableEntry)
+	booked := make(map[string]map[roomSlot]TimetableEntry)
+	for rows.Next() {
+		var e TimetableEntry
+		var v, day string
+		var date sqlDate
+		err := rows.Scan(&v, &day, &date, &e.Room, &e.Slot, &e.Start, &e.End, &e.Subject, &e.SubjectName, &e.Faculty, &e.Source)
+		if err != nil {
+			return nil, s.translate(err)
+		}
+		e.Start, e.End = clockTime(e.Start), clockTime(e.End)
+		if e.Source == SourceStatic {
+			static[versionDay{v, day}] = append(static[versionDay{v, day}], e)
+			continue
+		}
+		key := date.Format(dateLayout)
+		if booked[key] == nil {
+			booked[key] = make(map[roomSlot]TimetableEntry)
+		}
+		booked[key][roomSlot{e.Room, e.Slot}] = e
+	}
+	if err := rows.Err(); err != nil {
+		return nil, s.translate(err)
+	}
+	var days []DayTimetable
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		bookings := booked[date.Format(dateLayout)]
+		day := calendar.Day(date)
+		var entries []TimetableEntry
+		for _, e := range static[versionDay{version(date), day}] {
+			if _, ok := bookings[roomSlot{e.Room, e.Slot}]; !ok {
+				entries = append(entries, e)
+			}
+		}
+		for _, e := range bookings {
+			if show(e) {
+				entries = append(entries, e)
+			}
+		}
+		sortEntries(entries)
+		days = append(days, DayTimetable{Date: date, Day: day, Entries: entries})
+	}
+	return days, nil
+}
+
+/*
+sqlDate scans a DATE column that may be NULL. Columns of a UNION lose their
+declared type in SQLite, so the date can arrive as text as well as a time.
+*/
+type sqlDate struct {
+	time.Time
+}
+
+func (d *sqlDate) Scan(v interface{}) error {
+	var err error
+	switch v := v.(type) {
+	case nil:
+		d.Time = time.Time{}
+	case time.Time:
+		d.Time = v
+	case string:
+		d.Time, err = time.Parse(dateLayout, v[:min(len(v), len(dateLayout))])
+	case []byte:
+		d.Time, err = time.Parse(dateLayout, string(v[:min(len(v), len(dateLayout))]))
+	default:
+		err = fmt.Errorf("cannot scan %T into a date", v)
+	}
+	return err
+}
+
+// clockTime cuts the seconds off a TIME column, which MySQL returns as 15:04:05
+func clockTime(t string) string {
+	if len(t) > len("15:04") {
+		return t[:len("15:04")]
+	}
+	return t
+}
+
+func (s *sqlStore) GetAllSlot(ctx context.Context) ([]int, error) {
+	return s.queryInts(ctx, s.allSlot)
+}
+
+func (s *sqlStore) GetAllClass(ctx context.Context) ([]string, error) {
+	return s.queryStrings(ctx, s.allClass)
+}
+
+func (s *sqlStore) GetAllSubject(ctx context.Context) ([]string, error) {
+	return s.queryStrings(ctx, s.allSubject)
+}
+
+func (s *sqlStore) CancelBooking(ctx context.Context, actor Actor, class string, date time.Time, slot int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return s.translate(err)
+	}
+	defer tx.Rollback()
+
+	var owner string
+	err = tx.StmtContext(ctx, s.bookingOwner).QueryRowContext(ctx, class, date.Format(dateLayout), slot).Scan(&owner)
+	if err != nil {
+		return s.translate(err)
+	}
+	if !actor.mayModify(owner) {
+		return fmt.Errorf("%w: the booking belongs to %s", ErrForbidden, owner)
+	}
+	_, err = tx.StmtContext(ctx, s.cancelBooking).ExecContext(ctx, class, date.Format(dateLayout), slot)
+	if err != nil {
+		return s.translate(err)
+	}
+	return s.translate(tx.Commit())
+}
+
+func (s *sqlStore) SetStatic(ctx context.Context, actor Actor, e StaticEntry) error {
+	return s.SetVersionStatic(ctx, actor, DefaultVersion, e)
+}
+
+// SetVersionStatic replaces the cell in a transaction so that a bad reference
+// leaves the old one in place
+func (s *sqlStore) SetVersionStatic(ctx context.Context, actor Actor, version string, e StaticEntry) error {
+	if err := checkStatic(actor, e); err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return s.translate(err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.StmtContext(ctx, s.deleteStatic).ExecContext(ctx, version, e.Class, e.Day, e.Slot)
+	if err != nil {
+		return s.translate(err)
+	}
+	_, err = tx.StmtContext(ctx, s.insertStatic).ExecContext(ctx, version, e.Class, e.Day, e.Slot, e.Faculty, e.Subject)
+	if err != nil {
+		return s.translate(err)
+	}
+	return s.translate(tx.Commit())
+}
+
+// nullDate binds a zero date as NULL
+func nullDate(date time.Time) sql.NullString {
+	if date.IsZero() {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: date.Format(dateLayout), Valid: true}
+}
+
+// timetableVersions lists the versions without the admin check, for versionOn
+func (s *sqlStore) timetableVersions(ctx context.Context) ([]TimetableVersion, error) {
+	return s.scanVersions(ctx, s.versions)
+}
+
+// scanVersions lists the versions stmt selects
+func (s *sqlStore) scanVersions(ctx context.Context, stmt *sql.Stmt) ([]TimetableVersion, error) {
+	var versions []TimetableVersion
+	err := s.scanRows(ctx, stmt, func(scan func(...interface{}) error) error {
+		var v TimetableVersion
+		var start, end sqlDate
+		err := scan(&v.Name, &start, &end, &v.Published)
+		v.Start, v.End = start.Time, end.Time
+		versions = append(versions, v)
+		return err
+	})
+	return versions, err
+}
+
+func (s *sqlStore) GetTimetableVersions(ctx context.Context, actor Actor) ([]TimetableVersion, error) {
+	if !actor.Admin {
+		return nil, fmt.Errorf("%w: only admins may list timetable versions", ErrForbidden)
+	}
+	return s.timetableVersions(ctx)
+}
+
+func (s *sqlStore) SetTimetableVersion(ctx context.Context, actor Actor, v TimetableVersion) error {
+	v, err := checkVersion(actor, v)
+	if err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return s.translate(err)
+	}
+	defer tx.Rollback()
+
+	versions, err := s.scanVersions(ctx, tx.StmtContext(ctx, s.lockVersions))
+	if err != nil {
+		return err
+	}
+	if err := checkOverlap(versions, v); err != nil {
+		return err
+	}
+	stmt := s.updateVersion
+	err = tx.StmtContext(ctx, s.versionPublished).QueryRowContext(ctx, v.Name).Scan(new(bool))
+	if errors.Is(err, sql.ErrNoRows) {
+		stmt = s.insertVersion
+	} else if err != nil {
+		return s.translate(err)
+	}
+	if stmt == s.insertVersion {
+		_, err = tx.StmtContext(ctx, stmt).ExecContext(ctx, v.Name, nullDate(v.Start), nullDate(v.End), v.Published)
+	} else {
+		_, err = tx.StmtContext(ctx, stmt).ExecContext(ctx, nullDate(v.Start), nullDate(v.End), v.Published, v.Name)
+	}
+	if err != nil {
+		return s.translate(err)
+	}
+	return s.translate(tx.Commit())
+}
+
+func (s *sqlStore) DeleteTimetableVersion(ctx context.Context, actor Actor, name string) error {
+	if !actor.Admin {
+		return fmt.Errorf("%w: only admins may change the timetable", ErrForbidden)
+	}
+	if name == DefaultVersion {
+		return fmt.Errorf("%w: version %s can't be deleted", ErrInvalid, name)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return s.translate(err)
+	}
+	defer tx.Rollback()
+
+	var published bool
+	err = tx.StmtContext(ctx, s.versionPublished).QueryRowContext(ctx, name).Scan(&published)
+	if err != nil {
+		return s.translate(err)
+	}
+	if published {
+		return fmt.Errorf("%w: version %s is published", ErrInvalid, name)
+	}
+	for _, stmt := range []*sql.Stmt{s.deleteVersionStatic, s.deleteVersion} {
+		if _, err := tx.StmtContext(ctx, stmt).ExecContext(ctx, name); err != nil {
+			return s.translate(err)
+		}
+	}
+	return s.translate(tx.Commit())
+}
+
+func (s *sqlStore) CopyTimetableVersion(ctx context.Context, actor Actor, from string, to string) error {
+	if !actor.Admin {
+		return fmt.Errorf("%w: only admins may change the timetable", ErrForbidden)
+	}
+	if from == to {
+		return fmt.Errorf("%w: cannot copy version %s onto itself", ErrInvalid, from)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return s.translate(err)
+	}
+	defer tx.Rollback()
+
+	for _, name := range []string{from, to} {
+		err := tx.StmtContext(ctx, s.versionPublished).QueryRowContext(ctx, name).Scan(new(bool))
+		if err != nil {
+			return s.translate(err)
+		}
+	}
+	if _, err := tx.StmtContext(ctx, s.deleteVersionStatic).ExecContext(ctx, to); err != nil {
+		return s.translate(err)
+	}
+	if _, err := tx.StmtContext(ctx, s.copyVersionStatic).ExecContext(ctx, to, from); err != nil {
+		return s.translate(err)
+	}
+	return s.translate(tx.Commit())
+}
+
+func (s *sqlStore) GetCalendar(ctx context.Context) (Calendar, error) {
+	var c Calendar
+	err := s.scanRows(ctx, s.terms, func(scan func(...interface{}) error) error {
+		var t Term
+		var start, end sqlDate
+		err := scan(&t.Name, &start, &end)
+		t.Start, t.End = start.Time, end.Time
+		c.Terms = append(c.Terms, t)
+		return err
+	})
+	if err != nil {
+		return c, err
+	}
+	err = s.scanRows(ctx, s.holidays, func(scan func(...interface{}) error) error {
+		var h Holiday
+		var start, end sqlDate
+		err := scan(&h.Name, &start, &end)
+		h.Start, h.End = start.Time, end.Time
+		c.Holidays = append(c.Holidays, h)
+		return err
+	})
+	if err != nil {
+		return c, err
+	}
+	err = s.scanRows(ctx, s.dayOrders, func(scan func(...interface{}) error) error {
+		var o DayOrder
+		var date sqlDate
+		err := scan(&date, &o.Day)
+		o.Date = date.Time
+		c.DayOrders = append(c.DayOrders, o)
+		return err
+	})
+	return c, err
+}
+
+// scanRows runs a query without arguments and calls fn to scan each row
+func (s *sqlStore) scanRows(ctx context.Context, stmt *sql.Stmt, fn func(scan func(...interface{}) error) error) error {
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return s.translate(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := fn(rows.Scan); err != nil {
+			return s.translate(err)
+		}
+	}
+	return s.translate(rows.Err())
+}
+
+// SetCalendar replaces the calendar in a transaction, so that readers see
+// either the old or the new one
+func (s *sqlStore) SetCalendar(ctx context.Context, actor Actor, c Calendar) error {
+	c, err := checkCalendar(actor, c)
+	if err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return s.translate(err)
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range []*sql.Stmt{s.deleteTerms, s.deleteHolidays, s.deleteDayOrders} {
+		if _, err := tx.StmtContext(ctx, stmt).ExecContext(ctx); err != nil {
+			return s.translate(err)
+		}
+	}
+	for _, t := range c.Terms {
+		_, err := tx.StmtContext(ctx, s.insertTerm).ExecContext(ctx, t.Name,
+			t.Start.Format(dateLayout), t.End.Format(dateLayout))
+		if err != nil {
+			return s.translate(err)
+		}
+	}
+	for _, h := range c.Holidays {
+		_, err := tx.StmtContext(ctx, s.insertHoliday).ExecContext(ctx, h.Name,
+			h.Start.Format(dateLayout), h.End.Format(dateLayout))
+		if err != nil {
+			return s.translate(err)
+		}
+	}
+	for _, o := range c.DayOrders {
+		_, err := tx.StmtContext(ctx, s.insertDayOrder).ExecContext(ctx, o.Date.Format(dateLayout), o.Day)
+		if err != nil {
+			return s.translate(err)
+		}
+	}
+	return s.translate(tx.Commit())
+}
+
+// apiTokenColumns are scanned by scanAPIToken
+const apiTokenColumns = `id, name, scope, faculty_id, created_by, created_at, last_used_at`
+
+func scanAPIToken(row interface{ Scan(...interface{}) error }) (APIToken, error) {
+	var t APIToken
+	var faculty sql.NullString
+	var lastUsed sql.NullTime
+	err := row.Scan(&t.ID, &t.Name, &t.Scope, &faculty, &t.CreatedBy, &t.CreatedAt, &lastUsed)
+	t.Faculty = faculty.String
+	if lastUsed.Valid {
+		t.LastUsed = &lastUsed.Time
+	}
+	return t, err
+}
+
+func (s *sqlStore) CreateAPIToken(ctx context.Context, actor Actor, t APIToken, hash string) error {
+	if err := checkAPIToken(actor, t); err != nil {
+		return err
+	}
+	faculty := sql.NullString{String: t.Faculty, Valid: t.Faculty != ""}
+	_, err := s.insertAPIToken.ExecContext(ctx, t.ID, hash, t.Name, t.Scope, faculty,
+		t.CreatedBy, t.CreatedAt.UTC().Format(datetimeLayout))
+	return s.translate(err)
+}
+
+func (s *sqlStore) GetAPITokens(ctx context.Context, actor Actor) ([]APIToken, error) {
+	if !actor.Admin {
+		return nil, fmt.Errorf("%w: only admins may list API tokens", ErrForbidden)
+	}
+	rows, err := s.apiTokens.QueryContext(ctx)
+	if err != nil {
+		return nil, s.translate(err)
+	}
+	defer rows.Close()
+	var tokens []APIToken
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, s.translate(err)
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, s.translate(rows.Err())
+}
+
+func (s *sqlStore) RevokeAPIToken(ctx context.Context, actor Actor, id string) error {
+	if !actor.Admin {
+		return fmt.Errorf("%w: only admins may revoke API tokens", ErrForbidden)
+	}
+	result, err := s.deleteAPIToken.ExecContext(ctx, id)
+	if err != nil {
+		return s.translate(err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%w: API token %s", ErrNotFound, id)
+	}
+	return nil
+}
+
+func (s *sqlStore) UseAPIToken(ctx context.Context, hash string, now time.Time) (APIToken, error) {
+	t, err := scanAPIToken(s.apiTokenByHash.QueryRowContext(ctx, hash))
+	if err != nil {
+		return t, s.translate(err)
+	}
+	_, err = s.touchAPIToken.ExecContext(ctx, now.UTC().Format(datetimeLayout), t.ID)
+	if err != nil {
+		return t, s.translate(err)
+	}
+	now = now.UTC().Truncate(time.Second)
+	t.LastUsed = &now
+	return t, nil
+}
+
+func (s *sqlStore) GetBooking(ctx context.Context, faculty string) ([]BookingRecord, error) {
+	var booking []BookingRecord
+	rows, err := s.getBooking.QueryContext(ctx, faculty)
+	if err != nil {
+		return nil, s.translate(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tmp BookingRecord
+		var series sql.NullInt64
+		err := rows.Scan(&tmp.Class, &tmp.Date, &tmp.Slot, &tmp.Faculty, &tmp.Subject, &series)
+		if err != nil {
+			return nil, s.translate(err)
+		}
+		tmp.Series = series.Int64
+		booking = append(booking, tmp)
+	}
+	return booking, s.translate(rows.Err())
+}
+
+/*
+Booking inserts a single booking if the room is FREE in the static timetable.
+A slot that is not free yields zero rows affected and no error. A slot that
+is already booked, or a faculty member who is busy elsewhere at that time,
+yields a *ConflictError.
+*/
+func (s *sqlStore) Booking(ctx context.Context, class string, date time.Time, slot int, faculty string, subject string) (int64, error) {
+	return s.book(ctx, class, date, slot, slot, faculty, subject, true)
+}
+
+/*
+MultiBooking books every slot from startSlot to endSlot or none of them. If
+any slot is unavailable nothing is written and a *ConflictError lists each
+offending slot.
+*/
+func (s *sqlStore) MultiBooking(ctx context.Context, class string, date time.Time, startSlot int, endSlot int, faculty string, subject string) (int64, error) {
+	return s.book(ctx, class, date, startSlot, endSlot, faculty, subject, false)
+}
+
+/*
+book is the transaction behind Booking and MultiBooking. The rows that decide
+whether the slots are available are read and locked before anything is
+inserted, so that no other booking can slip in between the check and the
+inserts. With skipNotFree a range with a slot that is not free in the static
+timetable is silently not booked, which is how Booking has always behaved. A
+date without classes is reported as closed either way.
+*/
+func (s *sqlStore) book(ctx context.Context, class string, date time.Time, startSlot int, endSlot int, faculty string, subject string, skipNotFree bool) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, s.translate(err)
+	}
+	defer tx.Rollback()
+
+	state, err := s.slotStates(ctx, tx, class, date, startSlot, endSlot, faculty)
+	if err != nil {
+		return 0, err
+	}
+	if skipNotFree && !state[startSlot].closed {
+		for slot := startSlot; slot <= endSlot; slot++ {
+			if !state[slot].free {
+				return 0, nil
+			}
+		}
+	}
+	if err := rangeConflicts(class, date, startSlot, endSlot, state); err != nil {
+		return 0, err
+	}
+
+	var rowsAffected int64
+	insert := tx.StmtContext(ctx, s.insertBooking)
+	for slot := startSlot; slot <= endSlot; slot++ {
+		_, err := insert.ExecContext(ctx, class, date.Format(dateLayout), slot, faculty, subject, nil)
+		if err != nil {
+			return 0, s.translate(err)
+		}
+		rowsAffected++
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, s.translate(err)
+	}
+	return rowsAffected, nil
+}
+
+/*
+BookSeries checks every date of the series in one transaction, reading and
+locking the same rows as book, before writing the series, its skipped dates
+and its bookings.
+*/
+func (s *sqlStore) BookSeries(ctx context.Context, series Series, partial bool) (Series, []Conflict, error) {
+	series, err := checkSeries(series)
+	if err != nil {
+		return series, nil, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return series, nil, s.translate(err)
+	}
+	defer tx.Rollback()
+
+	dates, conflicts, err := seriesConflicts(series, func(date time.Time) (map[int]slotState, error) {
+		return s.slotStates(ctx, tx, series.Class, date, series.StartSlot, series.EndSlot, series.Faculty)
+	})
+	if err != nil {
+		return series, nil, err
+	}
+	if len(conflicts) > 0 && (!partial || len(dates) == 0) {
+		return series, nil, &ConflictError{Conflicts: conflicts}
+	}
+
+	result, err := tx.StmtContext(ctx, s.insertSeries).ExecContext(ctx, series.Class,
+		series.Start.Format(dateLayout), series.Until.Format(dateLayout), series.Interval,
+		series.StartSlot, series.EndSlot, series.Faculty, series.Subject)
+	if err != nil {
+		return series, nil, s.translate(err)
+	}
+	if series.ID, err = result.LastInsertId(); err != nil {
+		return series, nil, s.translate(err)
+	}
+	for _, c := range conflicts {
+		series.Skip = addDate(series.Skip, c.Date)
+	}
+	skip := tx.StmtContext(ctx, s.insertSeriesSkip)
+	for _, date := range series.Skip {
+		if _, err := skip.ExecContext(ctx, series.ID, date.Format(dateLayout)); err != nil {
+			return series, nil, s.translate(err)
+		}
+	}
+	insert := tx.StmtContext(ctx, s.insertBooking)
+	for _, date := range dates {
+		for slot := series.StartSlot; slot <= series.EndSlot; slot++ {
+			_, err := insert.ExecContext(ctx, series.Class, date.Format(dateLayout), slot,
+				series.Faculty, series.Subject, series.ID)
+			if err != nil {
+				return series, nil, s.translate(err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return series, nil, s.translate(err)
+	}
+	series.Occurrences = dates
+	return series, conflicts, nil
+}
+
+func (s *sqlStore) GetSeries(ctx context.Context, actor Actor, id int64) (Series, error) {
+	series := Series{ID: id}
+	var start, until sqlDate
+	err := s.seriesByID.QueryRowContext(ctx, id).Scan(&series.Class, &start, &until, &series.Interval,
+		&series.StartSlot, &series.EndSlot, &series.Faculty, &series.Subject)
+	if err != nil {
+		return series, s.translate(err)
+	}
+	if !actor.mayModify(series.Faculty) {
+		return Series{}, fmt.Errorf("%w: the series belongs to %s", ErrForbidden, series.Faculty)
+	}
+	series.Start, series.Until = start.Time, until.Time
+	if series.Skip, err = s.queryDates(ctx, s.seriesSkips, id); err != nil {
+		return series, err
+	}
+	if series.Occurrences, err = s.queryDates(ctx, s.seriesDates, id); err != nil {
+		return series, err
+	}
+	return series, nil
+}
+
+func (s *sqlStore) CancelSeries(ctx context.Context, actor Actor, id int64) error {
+	tx, err := s.seriesTx(ctx, actor, id)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range []*sql.Stmt{s.cancelSeries, s.deleteSeriesSkips, s.deleteSeries} {
+		if _, err := tx.StmtContext(ctx, stmt).ExecContext(ctx, id); err != nil {
+			return s.translate(err)
+		}
+	}
+	return s.translate(tx.Commit())
+}
+
+func (s *sqlStore) CancelOccurrence(ctx context.Context, actor Actor, id int64, date time.Time) error {
+	tx, err := s.seriesTx(ctx, actor, id)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.StmtContext(ctx, s.cancelSeriesDate).ExecContext(ctx, id, date.Format(dateLayout))
+	if err != nil {
+		return s.translate(err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%w: series %d has no booking on %s", ErrNotFound, id, date.Format(dateLayout))
+	}
+	_, err = tx.StmtContext(ctx, s.insertSeriesSkip).ExecContext(ctx, id, date.Format(dateLayout))
+	if err != nil {
+		return s.translate(err)
+	}
+	return s.translate(tx.Commit())
+}
+
+// seriesTx begins a transaction after locking the series and checking that
+// actor may change it
+func (s *sqlStore) seriesTx(ctx context.Context, actor Actor, id int64) (*sql.Tx, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, s.translate(err)
+	}
+	var owner string
+	err = tx.StmtContext(ctx, s.seriesOwner).QueryRowContext(ctx, id).Scan(&owner)
+	if err != nil {
+		tx.Rollback()
+		return nil, s.translate(err)
+	}
+	if !actor.mayModify(owner) {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: the series belongs to %s", ErrForbidden, owner)
+	}
+	return tx, nil
+}
+
+// slotStates reads, and locks, everything rangeConflicts needs to know
+func (s *sqlStore) slotStates(ctx context.Context, tx *sql.Tx, class string, date time.Time, startSlot int, endSlot int, faculty string) (map[int]slotState, error) {
+	state := make(map[int]slotState)
+	version, day, err := s.day(ctx, tx.StmtContext(ctx, s.calendarDay), date)
+	if err != nil {
+		return nil, err
+	}
+	if day == "" {
+		for slot := startSlot; slot <= endSlot; slot++ {
+			state[slot] = slotState{closed: true}
+		}
+		return state, nil
+	}
+
+	err = s.scanSlots(ctx, tx.StmtContext(ctx, s.staticRange), func(slot int, subject string) {
+		st := state[slot]
+		st.free = subject == "FREE"
+		state[slot] = st
+	}, class, version, day, startSlot, endSlot)
+	if err != nil {
+		return nil, err
+	}
+	err = s.scanSlots(ctx, tx.StmtContext(ctx, s.bookedRange), func(slot int, _ string) {
+		st := state[slot]
+		st.booked = true
+		state[slot] = st
+	}, class, date.Format(dateLayout), startSlot, endSlot)
+	if err != nil {
+		return nil, err
+	}
+	err = s.scanSlots(ctx, tx.StmtContext(ctx, s.facultyStatic), func(slot int, other string) {
+		if other != class {
+			st := state[slot]
+			st.teaching = other
+			state[slot] = st
+		}
+	}, faculty, version, day, startSlot, endSlot)
+	if err != nil {
+		return nil, err
+	}
+	err = s.scanSlots(ctx, tx.StmtContext(ctx, s.facultyBooked), func(slot int, other string) {
+		if other != class {
+			st := state[slot]
+			st.facultyBooked = other
+			state[slot] = st
+		}
+	}, faculty, date.Format(dateLayout), startSlot, endSlot)
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+/*
+scanSlots runs a query returning a slot id, optionally followed by a string
+column, and calls fn for each row
+*/
+func (s *sqlStore) scanSlots(ctx context.Context, stmt *sql.Stmt, fn func(slot int, value string), args ...interface{}) error {
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return s.translate(err)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return s.translate(err)
+	}
+	for rows.Next() {
+		var slot int
+		var value string
+		dest := []interface{}{&slot, &value}[:len(columns)]
+		if err := rows.Scan(dest...); err != nil {
+			return s.translate(err)
+		}
+		fn(slot, value)
+	}
+	return s.translate(rows.Err())
+}
+
+// queryStrings runs a query whose result is a single string column
+func (s *sqlStore) queryStrings(ctx context.Context, stmt *sql.Stmt, args ...interface{}) ([]string, error) {
+	var result []string
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, s.translate(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tmp string
+		if err := rows.Scan(&tmp); err != nil {
+			return nil, s.translate(err)
+		}
+		result = append(result, tmp)
+	}
+	return result, s.translate(rows.Err())
+}
+
+// queryDates runs a query whose result is a single DATE column
+func (s *sqlStore) queryDates(ctx context.Context, stmt *sql.Stmt, args ...interface{}) ([]time.Time, error) {
+	var result []time.Time
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, s.translate(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tmp sqlDate
+		if err := rows.Scan(&tmp); err != nil {
+			return nil, s.translate(err)
+		}
+		result = append(result, tmp.Time)
+	}
+	return result, s.translate(rows.Err())
+}
+
+// queryInts runs a query whose result is a single integer column
+func (s *sqlStore) queryInts(ctx context.Context, stmt *sql.Stmt, args ...interface{}) ([]int, error) {
+	var result []int
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, s.translate(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tmp int
+		if err := rows.Scan(&tmp); err != nil {
+			return nil, s.translate(err)
+		}
+		result = append(result, tmp)
+	}
+	return result, s.translate(rows.Err())
+}
+
+/*
+commonError classifies the errors that database/sql itself can return,
+whatever the driver. A cancelled or expired context is passed through
+untouched.
+*/
+func commonError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	if errors.Is(err, sql.ErrConnDone) || errors.Is(err, driver.ErrBadConn) {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return err
+}
+
+// load inserts every row of f in a single transaction
+func (s *sqlStore) load(ctx context.Context, f *Fixture) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return s.translate(err)
+	}
+	defer tx.Rollback()
+
+	for _, slot := range f.Slots {
+		_, err := tx.ExecContext(ctx, `INSERT INTO slot (id, stime, etime) VALUES (?, ?, ?)`,
+			slot.ID, slot.Start, slot.End)
+		if err != nil {
+			return s.translate(err)
+		}
+	}
+	for _, subject := range f.Subjects {
+		_, err := tx.ExecContext(ctx, `INSERT INTO subject (id, name) VALUES (?, ?)`,
+			subject.ID, subject.Name)
+		if err != nil {
+			return s.translate(err)
+		}
+	}
+	for _, faculty := range f.Faculty {
+		_, err := tx.ExecContext(ctx, `INSERT INTO faculty (id, name) VALUES (?, ?)`,
+			faculty.ID, faculty.Name)
+		if err != nil {
+			return s.translate(err)
+		}
+	}
+	for _, v := range f.Versions {
+		query := `INSERT INTO timetable_version (start_date, end_date, published, name) VALUES (?, ?, ?, ?)`
+		if v.Name == DefaultVersion {
+			query = `UPDATE timetable_version SET start_date=?, end_date=?, published=? WHERE name=?`
+		}
+		_, err := tx.ExecContext(ctx, query, nullDate(v.Start), nullDate(v.End), v.Published, v.Name)
+		if err != nil {
+			return s.translate(err)
+		}
+	}
+	for _, e := range f.Static {
+		_, err := tx.ExecContext(ctx, `INSERT INTO static
+            (version, class_id, day, slot_id, faculty_id, subject_id) VALUES (?, ?, ?, ?, ?, ?)`,
+			DefaultVersion, e.Class, e.Day, e.Slot, e.Faculty, e.Subject)
+		if err != nil {
+			return s.translate(err)
+		}
+	}
+	for _, b := range f.Bookings {
+		_, err := tx.ExecContext(ctx, `INSERT INTO dynamic
+            (class_id, date, slot_id, faculty_id, subject_id) VALUES (?, ?, ?, ?, ?)`,
+			b.Class, b.Date.Format(dateLayout), b.Slot, b.Faculty, b.Subject)
+		if err != nil {
+			return s.translate(err)
+		}
+	}
+	for _, t := range f.Calendar.Terms {
+		_, err := tx.ExecContext(ctx, `INSERT INTO term (name, start_date, end_date) VALUES (?, ?, ?)`,
+			t.Name, t.Start.Format(dateLayout), t.End.Format(dateLayout))
+		if err != nil {
+			return s.translate(err)
+		}
+	}
+	for _, h := range f.Calendar.Holidays {
+		end := h.End
+		if end.IsZero() {
+			end = h.Start
+		}
+		_, err := tx.ExecContext(ctx, `INSERT INTO holiday (name, start_date, end_date) VALUES (?, ?, ?)`,
+			h.Name, h.Start.Format(dateLayout), end.Format(dateLayout))
+		if err != nil {
+			return s.translate(err)
+		}
+	}
+	for _, o := range f.Calendar.DayOrders {
+		_, err := tx.ExecContext(ctx, `INSERT INTO day_order (date, day) VALUES (?, ?)`,
+			o.Date.Format(dateLayout), o.Day)
+		if err != nil {
+			return s.translate(err)
+		}
+	}
+	return s.translate(tx.Commit())
+}
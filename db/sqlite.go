@@ -0,0 +1,71 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// DefaultSQLitePath is the database file used by the sqlite driver when the
+// configuration does not name one
+const DefaultSQLitePath = "cora.db"
+
+var sqliteDialect = dialect{translate: sqliteError}
+
+func openSQLite(cfg Config) (*sqlStore, error) {
+	db, err := connectSQLite(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newSQLStore(db, sqliteDialect)
+}
+
+/*
+connectSQLite opens a file backed SQLite database. Unless the DSN already carries
+options, foreign keys are switched on (SQLite leaves them off by default),
+writers wait for each other instead of failing with SQLITE_BUSY, and
+transactions take the write lock as soon as they begin. The last one is what
+stands in for MySQL's SELECT ... FOR UPDATE.
+*/
+func connectSQLite(cfg Config) (*sql.DB, error) {
+	dsn := cfg.DSN
+	if dsn == "" {
+		dsn = DefaultSQLitePath
+	}
+	if !strings.Contains(dsn, "?") {
+		dsn += "?_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate"
+	}
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	return db, nil
+}
+
+// sqliteError translates a driver error into one of the package sentinels
+func sqliteError(err error) error {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return commonError(err)
+	}
+	switch sqliteErr.ExtendedCode {
+	case sqlite3.ErrConstraintPrimaryKey, sqlite3.ErrConstraintUnique:
+		return fmt.Errorf("%w: %v", ErrConflict, err)
+	case sqlite3.ErrConstraintForeignKey:
+		return fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	switch sqliteErr.Code {
+	case sqlite3.ErrBusy, sqlite3.ErrLocked, sqlite3.ErrCantOpen:
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return err
+}
@@ -1,8 +1,9 @@
 module github.com/deebakkarthi/coraserver
 
-go 1.15
+go 1.22
 
 require (
 	github.com/go-sql-driver/mysql v1.7.1
-	golang.org/x/oauth2 v0.8.0
+	github.com/mattn/go-sqlite3 v1.14.17
+	golang.org/x/oauth2 v0.24.0
 )
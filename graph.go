@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultGraphURL     = "https://graph.microsoft.com/v1.0/"
+	defaultGraphTimeout = 10 * time.Second
+	// graphBackoff is the wait before the first retry, doubled after each
+	graphBackoff = 200 * time.Millisecond
+)
+
+/*
+GraphClient is the part of Microsoft Graph the login uses. It is an interface
+so that the handlers can be tested against a fake.
+*/
+type GraphClient interface {
+	Me(ctx context.Context, accessToken string) (graphMe, error)
+	Organization(ctx context.Context, accessToken string) (graphOrganization, error)
+}
+
+// graphConfig is the "graph" section of config.json
+type graphConfig struct {
+	// BaseURL defaults to the global Graph v1.0 endpoint. National clouds
+	// have their own, e.g. https://graph.microsoft.us/v1.0/
+	BaseURL string `json:"baseURL"`
+	// Timeout is in seconds and bounds every attempt
+	Timeout int `json:"timeout"`
+	// Retries is how often a request failing with a network error, 429 or
+	// 5xx is repeated
+	Retries int `json:"retries"`
+}
+
+// httpGraphClient is the GraphClient that talks to the real thing
+type httpGraphClient struct {
+	baseURL string
+	client  *http.Client
+	retries int
+	backoff time.Duration
+}
+
+func newGraphClient(cfg graphConfig) *httpGraphClient {
+	c := &httpGraphClient{
+		baseURL: cfg.BaseURL,
+		client:  &http.Client{Timeout: defaultGraphTimeout},
+		retries: cfg.Retries,
+		backoff: graphBackoff,
+	}
+	if c.baseURL == "" {
+		c.baseURL = defaultGraphURL
+	}
+	if !strings.HasSuffix(c.baseURL, "/") {
+		c.baseURL += "/"
+	}
+	if cfg.Timeout > 0 {
+		c.client.Timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	return c
+}
+
+func (c *httpGraphClient) Me(ctx context.Context, accessToken string) (graphMe, error) {
+	var profile graphMe
+	err := c.get(ctx, accessToken, "me", &profile)
+	return profile, err
+}
+
+func (c *httpGraphClient) Organization(ctx context.Context, accessToken string) (graphOrganization, error) {
+	var organization graphOrganization
+	err := c.get(ctx, accessToken, "organization", &organization)
+	return organization, err
+}
+
+// errRetry marks a failed attempt that is worth repeating
+type errRetry struct {
+	err   error
+	after time.Duration
+}
+
+func (e *errRetry) Error() string {
+	return e.err.Error()
+}
+
+// get decodes the response to GET endpoint into v, retrying transient failures
+func (c *httpGraphClient) get(ctx context.Context, accessToken string, endpoint string, v interface{}) error {
+	wait := c.backoff
+	for attempt := 0; ; attempt++ {
+		err := c.try(ctx, accessToken, endpoint, v)
+		var retry *errRetry
+		if !errors.As(err, &retry) {
+			return err
+		}
+		if attempt >= c.retries {
+			return retry.err
+		}
+		if retry.after > 0 {
+			wait = retry.after
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+func (c *httpGraphClient) try(ctx context.Context, accessToken string, endpoint string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		var netErr net.Error
+		if ctx.Err() == nil && errors.As(err, &netErr) {
+			return &errRetry{err: err}
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return json.NewDecoder(resp.Body).Decode(v)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		retryAfter, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return &errRetry{
+			err:   fmt.Errorf("graph %s: unexpected response status: %s", endpoint, resp.Status),
+			after: time.Duration(retryAfter) * time.Second,
+		}
+	default:
+		return fmt.Errorf("graph %s: unexpected response status: %s", endpoint, resp.Status)
+	}
+}
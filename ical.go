@@ -0,0 +1,246 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deebakkarthi/coraserver/db"
+)
+
+// A feed covers calendarPast days before the current week and runs for
+// db.MaxRangeDays days. Calendar apps refetch it, so the window moves along.
+const calendarPast = 14
+
+// Times in a feed are floating, the wall clock time of the campus
+const (
+	icsDate     = "20060102"
+	icsDateTime = "20060102T150405"
+)
+
+/*
+roomCalendarHandler serves /calendar/room/A104.ics, the timetable of a room
+as an iCalendar feed. Like a timetable on the door it needs no sign in, and
+for the same reason it does not name the faculty.
+*/
+func (s *server) roomCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	room, ok := strings.CutSuffix(r.PathValue("file"), ".ics")
+	rooms, err := s.store.GetAllClass(r.Context())
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	if !ok || !contains(rooms, room) {
+		writeError(w, http.StatusNotFound, apiError{Code: codeNotFound, Message: "No such room"})
+		return
+	}
+	from, to := calendarWindow(time.Now())
+	days, err := s.store.GetTimetableRange(r.Context(), room, from, to)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	writeCalendar(w, room, days, time.Now())
+}
+
+/*
+facultyCalendarHandler serves /calendar/faculty/<token>.ics, the classes and
+bookings of the faculty member a calendar token was minted for
+*/
+func (s *server) facultyCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutSuffix(r.PathValue("file"), ".ics")
+	if !ok || !strings.HasPrefix(token, apiTokenPrefix) {
+		writeError(w, http.StatusNotFound, apiError{Code: codeNotFound, Message: "No such calendar"})
+		return
+	}
+	t, err := s.store.UseAPIToken(r.Context(), hashAPIToken(token), time.Now())
+	if err == nil && t.Scope != db.ScopeCalendar {
+		err = db.ErrNotFound
+	}
+	if errors.Is(err, db.ErrNotFound) {
+		writeError(w, http.StatusNotFound, apiError{Code: codeNotFound, Message: "No such calendar"})
+		return
+	}
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	from, to := calendarWindow(time.Now())
+	days, err := s.store.GetFacultySchedule(r.Context(), t.Faculty, from, to)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	writeCalendar(w, t.Faculty, days, time.Now())
+}
+
+// calendarTokenV2 is a new calendar token with the address of its feed
+type calendarTokenV2 struct {
+	apiTokenResponse
+	// URL is relative to the server
+	URL string `json:"url"`
+}
+
+/*
+v2CreateCalendarToken gives the signed in faculty member a private address
+for their calendar feed. An admin can revoke it like any API token.
+*/
+func (s *server) v2CreateCalendarToken(w http.ResponseWriter, r *http.Request) {
+	id, _ := identityFrom(r.Context())
+	response, err := s.mintAPIToken(r, id, "Calendar feed", db.ScopeCalendar, id.Mail)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	url := "/calendar/faculty/" + response.Token + ".ics"
+	w.Header().Set("Location", url)
+	writeJSON(w, http.StatusCreated, calendarTokenV2{response, url})
+}
+
+// calendarWindow is the range of dates a feed fetched at now covers
+func calendarWindow(now time.Time) (from time.Time, to time.Time) {
+	from = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from = from.AddDate(0, 0, -(int(from.Weekday())+6)%7-calendarPast)
+	return from, from.AddDate(0, 0, db.MaxRangeDays-1)
+}
+
+// series is a class of the static timetable, held on the same weekday
+type series struct {
+	uid   string
+	entry db.TimetableEntry
+	dates []time.Time
+}
+
+/*
+writeCalendar writes days as an iCalendar feed named name. Each static class
+becomes one weekly event, with an RRULE running to its last date in days and
+an EXDATE wherever it does not take place, e.g. because the room was booked
+over. Bookings are single events. FREE slots are left out.
+*/
+func writeCalendar(w http.ResponseWriter, name string, days []db.DayTimetable, now time.Time) {
+	byKey := make(map[string]*series)
+	var classes []*series
+	var bookings []db.DayTimetable
+	for _, day := range days {
+		booked := db.DayTimetable{Date: day.Date}
+		for _, e := range day.Entries {
+			switch {
+			case e.Subject == "FREE":
+			case e.Source == db.SourceBooking:
+				booked.Entries = append(booked.Entries, e)
+			default:
+				// The UID stays the same while the window moves along
+				key := fmt.Sprintf("class-%s-%s-%d-%s", e.Room, strings.ToLower(day.Date.Weekday().String()[:3]), e.Slot, e.Subject)
+				if byKey[key] == nil {
+					byKey[key] = &series{uid: key, entry: e}
+					classes = append(classes, byKey[key])
+				}
+				byKey[key].dates = append(byKey[key].dates, day.Date)
+			}
+		}
+		if len(booked.Entries) > 0 {
+			bookings = append(bookings, booked)
+		}
+	}
+	sort.SliceStable(classes, func(i, j int) bool {
+		a, b := classes[i], classes[j]
+		if !a.dates[0].Equal(b.dates[0]) {
+			return a.dates[0].Before(b.dates[0])
+		}
+		return a.entry.Slot < b.entry.Slot
+	})
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	c := &icsWriter{w: w}
+	c.line("BEGIN:VCALENDAR")
+	c.line("VERSION:2.0")
+	c.line("PRODID:-//coraserver//EN")
+	c.line("CALSCALE:GREGORIAN")
+	c.line("METHOD:PUBLISH")
+	c.line("X-WR-CALNAME:" + icsText(name))
+	stamp := now.UTC().Format(icsDateTime) + "Z"
+	for _, cl := range classes {
+		first, last := cl.dates[0], cl.dates[len(cl.dates)-1]
+		c.event(cl.entry, first, stamp, cl.uid, func() {
+			c.line("RRULE:FREQ=WEEKLY;UNTIL=" + last.Format(icsDate) + "T235959")
+			held := 0
+			for date := first; !date.After(last); date = date.AddDate(0, 0, 7) {
+				if held < len(cl.dates) && cl.dates[held].Equal(date) {
+					held++
+					continue
+				}
+				c.line("EXDATE:" + icsTime(date, cl.entry.Start))
+			}
+			c.line("CATEGORIES:Class")
+		})
+	}
+	for _, day := range bookings {
+		for _, e := range day.Entries {
+			c.event(e, day.Date, stamp, "booking-"+bookingID(e.Room, day.Date, e.Slot), func() {
+				c.line("CATEGORIES:Booking")
+			})
+		}
+	}
+	c.line("END:VCALENDAR")
+	if c.err != nil {
+		log.Println("Error writing calendar", c.err)
+	}
+}
+
+// icsTime is a floating DATE-TIME for clock, a slot time like 08:50
+func icsTime(date time.Time, clock string) string {
+	return date.Format(icsDate) + "T" + strings.ReplaceAll(clock, ":", "") + "00"
+}
+
+// icsText escapes a TEXT value
+func icsText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// icsWriter writes content lines, folded at 75 octets as RFC 5545 asks
+type icsWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (c *icsWriter) line(s string) {
+	for len(s) > 75 {
+		// Never split a UTF-8 sequence
+		n := 75
+		for n > 0 && s[n]&0xC0 == 0x80 {
+			n--
+		}
+		c.write(s[:n] + "\r\n")
+		s = " " + s[n:]
+	}
+	c.write(s + "\r\n")
+}
+
+func (c *icsWriter) write(s string) {
+	if c.err == nil {
+		_, c.err = io.WriteString(c.w, s)
+	}
+}
+
+// event writes a VEVENT for e on date, extra adds the lines that differ
+func (c *icsWriter) event(e db.TimetableEntry, date time.Time, stamp string, uid string, extra func()) {
+	c.line("BEGIN:VEVENT")
+	c.line("UID:" + uid + "@coraserver")
+	c.line("DTSTAMP:" + stamp)
+	c.line("DTSTART:" + icsTime(date, e.Start))
+	c.line("DTEND:" + icsTime(date, e.End))
+	summary := e.SubjectName
+	if summary == "" {
+		summary = e.Subject
+	}
+	c.line("SUMMARY:" + icsText(summary))
+	c.line("LOCATION:" + icsText(e.Room))
+	c.line("DESCRIPTION:" + icsText(e.Subject))
+	extra()
+	c.line("END:VEVENT")
+}
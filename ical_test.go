@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deebakkarthi/coraserver/db"
+)
+
+func TestWriteCalendar(t *testing.T) {
+	monday := time.Date(2023, 6, 12, 0, 0, 0, 0, time.UTC)
+	class := db.TimetableEntry{Room: "A104", Slot: 1, Start: "08:50", End: "09:40",
+		Subject: "19CSE312", SubjectName: "Distributed Systems, Part 1; an introduction to a rather long title",
+		Faculty: "d_bharathi@cb.amrita.edu", Source: db.SourceStatic}
+	free := db.TimetableEntry{Room: "A104", Slot: 2, Start: "09:40", End: "10:30", Subject: "FREE", Source: db.SourceStatic}
+	booking := db.TimetableEntry{Room: "A104", Slot: 1, Start: "08:50", End: "09:40",
+		Subject: "19CSE446", SubjectName: "Internet of Things", Source: db.SourceBooking}
+	days := []db.DayTimetable{
+		{Date: monday, Entries: []db.TimetableEntry{class, free}},
+		{Date: monday.AddDate(0, 0, 7), Entries: []db.TimetableEntry{booking, free}},
+		{Date: monday.AddDate(0, 0, 14), Entries: []db.TimetableEntry{class, free}},
+		{Date: monday.AddDate(0, 0, 21), Entries: []db.TimetableEntry{free}},
+	}
+	w := httptest.NewRecorder()
+	writeCalendar(w, "A104", days, monday)
+	body := w.Body.String()
+
+	if w.Header().Get("Content-Type") != "text/calendar; charset=utf-8" {
+		t.Errorf("Unexpected content type %s", w.Header().Get("Content-Type"))
+	}
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"UID:class-A104-mon-1-19CSE312@coraserver\r\n",
+		"DTSTART:20230612T085000\r\nDTEND:20230612T094000\r\n",
+		"RRULE:FREQ=WEEKLY;UNTIL=20230626T235959\r\n",
+		"EXDATE:20230619T085000\r\n",
+		"SUMMARY:Distributed Systems\\, Part 1\\; an introduction to a rather long tit\r\n le\r\n",
+		"UID:booking-A104_2023-06-19_1@coraserver\r\n",
+		"DTSTART:20230619T085000\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in\n%s", want, body)
+		}
+	}
+	if n := strings.Count(body, "BEGIN:VEVENT"); n != 2 {
+		t.Errorf("Expected 2 events, got %d", n)
+	}
+	if strings.Contains(body, "FREE") || strings.Contains(body, "bharathi") {
+		t.Errorf("Free slots and faculty must not appear:\n%s", body)
+	}
+	for _, line := range strings.Split(body, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Line longer than 75 octets: %q", line)
+		}
+	}
+}
+
+func TestCalendarFeeds(t *testing.T) {
+	s, _ := newV2TestServer(t)
+	h := s.newRouter()
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	w := get("/calendar/room/A104.ics")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "RRULE:FREQ=WEEKLY") {
+		t.Errorf("Expected the room feed, got %d %s", w.Code, w.Body)
+	}
+	if strings.Contains(w.Body.String(), "@cb.amrita.edu") {
+		t.Errorf("The public room feed names the faculty")
+	}
+	for _, path := range []string{"/calendar/room/Z999.ics", "/calendar/room/A104", "/calendar/faculty/nothing.ics"} {
+		if w := get(path); w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, w.Code)
+		}
+	}
+
+	const faculty = "d_bharathi@cb.amrita.edu"
+	w = do(t, s, h, "POST", "/api/v2/me/calendar", "", faculty, roleFaculty)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Failed to create the feed: %d %s", w.Code, w.Body)
+	}
+	var token calendarTokenV2
+	json.Unmarshal(w.Body.Bytes(), &token)
+	if token.Faculty != faculty || token.Scope != db.ScopeCalendar {
+		t.Errorf("Unexpected token %+v", token)
+	}
+	w = get(token.URL)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "LOCATION:C102") {
+		t.Errorf("Expected the faculty feed, got %d %s", w.Code, w.Body)
+	}
+
+	// The token opens the feed and nothing else
+	req := httptest.NewRequest("GET", "/api/v2/rooms", nil)
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Calendar token accepted by the API: %d", w.Code)
+	}
+
+	if w := do(t, s, h, "DELETE", "/api/v2/tokens/"+token.ID, "", "admin@cb.amrita.edu", roleAdmin); w.Code != http.StatusNoContent {
+		t.Fatalf("Failed to revoke: %d %s", w.Code, w.Body)
+	}
+	if w := get(token.URL); w.Code != http.StatusNotFound {
+		t.Errorf("Revoked feed still served: %d", w.Code)
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultAuthority is where Azure AD lives in the global cloud
+	defaultAuthority = "https://login.microsoftonline.com"
+	// azureIssuer is the issuer of Azure AD v2.0 ID tokens, {tenantid} is
+	// replaced by the tid claim of the token
+	azureIssuer = defaultAuthority + "/{tenantid}/v2.0"
+	// clockSkew is how far our clock may be off from the issuer's
+	clockSkew = time.Minute
+	// defaultKeyTTL is how long a key set is cached when the issuer does
+	// not say, minKeyRefresh how often an unknown key may cause a refetch
+	defaultKeyTTL = time.Hour
+	minKeyRefresh = time.Minute
+)
+
+var errUnknownKey = errors.New("id_token signed with an unknown key")
+
+/*
+idClaims are the claims of a verified ID token. The registered claims checked
+by the verifier are decoded into fields, everything else is looked up by name
+because which claims carry the user's details differs between providers (see
+claimMapping).
+*/
+type idClaims struct {
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	Expires   int64    `json:"exp"`
+	NotBefore int64    `json:"nbf"`
+	all       map[string]interface{}
+}
+
+// get returns the string claim called name, or "" if there is none
+func (c idClaims) get(name string) string {
+	s, _ := c.all[name].(string)
+	return s
+}
+
+// isTrue reports whether the boolean claim called name is true. Some issuers
+// send booleans as strings.
+func (c idClaims) isTrue(name string) bool {
+	switch v := c.all[name].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+// audience is the aud claim, which may be a string or an array of them
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+func (a audience) contains(clientID string) bool {
+	for _, aud := range a {
+		if aud == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+// keySet finds the public key an ID token was signed with
+type keySet interface {
+	key(ctx context.Context, kid string) (*rsa.PublicKey, error)
+}
+
+// staticKeySet is a fixed key set, for tests and for issuers without JWKS
+type staticKeySet map[string]*rsa.PublicKey
+
+func (s staticKeySet) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	if k, ok := s[kid]; ok {
+		return k, nil
+	}
+	return nil, errUnknownKey
+}
+
+/*
+remoteKeySet is the JSON Web Key Set published by the issuer at url. The keys
+are cached for as long as the response's Cache-Control allows, and fetched
+again early when a token names a key that isn't in the cache, which is how a
+key rotation shows up.
+*/
+type remoteKeySet struct {
+	url    string
+	client *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]*rsa.PublicKey
+	expires time.Time
+	fetched time.Time
+}
+
+func newRemoteKeySet(url string) *remoteKeySet {
+	return &remoteKeySet{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (s *remoteKeySet) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if k, ok := s.keys[kid]; ok && now.Before(s.expires) {
+		return k, nil
+	}
+	if s.keys != nil && now.Before(s.expires) && now.Sub(s.fetched) < minKeyRefresh {
+		return nil, errUnknownKey
+	}
+	if err := s.fetch(ctx); err != nil {
+		return nil, err
+	}
+	if k, ok := s.keys[kid]; ok {
+		return k, nil
+	}
+	return nil, errUnknownKey
+}
+
+// fetch replaces the cached keys. The caller must hold the lock.
+func (s *remoteKeySet) fetch(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetching key set: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching key set: unexpected response status: %s", resp.Status)
+	}
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return fmt.Errorf("decoding key set: %w", err)
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(e) > 4 {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	s.keys = keys
+	s.fetched = time.Now()
+	s.expires = s.fetched.Add(maxAge(resp.Header.Get("Cache-Control")))
+	return nil
+}
+
+// maxAge returns the max-age of a Cache-Control header, or defaultKeyTTL
+func maxAge(cacheControl string) time.Duration {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.TrimSpace(directive)
+		if strings.HasPrefix(directive, "max-age=") {
+			seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+			if err == nil && seconds > 0 {
+				return time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return defaultKeyTTL
+}
+
+/*
+idTokenVerifier checks the ID token returned with the access token: that it
+is signed (RS256) by one of the issuer's keys, was issued by issuer for
+clientID and has not expired. The issuer may contain {tenantid}, which is
+filled in from the token's tid claim, as Azure AD's multi-tenant endpoints
+issue tokens in the name of the user's own tenant.
+*/
+type idTokenVerifier struct {
+	keys     keySet
+	issuer   string
+	clientID string
+	now      func() time.Time
+}
+
+func newIDTokenVerifier(keys keySet, issuer string, clientID string) *idTokenVerifier {
+	return &idTokenVerifier{keys: keys, issuer: issuer, clientID: clientID, now: time.Now}
+}
+
+func (v *idTokenVerifier) verify(ctx context.Context, raw string) (idClaims, error) {
+	var claims idClaims
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return claims, errors.New("malformed id_token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return claims, fmt.Errorf("malformed id_token header: %w", err)
+	}
+	// Never trust the token to pick a weaker algorithm, "none" included
+	if header.Alg != "RS256" {
+		return claims, fmt.Errorf("unsupported id_token algorithm %q", header.Alg)
+	}
+	key, err := v.keys.key(ctx, header.Kid)
+	if err != nil {
+		return claims, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return claims, fmt.Errorf("malformed id_token signature: %w", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return claims, errors.New("invalid id_token signature")
+	}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return claims, fmt.Errorf("malformed id_token claims: %w", err)
+	}
+	if err := decodeSegment(parts[1], &claims.all); err != nil {
+		return claims, fmt.Errorf("malformed id_token claims: %w", err)
+	}
+
+	issuer := strings.ReplaceAll(v.issuer, "{tenantid}", claims.get("tid"))
+	if claims.Issuer != issuer {
+		return claims, fmt.Errorf("id_token issued by %q, expected %q", claims.Issuer, issuer)
+	}
+	if !claims.Audience.contains(v.clientID) {
+		return claims, errors.New("id_token was not issued for this app")
+	}
+	now := v.now()
+	if now.After(time.Unix(claims.Expires, 0).Add(clockSkew)) {
+		return claims, errors.New("id_token has expired")
+	}
+	if claims.NotBefore != 0 && now.Add(clockSkew).Before(time.Unix(claims.NotBefore, 0)) {
+		return claims, errors.New("id_token is not valid yet")
+	}
+	return claims, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	testClientID = "test-client"
+	testTenant   = "tenant-a"
+)
+
+var testKey, otherKey *rsa.PrivateKey
+
+func init() {
+	var err error
+	if testKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		panic(err)
+	}
+	if otherKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		panic(err)
+	}
+}
+
+// signToken makes a JWT with the given header and claims, signed by key
+func signToken(t *testing.T, key *rsa.PrivateKey, header map[string]string, claims map[string]interface{}) string {
+	t.Helper()
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("Failed to marshal: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := encode(header) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// testClaims are the claims of a valid Azure AD ID token
+func testClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":                "https://login.microsoftonline.com/" + testTenant + "/v2.0",
+		"aud":                testClientID,
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"nbf":                time.Now().Add(-time.Minute).Unix(),
+		"tid":                testTenant,
+		"name":               "Test Faculty",
+		"given_name":         "Test",
+		"preferred_username": "test.faculty@a.edu",
+	}
+}
+
+var ctx = context.Background()
+
+var testHeader = map[string]string{"alg": "RS256", "kid": "k1"}
+
+func TestVerifyIDToken(t *testing.T) {
+	v := newIDTokenVerifier(staticKeySet{"k1": &testKey.PublicKey}, azureIssuer, testClientID)
+	with := func(key string, value interface{}) map[string]interface{} {
+		c := testClaims()
+		c[key] = value
+		return c
+	}
+
+	claims, err := v.verify(ctx, signToken(t, testKey, testHeader, testClaims()))
+	if err != nil {
+		t.Fatalf("Valid token rejected: %v", err)
+	}
+	if claims.get("preferred_username") != "test.faculty@a.edu" || claims.get("tid") != testTenant {
+		t.Errorf("Unexpected claims %+v", claims)
+	}
+	if _, err := v.verify(ctx, signToken(t, testKey, testHeader, with("aud", []string{"other", testClientID}))); err != nil {
+		t.Errorf("Token with an audience list rejected: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"Garbage", "not.a.token"},
+		{"Other key", signToken(t, otherKey, testHeader, testClaims())},
+		{"Unknown kid", signToken(t, testKey, map[string]string{"alg": "RS256", "kid": "k2"}, testClaims())},
+		{"Algorithm none", signToken(t, testKey, map[string]string{"alg": "none", "kid": "k1"}, testClaims())},
+		{"Other issuer", signToken(t, testKey, testHeader, with("iss", "https://evil.example.com"))},
+		{"Issuer of another tenant", signToken(t, testKey, testHeader, with("tid", "tenant-b"))},
+		{"Other audience", signToken(t, testKey, testHeader, with("aud", "other"))},
+		{"Expired", signToken(t, testKey, testHeader, with("exp", time.Now().Add(-time.Hour).Unix()))},
+		{"Not yet valid", signToken(t, testKey, testHeader, with("nbf", time.Now().Add(time.Hour).Unix()))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := v.verify(ctx, tt.token); err == nil {
+				t.Error("Token was accepted")
+			}
+		})
+	}
+
+	// A signature over different claims must not verify
+	valid := strings.Split(signToken(t, testKey, testHeader, testClaims()), ".")
+	forged := strings.Split(signToken(t, otherKey, testHeader, with("preferred_username", "admin@a.edu")), ".")
+	if _, err := v.verify(ctx, forged[0]+"."+forged[1]+"."+valid[2]); err == nil {
+		t.Error("Token with swapped claims was accepted")
+	}
+}
+
+func TestRemoteKeySet(t *testing.T) {
+	var fetches int
+	kid := "k1"
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"kid": kid,
+			"n":   base64.RawURLEncoding.EncodeToString(testKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(testKey.E)).Bytes()),
+		}}})
+	}))
+	defer jwks.Close()
+	keys := newRemoteKeySet(jwks.URL)
+
+	for i := 0; i < 2; i++ {
+		key, err := keys.key(ctx, "k1")
+		if err != nil {
+			t.Fatalf("Failed to get key: %v", err)
+		}
+		if key.N.Cmp(testKey.N) != 0 || key.E != testKey.E {
+			t.Error("Wrong key")
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("Expected the key set to be fetched once, got %d", fetches)
+	}
+
+	// An unknown key does not refetch right away, so bad tokens can't be
+	// used to hammer the issuer
+	if _, err := keys.key(ctx, "k2"); err != errUnknownKey {
+		t.Errorf("Expected errUnknownKey, got %v", err)
+	}
+	if fetches != 1 {
+		t.Errorf("Unknown key refetched immediately")
+	}
+
+	// After a rotation the new key is picked up
+	kid = "k2"
+	keys.fetched = keys.fetched.Add(-2 * minKeyRefresh)
+	if _, err := keys.key(ctx, "k2"); err != nil {
+		t.Errorf("Rotated key not found: %v", err)
+	}
+	if fetches != 2 {
+		t.Errorf("Expected a second fetch, got %d", fetches)
+	}
+}
+
+func TestOAuthExchange(t *testing.T) {
+	idToken := signToken(t, testKey, testHeader, testClaims())
+	tokenEndpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("code_verifier") == "" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"at","token_type":"Bearer","expires_in":3600,"id_token":%q}`, idToken)
+	}))
+	defer tokenEndpoint.Close()
+
+	m, _ := newSessionManager("", 0, false)
+	roles, _ := newRolePolicy(nil, []string{"*@a.edu"})
+	orgs, _ := newOrgPolicy([]orgConfig{{ID: testTenant, Name: "University A"}}, "", true)
+	s := &server{
+		sessions: m,
+		roles:    roles,
+		orgs:     orgs,
+		provider: &provider{
+			verifier: newIDTokenVerifier(staticKeySet{"k1": &testKey.PublicKey}, azureIssuer, testClientID),
+			claims:   claimMapping{Organization: "tid"},
+			azure:    true,
+		},
+	}
+	oauthConfig = &oauth2.Config{
+		ClientID: testClientID,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  "https://login.example.com/authorize",
+			TokenURL: tokenEndpoint.URL,
+		},
+	}
+
+	exchange := func(loginPath string) oauthExchangeResponse {
+		t.Helper()
+		state, cookie := login(t, s, loginPath)
+		r := httptest.NewRequest("GET", "/oauth/exchange?code=abc&state="+url.QueryEscape(state), nil)
+		r.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		s.oauthExchangeHandler(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+		}
+		var response oauthExchangeResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Bad response: %v", err)
+		}
+		id, err := m.verify(sessionFrom(t, w))
+		if err != nil || id.Mail != response.Mail {
+			t.Errorf("Session cookie does not identify the user: %+v, %v", id, err)
+		}
+		return response
+	}
+
+	response := exchange("/oauth/login")
+	if response.Mail != "test.faculty@a.edu" || response.Organization != "University A" || response.Role != roleFaculty {
+		t.Errorf("Unexpected response %+v", response)
+	}
+	// Scripts on the page must not get to read the HttpOnly cookie's token
+	if response.Token != "" {
+		t.Error("Session token in the response of a cookie login")
+	}
+	response = exchange("/oauth/login?token=bearer")
+	if id, err := m.verify(response.Token); err != nil || id.Mail != response.Mail {
+		t.Errorf("Session token does not identify the user: %+v, %v", id, err)
+	}
+}
+
+// sessionFrom returns the session cookie w sets
+func sessionFrom(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	for _, c := range w.Result().Cookies() {
+		if c.Name == sessionCookie {
+			if !c.HttpOnly {
+				t.Error("Session cookie is readable by scripts")
+			}
+			return c.Value
+		}
+	}
+	t.Fatal("No session cookie set")
+	return ""
+}
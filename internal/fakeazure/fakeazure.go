@@ -0,0 +1,247 @@
+/*
+Package fakeazure is a stand-in for Azure AD and Microsoft Graph, just enough
+of both for coraserver's login: the v2.0 token endpoint, the signing keys and
+Graph's /me and /organization. Tests start one with New, hand out codes with
+AddCode and point coraserver at URL.
+*/
+package fakeazure
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+const keyID = "fakeazure"
+
+// User is who signs in with a code
+type User struct {
+	Name       string
+	GivenName  string
+	Mail       string
+	TenantID   string
+	TenantName string
+	// HideMail leaves the e-mail address out of the ID token, so that it
+	// can only be learnt from Graph
+	HideMail bool
+	// HomeTenantID makes the user a guest of TenantID, whose account
+	// belongs to another tenant
+	HomeTenantID string
+}
+
+/*
+Server is a running fake. Graph is served under /v1.0/, everything else under
+/{tenant}/ like the real login.microsoftonline.com.
+*/
+type Server struct {
+	*httptest.Server
+	ClientID string
+
+	key *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]User
+	// tokens are the access tokens handed out, by the user they belong to
+	tokens      map[string]User
+	graphStatus int
+	graphCalls  int
+}
+
+// New starts a fake that issues tokens to the app clientID
+func New(clientID string) *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	s := &Server{
+		ClientID: clientID,
+		key:      key,
+		codes:    make(map[string]User),
+		tokens:   make(map[string]User),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/me", s.me)
+	mux.HandleFunc("/v1.0/organization", s.organization)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/oauth2/v2.0/token"):
+			s.token(w, r)
+		case strings.HasSuffix(r.URL.Path, "/discovery/v2.0/keys"):
+			s.keys(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// AddCode makes code redeemable, once, for u
+func (s *Server) AddCode(code string, u User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.codes[code] = u
+}
+
+// FailGraph makes Graph answer every request with status, 0 restores it
+func (s *Server) FailGraph(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.graphStatus = status
+}
+
+// GraphCalls is how many requests Graph has received
+func (s *Server) GraphCalls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.graphCalls
+}
+
+func oauthError(w http.ResponseWriter, code string, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": code, "error_description": description})
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		oauthError(w, "invalid_request", err.Error())
+		return
+	}
+	clientID, _, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.Form.Get("client_id")
+	}
+	if clientID != s.ClientID {
+		oauthError(w, "invalid_client", "unknown client")
+		return
+	}
+	if r.Form.Get("code_verifier") == "" {
+		oauthError(w, "invalid_grant", "PKCE code verifier missing")
+		return
+	}
+	s.mu.Lock()
+	u, ok := s.codes[r.Form.Get("code")]
+	delete(s.codes, r.Form.Get("code"))
+	accessToken := randomString()
+	if ok {
+		s.tokens[accessToken] = u
+	}
+	s.mu.Unlock()
+	if !ok {
+		oauthError(w, "invalid_grant", "the code has expired or was already redeemed")
+		return
+	}
+
+	claims := map[string]interface{}{
+		"iss":        s.URL + "/" + u.TenantID + "/v2.0",
+		"aud":        s.ClientID,
+		"iat":        time.Now().Unix(),
+		"nbf":        time.Now().Unix(),
+		"exp":        time.Now().Add(time.Hour).Unix(),
+		"sub":        randomString(),
+		"tid":        u.TenantID,
+		"name":       u.Name,
+		"given_name": u.GivenName,
+	}
+	if !u.HideMail {
+		claims["email"] = u.Mail
+		claims["preferred_username"] = u.Mail
+	}
+	if u.HomeTenantID != "" {
+		claims["idp"] = "https://sts.windows.net/" + u.HomeTenantID + "/"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     s.sign(claims),
+	})
+}
+
+// sign makes an RS256 JWT of claims
+func (s *Server) sign(claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyID})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func (s *Server) keys(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA",
+		"use": "sig",
+		"kid": keyID,
+		"n":   base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+	}}})
+}
+
+// graphUser authenticates a Graph request, writing the error if it fails
+func (s *Server) graphUser(w http.ResponseWriter, r *http.Request) (User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.graphCalls++
+	if s.graphStatus != 0 {
+		http.Error(w, http.StatusText(s.graphStatus), s.graphStatus)
+		return User{}, false
+	}
+	u, ok := s.tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+	if !ok {
+		http.Error(w, "InvalidAuthenticationToken", http.StatusUnauthorized)
+		return User{}, false
+	}
+	return u, true
+}
+
+func (s *Server) me(w http.ResponseWriter, r *http.Request) {
+	u, ok := s.graphUser(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"displayName":       u.Name,
+		"givenName":         u.GivenName,
+		"mail":              u.Mail,
+		"userPrincipalName": u.Mail,
+	})
+}
+
+func (s *Server) organization(w http.ResponseWriter, r *http.Request) {
+	u, ok := s.graphUser(w, r)
+	if !ok {
+		return
+	}
+	// Personal accounts belong to no organization
+	value := []map[string]string{}
+	if u.TenantID != "" {
+		value = append(value, map[string]string{"id": u.TenantID, "displayName": u.TenantName})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"value": value})
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
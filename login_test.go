@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deebakkarthi/coraserver/internal/fakeazure"
+	"golang.org/x/oauth2"
+)
+
+// newFakeAzureServer is a server signing users in at a fake Azure AD
+func newFakeAzureServer(t *testing.T, graphFallback bool) (*server, *fakeazure.Server) {
+	t.Helper()
+	fake := fakeazure.New(testClientID)
+	t.Cleanup(fake.Close)
+
+	m, _ := newSessionManager("", 0, false)
+	roles, _ := newRolePolicy([]string{"admin@a.edu"}, []string{"*@a.edu"})
+	orgs, _ := newOrgPolicy([]orgConfig{{ID: testTenant, Name: "University A"}}, "Only for University A", true)
+	idp := azureProvider(fake.URL, "organizations", testClientID, claimMapping{})
+	graph := newGraphClient(graphConfig{BaseURL: fake.URL + "/v1.0", Retries: 1})
+	graph.backoff = time.Millisecond
+	oauthConfig = &oauth2.Config{ClientID: testClientID, ClientSecret: "secret", Endpoint: idp.endpoint}
+	return &server{
+		sessions:      m,
+		roles:         roles,
+		orgs:          orgs,
+		provider:      idp,
+		graphFallback: graphFallback,
+		graph:         graph,
+	}, fake
+}
+
+// signIn goes through /oauth/login and /oauth/exchange with code
+func signIn(t *testing.T, s *server, code string) *httptest.ResponseRecorder {
+	t.Helper()
+	state, cookie := login(t, s, "/oauth/login")
+	r := httptest.NewRequest("GET", "/oauth/exchange?code="+code+"&state="+url.QueryEscape(state), nil)
+	r.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	s.oauthExchangeHandler(w, r)
+	return w
+}
+
+func TestLogin(t *testing.T) {
+	faculty := fakeazure.User{
+		Name:       "Test Faculty",
+		GivenName:  "Test",
+		Mail:       "test.faculty@a.edu",
+		TenantID:   testTenant,
+		TenantName: "Tenant A",
+	}
+	outsider := fakeazure.User{GivenName: "Eve", Mail: "eve@b.edu", TenantID: "tenant-b"}
+	hidden := faculty
+	hidden.HideMail = true
+	guest := faculty
+	guest.HomeTenantID = "tenant-b"
+
+	tests := []struct {
+		name          string
+		graphFallback bool
+		graphStatus   int
+		user          *fakeazure.User
+		status        int
+		body          string
+		graphCalls    int
+	}{
+		{"Allowed organization", false, 0, &faculty, http.StatusOK, `"organization":"University A"`, 0},
+		{"Role", false, 0, &faculty, http.StatusOK, `"role":"faculty"`, 0},
+		{"Other organization", false, 0, &outsider, http.StatusForbidden, "Only for University A", 0},
+		{"Guest", false, 0, &guest, http.StatusForbidden, "Guest accounts", 0},
+		{"Expired code", false, 0, nil, http.StatusBadRequest, `"field":"code"`, 0},
+		{"No e-mail without Graph", false, 0, &hidden, http.StatusForbidden, "e-mail", 0},
+		{"No e-mail with Graph", true, 0, &hidden, http.StatusOK, `"mail":"test.faculty@a.edu"`, 1},
+		{"Graph not needed", true, 0, &faculty, http.StatusOK, `"mail":"test.faculty@a.edu"`, 0},
+		// One retry, then the login fails
+		{"Graph failure", true, http.StatusServiceUnavailable, &hidden, http.StatusForbidden, "profile could not be read", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, fake := newFakeAzureServer(t, tt.graphFallback)
+			fake.FailGraph(tt.graphStatus)
+			code := "code-" + strings.ReplaceAll(tt.name, " ", "-")
+			if tt.user != nil {
+				fake.AddCode(code, *tt.user)
+			}
+			w := signIn(t, s, code)
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), tt.body) {
+				t.Errorf("Expected %q in the response, got %s", tt.body, w.Body)
+			}
+			if fake.GraphCalls() != tt.graphCalls {
+				t.Errorf("Expected %d Graph calls, got %d", tt.graphCalls, fake.GraphCalls())
+			}
+		})
+	}
+}
+
+func TestLoginCodeIsSingleUse(t *testing.T) {
+	s, fake := newFakeAzureServer(t, false)
+	fake.AddCode("once", fakeazure.User{GivenName: "Test", Mail: "test.faculty@a.edu", TenantID: testTenant})
+	w := signIn(t, s, "once")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	if _, err := s.sessions.verify(sessionFrom(t, w)); err != nil {
+		t.Errorf("Issued session does not verify: %v", err)
+	}
+	if w := signIn(t, s, "once"); w.Code != http.StatusBadRequest {
+		t.Errorf("Replayed code got status %d", w.Code)
+	}
+}
@@ -1,27 +1,32 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
-	"math/rand"
 	"net/http"
-	"strconv"
-	"time"
+	"os"
+	"strings"
 
 	"github.com/deebakkarthi/coraserver/db"
 	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/microsoft"
 )
 
 // Global OAuth Configuration variable
 var oauthConfig *oauth2.Config
 
+// Everything read from config.json. The store itself is opened in main so
+// that a failure to connect is reported once at startup.
+var config oauthJSONRepr
+
 const (
-	configFile     = "./config.json"
-	port           = ":42069"
-	organizationID = "00f9cda3-075e-44e5-aa0b-aba3add6539f"
+	configFile = "./config.json"
+	port       = ":42069"
+	// dateLayout is how dates are written in requests and responses
+	dateLayout = "2006-01-02"
 )
 
 /*
@@ -39,13 +44,54 @@ should always be exported.
 
 type insertResponse struct {
 	Inserted bool `json:"inserted"`
+	// Conflicts lists the slots that prevented the booking
+	Conflicts []db.Conflict `json:"conflicts,omitempty"`
 }
 type oauthJSONRepr struct {
-	ClientID     string   `json:"clientID"`
-	ClientSecret string   `json:"clientSecret"`
-	RedirectURL  string   `json:"redirectURL"`
-	Scopes       []string `json:"scopes"`
-	Tenant       string   `json:"tenant"`
+	ClientID     string    `json:"clientID"`
+	ClientSecret string    `json:"clientSecret"`
+	RedirectURL  string    `json:"redirectURL"`
+	Scopes       []string  `json:"scopes"`
+	Tenant       string    `json:"tenant"`
+	Database     db.Config `json:"database"`
+	// Admins are the e-mail addresses allowed to act on anyone's bookings
+	// and to change the static timetable
+	Admins []string `json:"admins"`
+	// Faculty are patterns matching the e-mail addresses of faculty, who
+	// may book rooms. Everybody else is a student.
+	Faculty []string `json:"faculty"`
+	// Organizations may sign in, anybody else is shown RejectionMessage
+	Organizations    []orgConfig `json:"organizations"`
+	RejectionMessage string      `json:"rejectionMessage"`
+	// GraphFallback asks Microsoft Graph for the e-mail address, name or
+	// tenant when the ID token does not carry them
+	GraphFallback bool        `json:"graphFallback"`
+	Graph         graphConfig `json:"graph"`
+	// Provider is the OpenID Connect issuer to sign in with instead of
+	// Azure AD
+	Provider providerConfig `json:"provider"`
+	// SessionKey is the base64 encoded secret that session tokens are
+	// signed with, SessionTTL their lifetime in seconds
+	SessionKey string `json:"sessionKey"`
+	SessionTTL int    `json:"sessionTTL"`
+}
+
+// server carries the dependencies shared by the HTTP handlers
+type server struct {
+	store    db.Store
+	sessions *sessionManager
+	roles    *rolePolicy
+	orgs     *orgPolicy
+	provider *provider
+	// graphFallback lets an Azure AD login ask Microsoft Graph for what the
+	// ID token lacks
+	graphFallback bool
+	graph         GraphClient
+}
+
+// actor identifies the user making a change
+func actor(id identity) db.Actor {
+	return db.Actor{ID: id.Mail, Admin: id.Role == roleAdmin}
 }
 
 type graphMe struct {
@@ -115,412 +161,541 @@ type oauthExchangeResponse struct {
 	Name         string `json:"name"`
 	Mail         string `json:"mail"`
 	Organization string `json:"organization"`
+	Role         role   `json:"role"`
+	// Token is the session token, which is set as an HttpOnly cookie. It is
+	// only in the response when the login was started with
+	// /oauth/login?token=bearer, by clients that do not keep cookies and
+	// send it back in an Authorization: Bearer header instead; a page's
+	// scripts must not get to read it.
+	Token string `json:"token,omitempty"`
 }
 
 /*
-=init()= is a special type of function like =main()= that is called automatically
-by the go runtime. It is used to setup things that are needed before the main
-function. Here we are setting up the oauthConfig variable by unmarshalling the
-=config.json= file
+loadConfig reads config.json and sets up the oauthConfig variable from it. It
+used to be =init()=, a special function the go runtime calls before =main()=,
+but that made the configuration a requirement for merely loading the package,
+tests included.
 */
-func init() {
-
-	file, err := ioutil.ReadFile(configFile)
+func loadConfig(path string) error {
+	file, err := ioutil.ReadFile(path)
 	if err != nil {
-		log.Fatal("Error reading JSON file:", err)
+		return fmt.Errorf("Error reading JSON file: %w", err)
 	}
 
 	var jsonData oauthJSONRepr
 	err = json.Unmarshal(file, &jsonData)
 	if err != nil {
-		log.Fatal("Error unmarshalling JSON:", err)
+		return fmt.Errorf("Error unmarshalling JSON: %w", err)
 	}
 
+	// The login is verified through the ID token, which needs openid
+	if !contains(jsonData.Scopes, "openid") {
+		jsonData.Scopes = append(jsonData.Scopes, "openid")
+	}
 	oauthConfig = &oauth2.Config{
 		ClientID:     jsonData.ClientID,
 		ClientSecret: jsonData.ClientSecret,
 		RedirectURL:  jsonData.RedirectURL,
 		Scopes:       jsonData.Scopes,
-		Endpoint:     microsoft.AzureADEndpoint(jsonData.Tenant),
+		// Endpoint depends on the provider, see newProvider
 	}
+	config = jsonData
+	return nil
+}
 
+func contains(list []string, value string) bool {
+	for _, s := range list {
+		if s == value {
+			return true
+		}
+	}
+	return false
 }
 
 func main() {
-	router := http.NewServeMux()
+	if err := loadConfig(configFile); err != nil {
+		log.Fatal(err)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrateCommand(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	store, err := db.Open(config.Database)
+	if err != nil {
+		log.Fatal("Error opening database:", err)
+	}
+	defer store.Close()
+	// Only mark the cookie Secure when the login itself is served over TLS,
+	// otherwise plain http development setups could never log in
+	sessions, err := newSessionManager(config.SessionKey, config.SessionTTL,
+		strings.HasPrefix(config.RedirectURL, "https://"))
+	if err != nil {
+		log.Fatal("Error setting up sessions:", err)
+	}
+	sessions.apiTokens = store
+	roles, err := newRolePolicy(config.Admins, config.Faculty)
+	if err != nil {
+		log.Fatal("Error setting up roles:", err)
+	}
+	orgs, err := newOrgPolicy(config.Organizations, config.RejectionMessage, config.Provider.Issuer == "")
+	if err != nil {
+		log.Fatal("Error setting up organizations:", err)
+	}
+	idp, err := newProvider(context.Background(), config.Provider, config.Tenant, config.ClientID)
+	if err != nil {
+		log.Fatal("Error setting up the identity provider:", err)
+	}
+	oauthConfig.Endpoint = idp.endpoint
+	srv := &server{
+		store:         store,
+		sessions:      sessions,
+		roles:         roles,
+		orgs:          orgs,
+		provider:      idp,
+		graphFallback: config.GraphFallback && idp.azure,
+		graph:         newGraphClient(config.Graph),
+	}
 
-	router.HandleFunc("/oauth/login", oauthLoginHandler)
-	router.HandleFunc("/oauth/exchange", oauthExchangeHandler)
-	router.HandleFunc("/db/freeclass", freeClassHandler)
-	router.HandleFunc("/db/freeslot", freeSlotHandler)
-	router.HandleFunc("/db/daytimetable", dayTimetableHandler)
-	router.HandleFunc("/db/booking", bookingHandler)
-	router.HandleFunc("/db/getAllSlot", getAllSlotHandler)
-	router.HandleFunc("/db/getAllClass", getAllClassHandler)
-	router.HandleFunc("/db/getAllSubject", getAllSubjectHandler)
-	router.HandleFunc("/db/getBooking", getBookingHandler)
-	router.HandleFunc("/db/cancelBooking", cancelBookingHandler)
-	router.HandleFunc("/db/multiFreeSlot", multiFreeSlotHandler)
-	router.HandleFunc("/db/multiBooking", multiBookingHandler)
-
-	server := &http.Server{Addr: port, Handler: router}
+	router := srv.newRouter()
+
+	server := &http.Server{Addr: port, Handler: withRequestID(withJSONErrors(router))}
 
 	log.Println("Server starting on port ", port)
 	log.Fatal(server.ListenAndServe())
 }
 
-func generateRandomString(length int) string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	rand.Seed(time.Now().UnixNano())
-	randomString := make([]byte, length)
-	for i := 0; i < length; i++ {
-		randomString[i] = charset[rand.Intn(len(charset))]
+/*
+legacyRoutes are the /db endpoints of the original API. Unlike /api/v2 the
+GET ones answer any method, Method only says how the clients call them. The
+admin writes added since only answer their Method: the session cookie is
+SameSite=Lax, which still sends it along with a GET from a link on another
+site.
+*/
+func (s *server) legacyRoutes() []route {
+	return []route{
+		{"GET", "/db/freeclass", roleStudent, s.freeClassHandler},
+		{"GET", "/db/freeslot", roleStudent, s.freeSlotHandler},
+		{"GET", "/db/daytimetable", roleStudent, s.dayTimetableHandler},
+		{"GET", "/db/booking", roleFaculty, s.bookingHandler},
+		{"GET", "/db/getAllSlot", roleStudent, s.getAllSlotHandler},
+		{"GET", "/db/getAllClass", roleStudent, s.getAllClassHandler},
+		{"GET", "/db/getAllSubject", roleStudent, s.getAllSubjectHandler},
+		{"GET", "/db/getBooking", roleFaculty, s.getBookingHandler},
+		{"GET", "/db/cancelBooking", roleFaculty, s.cancelBookingHandler},
+		{"GET", "/db/multiFreeSlot", roleStudent, s.multiFreeSlotHandler},
+		{"GET", "/db/multiBooking", roleFaculty, s.multiBookingHandler},
+		{"POST", "/db/setStatic", roleAdmin, s.setStaticHandler},
+		{"POST", "/db/createAPIToken", roleAdmin, s.createAPITokenHandler},
+		{"GET", "/db/getAPITokens", roleAdmin, s.getAPITokensHandler},
+		{"DELETE", "/db/revokeAPIToken", roleAdmin, s.revokeAPITokenHandler},
 	}
-	return string(randomString)
 }
 
-func oauthLoginHandler(w http.ResponseWriter, r *http.Request) {
-	state := generateRandomString(16)
-	authURL := oauthConfig.AuthCodeURL(state, oauth2.AccessTypeOnline, oauth2.SetAuthURLParam("prompt", "select_account"))
-	http.Redirect(w, r, authURL, http.StatusFound)
+// newRouter serves every endpoint of the server
+func (s *server) newRouter() *http.ServeMux {
+	router := http.NewServeMux()
+	router.HandleFunc("/oauth/login", s.oauthLoginHandler)
+	router.HandleFunc("/oauth/exchange", s.oauthExchangeHandler)
+	router.HandleFunc("/oauth/logout", s.oauthLogoutHandler)
+	for _, rt := range s.legacyRoutes() {
+		pattern := rt.Path
+		if rt.Method != "GET" {
+			pattern = rt.Method + " " + rt.Path
+		}
+		router.HandleFunc(pattern, s.sessions.requireRole(rt.Role, rt.Handler))
+	}
+	s.registerV2(router)
+	router.HandleFunc("GET /api/openapi.json", openAPIHandler)
+	// Calendar apps can't sign in, the feeds check their own access
+	router.HandleFunc("GET /calendar/room/{file}", s.roomCalendarHandler)
+	router.HandleFunc("GET /calendar/faculty/{file}", s.facultyCalendarHandler)
+	return router
 }
 
-func requestGraphAPI(accessToken string, endpoint string) ([]byte, error) {
-	url := "https://graph.microsoft.com/v1.0/" + endpoint
-	req, err := http.NewRequest("GET", url, nil)
+/*
+oauthLoginHandler sends the browser to the identity provider. The state and PKCE verifier
+generated here are remembered in a cookie and checked by oauthExchangeHandler.
+*/
+func (s *server) oauthLoginHandler(w http.ResponseWriter, r *http.Request) {
+	state, err := randomToken(32)
 	if err != nil {
-		return nil, err
+		log.Println("Error generating OAuth state", err)
+		writeError(w, http.StatusInternalServerError, apiError{Code: codeInternal, Message: "Internal server error"})
+		return
 	}
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("Accept", "application/json")
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+	attempt := loginAttempt{
+		state:    state,
+		verifier: oauth2.GenerateVerifier(),
+		bearer:   r.URL.Query().Get("token") == "bearer",
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected response status: %s", resp.Status)
+	s.sessions.setLoginCookie(w, attempt)
+	options := append(s.provider.authOptions(), oauth2.AccessTypeOnline,
+		oauth2.S256ChallengeOption(attempt.verifier))
+	authURL := oauthConfig.AuthCodeURL(attempt.state, options...)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+/*
+fillFromGraph asks Microsoft Graph for whatever the ID token did not say:
+/me for the e-mail address and name, /organization for the tenant. Only used
+when graphFallback is set in config.json, as it costs a round trip each.
+*/
+func (s *server) fillFromGraph(ctx context.Context, accessToken string, name *string, mail *string, tenants []organization) ([]organization, error) {
+	if *mail == "" || *name == "" {
+		profile, err := s.graph.Me(ctx, accessToken)
+		if err != nil {
+			return nil, err
+		}
+		// Like the ID token's email claim, mail is not verified
+		if *mail == "" {
+			*mail = profile.UserPrincipalName
+		}
+		if *name == "" {
+			*name = profile.GivenName
+		}
 	}
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	if len(tenants) == 0 {
+		graphOrg, err := s.graph.Organization(ctx, accessToken)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range graphOrg.Value {
+			tenants = append(tenants, organization{ID: v.ID, Name: v.DisplayName})
+		}
 	}
-
-	return body, nil
+	return tenants, nil
 }
 
-func oauthExchangeHandler(w http.ResponseWriter, r *http.Request) {
+func (s *server) oauthExchangeHandler(w http.ResponseWriter, r *http.Request) {
+	attempt, err := s.sessions.checkLogin(r)
+	if err != nil {
+		log.Println("Rejected OAuth exchange", err)
+		writeError(w, http.StatusBadRequest, apiError{Code: codeBadRequest, Message: err.Error(), Field: "state"})
+		return
+	}
+	// The state and verifier are good for one exchange only
+	s.sessions.clearLoginCookie(w)
 	code := r.URL.Query().Get("code")
-	token, err := oauthConfig.Exchange(r.Context(), code)
+	token, err := oauthConfig.Exchange(r.Context(), code, oauth2.VerifierOption(attempt.verifier))
 	if err != nil {
 		log.Println("Error while exchanging authorization code", err)
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(err.Error()))
+		writeError(w, http.StatusBadRequest, apiError{Code: codeBadRequest, Message: exchangeMessage(err), Field: "code"})
 		return
 	}
-	graphMeResponse, err := requestGraphAPI(token.AccessToken, "me")
+	rawIDToken, _ := token.Extra("id_token").(string)
+	claims, err := s.provider.verifier.verify(r.Context(), rawIDToken)
 	if err != nil {
-		log.Println("Error getting user profile", err)
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		w.WriteHeader(http.StatusForbidden)
-		w.Write([]byte(err.Error()))
+		log.Println("Error verifying id_token", err)
+		writeError(w, http.StatusForbidden, apiError{Code: codeForbidden, Message: "The identity provider's answer could not be verified"})
 		return
 	}
-	graphOrganizationResponse, err := requestGraphAPI(token.AccessToken, "organization")
+	name, mail, org, err := s.provider.user(claims)
 	if err != nil {
-		log.Println("Error getting user organization", err)
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		w.WriteHeader(http.StatusForbidden)
-		w.Write([]byte(err.Error()))
+		log.Println("Rejected login of", claims.get("sub"), err)
+		writeError(w, http.StatusForbidden, apiError{Code: codeForbidden, Message: err.Error()})
 		return
 	}
-	var organization graphOrganization
-	var profile graphMe
-	json.Unmarshal(graphMeResponse, &profile)
-	json.Unmarshal(graphOrganizationResponse, &organization)
-	if organization.Value[0].ID == organizationID {
-		response := oauthExchangeResponse{
-			Name:         profile.GivenName,
-			Mail:         profile.Mail,
-			Organization: organization.Value[0].DisplayName,
-		}
-		responseJSON, err := json.Marshal(response)
+	var tenants []organization
+	if org != "" {
+		tenants = append(tenants, organization{ID: org})
+	}
+	if s.graphFallback {
+		tenants, err = s.fillFromGraph(r.Context(), token.AccessToken, &name, &mail, tenants)
 		if err != nil {
-			log.Println("Error marshalling data", err)
-			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(err.Error()))
+			log.Println("Error getting user profile", err)
+			writeError(w, http.StatusForbidden, apiError{Code: codeForbidden, Message: "Your profile could not be read, try again later"})
 			return
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write(responseJSON)
-	} else {
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		w.WriteHeader(http.StatusForbidden)
-		w.Write([]byte("This app is only for members of Amrita Vishwa Vidyapeetham"))
-	}
-	return
+	}
+	if mail == "" {
+		log.Println("No e-mail address in id_token of", claims.get("sub"))
+		writeError(w, http.StatusForbidden, apiError{Code: codeForbidden, Message: "The sign in did not reveal your e-mail address"})
+		return
+	}
+	orgName, err := s.orgs.admit(mail, tenants)
+	if err != nil {
+		log.Println("Rejected login of", mail, err)
+		writeError(w, http.StatusForbidden, apiError{Code: codeForbidden, Message: err.Error()})
+		return
+	}
+	response := oauthExchangeResponse{
+		Name:         name,
+		Mail:         mail,
+		Organization: orgName,
+		Role:         s.roles.roleOf(mail),
+	}
+	session, err := s.sessions.issue(identity{
+		Name:         response.Name,
+		Mail:         response.Mail,
+		Organization: response.Organization,
+		Role:         response.Role,
+	})
+	if err != nil {
+		log.Println("Error issuing session", err)
+		writeError(w, http.StatusInternalServerError, apiError{Code: codeInternal, Message: "Internal server error"})
+		return
+	}
+	s.sessions.setCookie(w, session)
+	if attempt.bearer {
+		response.Token = session
+	}
+	writeJSON(w, http.StatusOK, response)
 }
 
-func freeClassHandler(w http.ResponseWriter, r *http.Request) {
-	slotStr := r.URL.Query().Get("slot")
-	date, err := time.Parse("2006-01-02", r.URL.Query().Get("date"))
+/*
+exchangeMessage tells the user why the authorization code was refused. The
+OAuth error code of the identity provider is kept, its description may carry
+internals and is only logged.
+*/
+func exchangeMessage(err error) string {
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) && retrieveErr.ErrorCode != "" {
+		return "The sign in was refused: " + retrieveErr.ErrorCode
+	}
+	return "The sign in could not be completed"
+}
+
+// oauthLogoutHandler forgets the session cookie of a browser
+func (s *server) oauthLogoutHandler(w http.ResponseWriter, r *http.Request) {
+	s.sessions.clearCookie(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) freeClassHandler(w http.ResponseWriter, r *http.Request) {
+	date, err := queryDate(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeBadRequest(w, err)
 		return
 	}
-	slot, err := strconv.Atoi(slotStr)
+	slot, err := queryInt(r, "slot")
 	if err != nil {
-		http.Error(w, "Invalid slot value", http.StatusBadRequest)
+		writeBadRequest(w, err)
 		return
 	}
-	var classroom []string = db.GetFreeClass(slot, date)
-	responseJSON, err := json.Marshal(classroom)
+	classroom, err := s.store.GetFreeClass(r.Context(), slot, date)
 	if err != nil {
-		log.Println("Error marshalling data", err)
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
+		writeDBError(w, err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(responseJSON)
+	writeJSON(w, http.StatusOK, classroom)
 }
 
-func freeSlotHandler(w http.ResponseWriter, r *http.Request) {
+func (s *server) freeSlotHandler(w http.ResponseWriter, r *http.Request) {
 	class := r.URL.Query().Get("class")
-	date, err := time.Parse("2006-01-02", r.URL.Query().Get("date"))
+	date, err := queryDate(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeBadRequest(w, err)
 		return
 	}
-	var slot []int = db.GetFreeSlot(class, date)
-	responseJSON, err := json.Marshal(slot)
+	slot, err := s.store.GetFreeSlot(r.Context(), class, date)
 	if err != nil {
-		log.Println("Error marshalling data", err)
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
+		writeDBError(w, err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(responseJSON)
+	writeJSON(w, http.StatusOK, slot)
 }
 
-func multiFreeSlotHandler(w http.ResponseWriter, r *http.Request) {
-	startSlotStr := r.URL.Query().Get("startSlot")
-	endSlotStr := r.URL.Query().Get("endSlot")
-	startSlot, err := strconv.Atoi(startSlotStr)
+func (s *server) multiFreeSlotHandler(w http.ResponseWriter, r *http.Request) {
+	startSlot, err := queryInt(r, "startSlot")
 	if err != nil {
-		http.Error(w, "Invalid slot value", http.StatusBadRequest)
+		writeBadRequest(w, err)
 		return
 	}
-	endSlot, err := strconv.Atoi(endSlotStr)
+	endSlot, err := queryInt(r, "endSlot")
 	if err != nil {
-		http.Error(w, "Invalid slot value", http.StatusBadRequest)
+		writeBadRequest(w, err)
 		return
 	}
-	date, err := time.Parse("2006-01-02", r.URL.Query().Get("date"))
+	date, err := queryDate(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeBadRequest(w, err)
 		return
 	}
-	var slot []string = db.MultiFreeSlot(startSlot, endSlot, date)
-	responseJSON, err := json.Marshal(slot)
+	slot, err := s.store.MultiFreeSlot(r.Context(), startSlot, endSlot, date)
 	if err != nil {
-		log.Println("Error marshalling data", err)
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
+		writeDBError(w, err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(responseJSON)
+	writeJSON(w, http.StatusOK, slot)
 }
 
-func dayTimetableHandler(w http.ResponseWriter, r *http.Request) {
+func (s *server) dayTimetableHandler(w http.ResponseWriter, r *http.Request) {
 	class := r.URL.Query().Get("class")
-	date, err := time.Parse("2006-01-02", r.URL.Query().Get("date"))
-	var subject []string = db.GetTimetableByDay(class, date)
-	responseJSON, err := json.Marshal(subject)
+	date, err := queryDate(r)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	entries, err := s.store.GetTimetableByDay(r.Context(), class, date)
 	if err != nil {
-		log.Println("Error marshalling data", err)
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
+		writeDBError(w, err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(responseJSON)
+	// The original API only has the subject of each slot
+	var subject []string
+	for _, e := range entries {
+		subject = append(subject, e.Subject)
+	}
+	writeJSON(w, http.StatusOK, subject)
 }
 
-func getAllSlotHandler(w http.ResponseWriter, r *http.Request) {
-	var slot []int = db.GetAllSlot()
-	responseJSON, err := json.Marshal(slot)
+func (s *server) getAllSlotHandler(w http.ResponseWriter, r *http.Request) {
+	slot, err := s.store.GetAllSlot(r.Context())
 	if err != nil {
-		log.Println("Error marshalling data", err)
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
+		writeDBError(w, err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(responseJSON)
+	writeJSON(w, http.StatusOK, slot)
 }
 
-func getAllClassHandler(w http.ResponseWriter, r *http.Request) {
-	var class []string = db.GetAllClass()
-	responseJSON, err := json.Marshal(class)
+func (s *server) getAllClassHandler(w http.ResponseWriter, r *http.Request) {
+	class, err := s.store.GetAllClass(r.Context())
 	if err != nil {
-		log.Println("Error marshalling data", err)
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
+		writeDBError(w, err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(responseJSON)
+	writeJSON(w, http.StatusOK, class)
 }
 
-func getAllSubjectHandler(w http.ResponseWriter, r *http.Request) {
-	var subject []string = db.GetAllSubject()
-	responseJSON, err := json.Marshal(subject)
+func (s *server) getAllSubjectHandler(w http.ResponseWriter, r *http.Request) {
+	subject, err := s.store.GetAllSubject(r.Context())
 	if err != nil {
-		log.Println("Error marshalling data", err)
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
+		writeDBError(w, err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(responseJSON)
+	writeJSON(w, http.StatusOK, subject)
 }
 
-func getBookingHandler(w http.ResponseWriter, r *http.Request) {
-	faculty := r.URL.Query().Get("faculty")
-	var subject []db.BookingRecord = db.GetBooking(faculty)
-	responseJSON, err := json.Marshal(subject)
+func (s *server) getBookingHandler(w http.ResponseWriter, r *http.Request) {
+	// Only the logged in user's own bookings are listed
+	id, _ := identityFrom(r.Context())
+	subject, err := s.store.GetBooking(r.Context(), id.Mail)
 	if err != nil {
-		log.Println("Error marshalling data", err)
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
+		writeDBError(w, err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(responseJSON)
+	writeJSON(w, http.StatusOK, subject)
 }
 
-func bookingHandler(w http.ResponseWriter, r *http.Request) {
+/*
+writeInsertResponse answers the legacy booking endpoints, which report a
+booking that could not be made in the body rather than the status code
+*/
+func writeInsertResponse(w http.ResponseWriter, rowsAffected int64, err error) {
+	// A clash with an existing booking is reported through the response body,
+	// anything else means the booking could not even be attempted
+	if err != nil && !errors.Is(err, db.ErrConflict) && !errors.Is(err, db.ErrNotFound) {
+		writeDBError(w, err)
+		return
+	}
 	var response insertResponse
+	var conflictErr *db.ConflictError
+	if errors.As(err, &conflictErr) {
+		response.Conflicts = conflictErr.Conflicts
+	} else if err != nil {
+		log.Println(err)
+	}
+	response.Inserted = err == nil && rowsAffected > 0
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *server) bookingHandler(w http.ResponseWriter, r *http.Request) {
 	class := r.URL.Query().Get("class")
-	date, err := time.Parse("2006-01-02", r.URL.Query().Get("date"))
+	date, err := queryDate(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeBadRequest(w, err)
 		return
 	}
-	slot, err := strconv.Atoi(r.URL.Query().Get("slot"))
+	slot, err := queryInt(r, "slot")
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeBadRequest(w, err)
 		return
 	}
-	faculty := r.URL.Query().Get("faculty")
+	// Bookings are always made by and for the logged in user
+	id, _ := identityFrom(r.Context())
 	subject := r.URL.Query().Get("subject")
+	rowsAffected, err := s.store.Booking(r.Context(), class, date, slot, id.Mail, subject)
+	writeInsertResponse(w, rowsAffected, err)
+}
+
+func (s *server) multiBookingHandler(w http.ResponseWriter, r *http.Request) {
+	class := r.URL.Query().Get("class")
+	date, err := queryDate(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeBadRequest(w, err)
 		return
 	}
-	rowsAffected, err := db.Booking(class, date, slot, faculty, subject)
+	startSlot, err := queryInt(r, "startSlot")
 	if err != nil {
-		log.Println(err)
-		response.Inserted = false
-	} else {
-		if rowsAffected > 0 {
-			response.Inserted = true
-		} else {
-			response.Inserted = false
-		}
+		writeBadRequest(w, err)
+		return
 	}
-	responseJSON, err := json.Marshal(response)
+	endSlot, err := queryInt(r, "endSlot")
 	if err != nil {
-		log.Println("Error marshalling data", err)
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
+		writeBadRequest(w, err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(responseJSON)
-	return
+	// Bookings are always made by and for the logged in user
+	id, _ := identityFrom(r.Context())
+	subject := r.URL.Query().Get("subject")
+	// MultiBooking is all or nothing
+	rowsAffected, err := s.store.MultiBooking(r.Context(), class, date, startSlot, endSlot, id.Mail, subject)
+	writeInsertResponse(w, rowsAffected, err)
 }
 
-func multiBookingHandler(w http.ResponseWriter, r *http.Request) {
-	var response insertResponse
+/*
+cancelBookingHandler is followed as a link from profile.html, so a browser
+asking for a page is sent back there. Other clients get 204 No Content.
+*/
+func (s *server) cancelBookingHandler(w http.ResponseWriter, r *http.Request) {
 	class := r.URL.Query().Get("class")
-	date, err := time.Parse("2006-01-02", r.URL.Query().Get("date"))
+	date, err := queryDate(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeBadRequest(w, err)
 		return
 	}
-	startSlot, err := strconv.Atoi(r.URL.Query().Get("startSlot"))
+	slot, err := queryInt(r, "slot")
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeBadRequest(w, err)
 		return
 	}
-	endSlot, err := strconv.Atoi(r.URL.Query().Get("endSlot"))
+	// The user cancelling must be the one that made the booking unless they
+	// are an admin
+	id, _ := identityFrom(r.Context())
+	err = s.store.CancelBooking(r.Context(), actor(id), class, date, slot)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeDBError(w, err)
 		return
 	}
-	faculty := r.URL.Query().Get("faculty")
-	subject := r.URL.Query().Get("subject")
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if strings.Contains(r.Header.Get("Accept"), "text/html") {
+		http.Redirect(w, r, "/profile.html", http.StatusFound)
 		return
 	}
-	rowsAffected, err := db.MultiBooking(class, date, startSlot, endSlot, faculty, subject)
-	if err != nil {
-		log.Println(err)
-		response.Inserted = false
-	} else {
-		if rowsAffected == int64(endSlot-startSlot+1) {
-			response.Inserted = true
-		} else {
-			response.Inserted = false
-		}
-	}
-	responseJSON, err := json.Marshal(response)
-	if err != nil {
-		log.Println("Error marshalling data", err)
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(responseJSON)
-	return
+	w.WriteHeader(http.StatusNoContent)
 }
 
-func cancelBookingHandler(w http.ResponseWriter, r *http.Request) {
-	class := r.URL.Query().Get("class")
-	date, err := time.Parse("2006-01-02", r.URL.Query().Get("date"))
+// setStaticHandler changes one cell of the weekly timetable
+func (s *server) setStaticHandler(w http.ResponseWriter, r *http.Request) {
+	slot, err := queryInt(r, "slot")
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeBadRequest(w, err)
 		return
 	}
-	slot, err := strconv.Atoi(r.URL.Query().Get("slot"))
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	entry := db.StaticEntry{
+		Class:   r.URL.Query().Get("class"),
+		Day:     r.URL.Query().Get("day"),
+		Slot:    slot,
+		Faculty: r.URL.Query().Get("faculty"),
+		Subject: r.URL.Query().Get("subject"),
 	}
-	err = db.CancelBooking(class, date, slot)
+	id, _ := identityFrom(r.Context())
+	err = s.store.SetStatic(r.Context(), actor(id), entry)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeDBError(w, err)
+		return
 	}
-	http.Redirect(w, r, "/profile.html", http.StatusFound)
-	return
+	w.WriteHeader(http.StatusNoContent)
 }
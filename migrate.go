@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deebakkarthi/coraserver/db"
+)
+
+const migrateUsage = "usage: coraserver migrate up|down|status"
+
+/*
+migrateCommand implements `coraserver migrate up|down|status` against the
+database configured in config.json. up applies every pending migration, down
+reverts the latest one and status lists them all.
+*/
+func migrateCommand(args []string) error {
+	if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+	m, err := db.NewMigrator(config.Database)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := m.Up(ctx)
+		for _, version := range applied {
+			fmt.Println("Applied migration", version)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("Schema is up to date")
+		}
+	case "down":
+		version, err := m.Down(ctx)
+		if err != nil {
+			return err
+		}
+		if version == 0 {
+			fmt.Println("No migration to revert")
+		} else {
+			fmt.Println("Reverted migration", version)
+		}
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, s := range status {
+			applied := "pending"
+			if s.Applied() {
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		w.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+	return nil
+}
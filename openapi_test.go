@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/deebakkarthi/coraserver/db"
+)
+
+// openAPIDoc is the part of openapi.json the tests look at
+type openAPIDoc struct {
+	OpenAPI    string                                 `json:"openapi"`
+	Paths      map[string]map[string]openAPIOperation `json:"paths"`
+	Components struct {
+		Schemas map[string]openAPISchema `json:"schemas"`
+	} `json:"components"`
+}
+
+type openAPIOperation struct {
+	Role       string `json:"x-role"`
+	Parameters []struct {
+		Name string `json:"name"`
+		In   string `json:"in"`
+	} `json:"parameters"`
+	Responses map[string]json.RawMessage `json:"responses"`
+}
+
+type openAPISchema struct {
+	Ref        string                     `json:"$ref"`
+	AllOf      []openAPISchema            `json:"allOf"`
+	Properties map[string]json.RawMessage `json:"properties"`
+	Required   []string                   `json:"required"`
+}
+
+// openAPITypes are the Go types behind the schemas of openapi.json
+var openAPITypes = map[string]interface{}{
+	"BookingRecord":           db.BookingRecord{},
+	"Conflict":                db.Conflict{},
+	"TimetableEntry":          db.TimetableEntry{},
+	"InsertResponse":          insertResponse{},
+	"OAuthExchangeResponse":   oauthExchangeResponse{},
+	"RoomAvailability":        roomAvailabilityV2{},
+	"Day":                     dayV2{},
+	"Calendar":                calendarV2{},
+	"Term":                    termV2{},
+	"Holiday":                 holidayV2{},
+	"DayOrder":                dayOrderV2{},
+	"TimetableVersion":        versionV2{},
+	"TimetableVersionRequest": versionRequestV2{},
+	"TimetableCopyRequest":    copyRequestV2{},
+	"StaticEntryRequest":      staticV2{},
+	"Booking":                 bookingV2{},
+	"BookingRequest":          bookingRequestV2{},
+	"Series":                  seriesV2{},
+	"SeriesRequest":           seriesRequestV2{},
+	"APIToken":                db.APIToken{},
+	"APITokenRequest":         apiTokenRequestV2{},
+	"APITokenResponse":        apiTokenResponse{},
+	"CalendarToken":           calendarTokenV2{},
+	"Error":                   apiError{},
+	"ErrorResponse":           errorResponse{},
+}
+
+func loadOpenAPI(t *testing.T) openAPIDoc {
+	t.Helper()
+	var doc openAPIDoc
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Fatalf("Expected an OpenAPI 3 document, got version %q", doc.OpenAPI)
+	}
+	return doc
+}
+
+var pathParam = regexp.MustCompile(`{(\w+)}`)
+
+// TestOpenAPIRoutes checks that the routes and the document list the same
+// endpoints, with the same roles and path parameters
+func TestOpenAPIRoutes(t *testing.T) {
+	doc := loadOpenAPI(t)
+	s, _ := newV2TestServer(t)
+	router := s.newRouter()
+
+	for _, rt := range append(s.legacyRoutes(), s.v2Routes()...) {
+		op, ok := doc.Paths[rt.Path][strings.ToLower(rt.Method)]
+		if !ok {
+			t.Errorf("%s %s is not documented", rt.Method, rt.Path)
+			continue
+		}
+		if op.Role != rt.Role.String() {
+			t.Errorf("%s %s needs %s, documented as %q", rt.Method, rt.Path, rt.Role, op.Role)
+		}
+		for _, m := range pathParam.FindAllStringSubmatch(rt.Path, -1) {
+			found := false
+			for _, p := range op.Parameters {
+				found = found || p.In == "path" && p.Name == m[1]
+			}
+			if !found {
+				t.Errorf("%s %s does not document the path parameter %s", rt.Method, rt.Path, m[1])
+			}
+		}
+	}
+
+	for path, ops := range doc.Paths {
+		for method, op := range ops {
+			method = strings.ToUpper(method)
+			r := httptest.NewRequest(method, pathParam.ReplaceAllString(path, "x"), nil)
+			_, pattern := router.Handler(r)
+			if pattern != path && pattern != method+" "+path {
+				t.Errorf("%s %s is documented but not served, it matches %q", method, path, pattern)
+			}
+			if len(op.Responses) == 0 {
+				t.Errorf("%s %s documents no responses", method, path)
+			}
+		}
+	}
+}
+
+// jsonFields lists the JSON field names of a struct, the way encoding/json
+// would encode it
+func jsonFields(t reflect.Type) []string {
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if f.Anonymous && name == "" {
+			fields = append(fields, jsonFields(f.Type)...)
+			continue
+		}
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, name)
+	}
+	return fields
+}
+
+// properties collects the properties of a schema, following allOf and $ref
+func (doc openAPIDoc) properties(s openAPISchema) (properties []string, required []string) {
+	if s.Ref != "" {
+		return doc.properties(doc.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")])
+	}
+	for _, part := range s.AllOf {
+		p, r := doc.properties(part)
+		properties = append(properties, p...)
+		required = append(required, r...)
+	}
+	for name := range s.Properties {
+		properties = append(properties, name)
+	}
+	return properties, append(required, s.Required...)
+}
+
+// TestOpenAPISchemas checks every schema against the type it describes
+func TestOpenAPISchemas(t *testing.T) {
+	doc := loadOpenAPI(t)
+	for name, schema := range doc.Components.Schemas {
+		v, ok := openAPITypes[name]
+		if !ok {
+			t.Errorf("Schema %s has no Go type in openAPITypes", name)
+			continue
+		}
+		want := jsonFields(reflect.TypeOf(v))
+		got, required := doc.properties(schema)
+		sort.Strings(want)
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Schema %s has properties %v, %T has %v", name, got, v, want)
+		}
+		for _, r := range required {
+			if !strings.Contains(" "+strings.Join(got, " ")+" ", " "+r+" ") {
+				t.Errorf("Schema %s requires the unknown property %s", name, r)
+			}
+		}
+	}
+	for name := range openAPITypes {
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("%s is not in openapi.json", name)
+		}
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	s, _ := newV2TestServer(t)
+	w := httptest.NewRecorder()
+	s.newRouter().ServeHTTP(w, httptest.NewRequest("GET", "/api/openapi.json", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Expected the document without signing in, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if !json.Valid(w.Body.Bytes()) {
+		t.Errorf("Served document is not valid JSON")
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// defaultRejection is shown to users of an organization that is not allowed
+const defaultRejection = "This app is only for members of Amrita Vishwa Vidyapeetham"
+
+/*
+defaultOrganizations is used when config.json lists none, so that deployments
+predating the organizations setting keep admitting exactly who they used to.
+*/
+var defaultOrganizations = []orgConfig{{
+	ID:   "00f9cda3-075e-44e5-aa0b-aba3add6539f",
+	Name: "Amrita Vishwa Vidyapeetham",
+}}
+
+/*
+orgConfig is an organization allowed to sign in. ID is the Azure AD tenant ID
+and Domains are e-mail domains; a user is admitted if they belong to the
+tenant, when ID is set, and their address is in one of the domains, when any
+are listed. Name replaces the display name Graph reports for the tenant.
+Rejection is shown to members of the tenant whose domain is not allowed.
+*/
+type orgConfig struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Domains   []string `json:"domains"`
+	Rejection string   `json:"rejection"`
+}
+
+// organization is what the identity provider says the user belongs to
+type organization struct {
+	ID   string
+	Name string
+}
+
+// errRejected is returned by admit, its message is meant for the user
+type errRejected struct {
+	message string
+}
+
+func (e *errRejected) Error() string {
+	return e.message
+}
+
+// orgPolicy decides which organizations may sign in
+type orgPolicy struct {
+	allowed   []orgConfig
+	rejection string
+}
+
+/*
+newOrgPolicy checks the organizations of config.json. With Azure AD every
+entry needs its tenant ID: the common endpoints sign in users of any tenant
+and personal accounts, whose addresses no tenant has verified, so a domain on
+its own admits anybody who claims an address in it.
+*/
+func newOrgPolicy(allowed []orgConfig, rejection string, azure bool) (*orgPolicy, error) {
+	if len(allowed) == 0 {
+		allowed = defaultOrganizations
+	}
+	if rejection == "" {
+		rejection = defaultRejection
+	}
+	p := &orgPolicy{rejection: rejection}
+	for _, org := range allowed {
+		if org.ID == "" && len(org.Domains) == 0 {
+			return nil, errors.New("an organization needs an id, domains or both")
+		}
+		if org.ID == "" && azure {
+			return nil, fmt.Errorf("organization %v needs the id of its Azure AD tenant", org.Domains)
+		}
+		domains := make([]string, len(org.Domains))
+		for i, domain := range org.Domains {
+			domains[i] = strings.ToLower(strings.TrimPrefix(domain, "@"))
+		}
+		org.Domains = domains
+		p.allowed = append(p.allowed, org)
+	}
+	return p, nil
+}
+
+/*
+admit checks a user who just signed in, given their address and the
+organizations the identity provider reported for them, which may be none
+(personal accounts). It returns the name of the organization they were
+admitted as.
+*/
+func (p *orgPolicy) admit(mail string, tenants []organization) (string, error) {
+	domain := ""
+	if at := strings.LastIndexByte(mail, '@'); at >= 0 {
+		domain = strings.ToLower(mail[at+1:])
+	}
+	var rejected *errRejected
+	for _, org := range p.allowed {
+		name, member := org.Name, org.ID == ""
+		for _, tenant := range tenants {
+			if org.ID == "" || tenant.ID == org.ID {
+				member = true
+				if name == "" {
+					name = tenant.Name
+				}
+				break
+			}
+		}
+		if !member {
+			continue
+		}
+		if inDomains(domain, org.Domains) {
+			return name, nil
+		}
+		// Remember the first tenant specific message, a later organization
+		// may still admit the user
+		if rejected == nil && org.ID != "" {
+			rejected = &errRejected{org.Rejection}
+			if rejected.message == "" {
+				rejected.message = fmt.Sprintf("%s is not allowed to sign in to this app", mail)
+			}
+		}
+	}
+	if rejected != nil {
+		return "", rejected
+	}
+	return "", &errRejected{p.rejection}
+}
+
+// inDomains reports whether domain is allowed by domains; none means any
+func inDomains(domain string, domains []string) bool {
+	if len(domains) == 0 {
+		return true
+	}
+	for _, d := range domains {
+		if domain == d {
+			return true
+		}
+	}
+	return false
+}
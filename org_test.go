@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestAdmit(t *testing.T) {
+	p, err := newOrgPolicy([]orgConfig{
+		{ID: "tenant-a", Name: "University A", Domains: []string{"@a.edu"}, Rejection: "Use your a.edu account"},
+		{ID: "tenant-b"},
+		{Domains: []string{"guest.edu"}},
+	}, "Members only", false)
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+	tenant := func(id, name string) []organization {
+		return []organization{{ID: id, Name: name}}
+	}
+
+	tests := []struct {
+		name    string
+		mail    string
+		tenants []organization
+		want    string
+		err     string
+	}{
+		{"Configured name", "x@A.edu", tenant("tenant-a", "Graph A"), "University A", ""},
+		{"Wrong domain", "x@alumni.a.edu", tenant("tenant-a", "Graph A"), "", "Use your a.edu account"},
+		{"Graph name", "x@b.edu", tenant("tenant-b", "Graph B"), "Graph B", ""},
+		{"Domain only", "x@guest.edu", nil, "", ""},
+		{"Unknown tenant", "x@c.edu", tenant("tenant-c", "Graph C"), "", "Members only"},
+		{"No organization", "x@c.edu", nil, "", "Members only"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.admit(tt.mail, tt.tenants)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("Expected %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected rejection: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected organization %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := newOrgPolicy([]orgConfig{{Name: "Everyone"}}, "", false); err == nil {
+		t.Error("Organization without id or domains was accepted")
+	}
+	// Azure AD would admit anybody claiming an address in the domain
+	if _, err := newOrgPolicy([]orgConfig{{Domains: []string{"guest.edu"}}}, "", true); err == nil {
+		t.Error("Azure AD organization without a tenant id was accepted")
+	}
+}
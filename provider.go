@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+/*
+claimMapping names the ID token claims that hold the user's name, e-mail
+address and organization. The organization claim is what the organizations
+allow-list in config.json is matched against: the tenant ID (tid) for Azure
+AD, the hosted domain (hd) for Google Workspace, or whatever claim a Keycloak
+mapper adds. Unset fields fall back to the standard OpenID Connect claims.
+*/
+type claimMapping struct {
+	Name         string `json:"name"`
+	Email        string `json:"email"`
+	Organization string `json:"organization"`
+}
+
+// providerConfig is the "provider" section of config.json
+type providerConfig struct {
+	// Issuer is the OpenID Connect issuer URL, its configuration is read
+	// from Issuer/.well-known/openid-configuration. Empty means Azure AD.
+	Issuer string       `json:"issuer"`
+	Claims claimMapping `json:"claims"`
+	// Authority is the Azure AD login host, which differs for the national
+	// clouds, e.g. https://login.microsoftonline.us
+	Authority string `json:"authority"`
+	// AllowUnverifiedEmail accepts addresses the issuer does not mark
+	// email_verified, for issuers that check every address but don't say so
+	AllowUnverifiedEmail bool `json:"allowUnverifiedEmail"`
+}
+
+/*
+provider is the OpenID Connect identity provider users sign in with: where to
+send them, how to check the ID token that comes back and how to read it.
+*/
+type provider struct {
+	endpoint oauth2.Endpoint
+	verifier *idTokenVerifier
+	claims   claimMapping
+	// azure is set for Azure AD, whose logins Microsoft Graph can fill in
+	azure bool
+	// allowUnverifiedEmail is providerConfig.AllowUnverifiedEmail
+	allowUnverifiedEmail bool
+}
+
+// newProvider sets up the provider of cfg for the app clientID
+func newProvider(ctx context.Context, cfg providerConfig, tenant string, clientID string) (*provider, error) {
+	if cfg.Issuer == "" {
+		if cfg.Claims.Email != "" {
+			return nil, errors.New("provider.claims.email can't be set for Azure AD, the user principal name is used")
+		}
+		return azureProvider(cfg.Authority, tenant, clientID, cfg.Claims), nil
+	}
+	p, err := discoverProvider(ctx, cfg.Issuer, clientID, cfg.Claims)
+	if err != nil {
+		return nil, err
+	}
+	p.allowUnverifiedEmail = cfg.AllowUnverifiedEmail
+	return p, nil
+}
+
+/*
+azureProvider is Azure AD, without discovery as its endpoints are known. An
+empty authority means the global cloud.
+*/
+func azureProvider(authority string, tenant string, clientID string, claims claimMapping) *provider {
+	if authority == "" {
+		authority = defaultAuthority
+	}
+	authority = strings.TrimSuffix(authority, "/")
+	if claims.Organization == "" {
+		claims.Organization = "tid"
+	}
+	keys := newRemoteKeySet(authority + "/" + tenant + "/discovery/v2.0/keys")
+	return &provider{
+		endpoint: oauth2.Endpoint{
+			AuthURL:  authority + "/" + tenant + "/oauth2/v2.0/authorize",
+			TokenURL: authority + "/" + tenant + "/oauth2/v2.0/token",
+		},
+		verifier: newIDTokenVerifier(keys, authority+"/{tenantid}/v2.0", clientID),
+		claims:   claims,
+		azure:    true,
+	}
+}
+
+/*
+discoverProvider reads the configuration published by an OpenID Connect
+issuer, such as https://accounts.google.com or a Keycloak realm.
+*/
+func discoverProvider(ctx context.Context, issuer string, clientID string, claims claimMapping) (*provider, error) {
+	wellKnown := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", wellKnown, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("discovering %s: %w", issuer, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("discovering %s: unexpected response status: %s", issuer, resp.Status)
+	}
+	var doc struct {
+		Issuer                string   `json:"issuer"`
+		AuthorizationEndpoint string   `json:"authorization_endpoint"`
+		TokenEndpoint         string   `json:"token_endpoint"`
+		JWKSURI               string   `json:"jwks_uri"`
+		SigningAlgs           []string `json:"id_token_signing_alg_values_supported"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("discovering %s: %w", issuer, err)
+	}
+	// The issuer must vouch for itself, or anybody serving the document
+	// could impersonate it
+	if doc.Issuer != issuer {
+		return nil, fmt.Errorf("discovering %s: document is for issuer %q", issuer, doc.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, fmt.Errorf("discovering %s: incomplete configuration", issuer)
+	}
+	if len(doc.SigningAlgs) > 0 && !contains(doc.SigningAlgs, "RS256") {
+		return nil, fmt.Errorf("discovering %s: ID tokens are not signed with RS256", issuer)
+	}
+	return &provider{
+		endpoint: oauth2.Endpoint{AuthURL: doc.AuthorizationEndpoint, TokenURL: doc.TokenEndpoint},
+		verifier: newIDTokenVerifier(newRemoteKeySet(doc.JWKSURI), doc.Issuer, clientID),
+		claims:   claims,
+	}, nil
+}
+
+/*
+user reads the user's details from the claims of their ID token. The address
+decides the user's role, so it is only taken from a claim the identity
+provider vouches for; err is meant for the user when there is none.
+*/
+func (p *provider) user(c idClaims) (name string, mail string, org string, err error) {
+	name = first(c, p.claims.Name, "given_name", "name")
+	if p.claims.Organization != "" {
+		org = c.get(p.claims.Organization)
+	}
+	if p.azure {
+		mail, err = azureMail(c)
+		return name, mail, org, err
+	}
+	if p.claims.Email != "" {
+		mail = c.get(p.claims.Email)
+	} else if mail = c.get("email"); mail == "" && strings.Contains(c.get("preferred_username"), "@") {
+		mail = c.get("preferred_username")
+	}
+	// Where users register themselves, as they may with Keycloak, anybody
+	// could otherwise sign up with an admin's address
+	if mail != "" && !p.allowUnverifiedEmail && !c.isTrue("email_verified") {
+		return name, "", org, &errRejected{"Your e-mail address is not verified, verify it with your identity provider and sign in again"}
+	}
+	return name, mail, org, nil
+}
+
+/*
+azureMail is the user principal name of an Azure AD user. The email claim is
+not used: the tenant's admins can set it to anything. A UPN on the other hand
+has to end in a domain the tenant proved it owns, but only for members of the
+tenant; guests, whose idp claim names their home tenant, are refused.
+*/
+func azureMail(c idClaims) (string, error) {
+	if idp := c.get("idp"); idp != "" && idp != c.Issuer {
+		return "", &errRejected{"Guest accounts can't sign in, use an account of your own organization"}
+	}
+	if upn := c.get("preferred_username"); strings.Contains(upn, "@") {
+		return upn, nil
+	}
+	return c.get("upn"), nil
+}
+
+// first returns the configured claim if set, otherwise the first fallback
+// that is present
+func first(c idClaims, configured string, fallbacks ...string) string {
+	if configured != "" {
+		return c.get(configured)
+	}
+	for _, claim := range fallbacks {
+		if v := c.get(claim); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// authOptions are added to the login redirect
+func (p *provider) authOptions() []oauth2.AuthCodeOption {
+	if p.azure {
+		// Let users with several Microsoft accounts choose which to use
+		return []oauth2.AuthCodeOption{oauth2.SetAuthURLParam("prompt", "select_account")}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testIssuer serves an OpenID Connect discovery document and key set
+func testIssuer(t *testing.T, claimedIssuer func(url string) string) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"issuer":                                claimedIssuer(srv.URL),
+				"authorization_endpoint":                srv.URL + "/auth",
+				"token_endpoint":                        srv.URL + "/token",
+				"jwks_uri":                              srv.URL + "/keys",
+				"id_token_signing_alg_values_supported": []string{"RS256"},
+			})
+		case "/keys":
+			json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "k1",
+				"n":   base64.RawURLEncoding.EncodeToString(testKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(testKey.E)).Bytes()),
+			}}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestDiscoverProvider(t *testing.T) {
+	issuer := testIssuer(t, func(url string) string { return url })
+	p, err := newProvider(ctx, providerConfig{
+		Issuer: issuer.URL,
+		Claims: claimMapping{Organization: "hd"},
+	}, "", testClientID)
+	if err != nil {
+		t.Fatalf("Discovery failed: %v", err)
+	}
+	if p.endpoint.AuthURL != issuer.URL+"/auth" || p.endpoint.TokenURL != issuer.URL+"/token" {
+		t.Errorf("Unexpected endpoint %+v", p.endpoint)
+	}
+	if p.azure || len(p.authOptions()) != 0 {
+		t.Error("Discovered provider treated as Azure AD")
+	}
+
+	token := signToken(t, testKey, testHeader, map[string]interface{}{
+		"iss":            issuer.URL,
+		"aud":            testClientID,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"name":           "Test Faculty",
+		"email":          "test.faculty@example.edu",
+		"email_verified": true,
+		"hd":             "example.edu",
+	})
+	claims, err := p.verifier.verify(ctx, token)
+	if err != nil {
+		t.Fatalf("Token of discovered issuer rejected: %v", err)
+	}
+	name, mail, org, err := p.user(claims)
+	if err != nil || name != "Test Faculty" || mail != "test.faculty@example.edu" || org != "example.edu" {
+		t.Errorf("Unexpected user %q %q %q, %v", name, mail, org, err)
+	}
+
+	// An address the issuer has not verified is refused, unless the
+	// configuration says the issuer verifies every address
+	unverified := signToken(t, testKey, testHeader, map[string]interface{}{
+		"iss":            issuer.URL,
+		"aud":            testClientID,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"email":          "admin@example.edu",
+		"email_verified": false,
+	})
+	claims, err = p.verifier.verify(ctx, unverified)
+	if err != nil {
+		t.Fatalf("Token of discovered issuer rejected: %v", err)
+	}
+	if _, mail, _, err := p.user(claims); err == nil || mail != "" {
+		t.Errorf("Unverified address %q was accepted", mail)
+	}
+	trusting, err := newProvider(ctx, providerConfig{Issuer: issuer.URL, AllowUnverifiedEmail: true}, "", testClientID)
+	if err != nil {
+		t.Fatalf("Discovery failed: %v", err)
+	}
+	if _, mail, _, err := trusting.user(claims); err != nil || mail != "admin@example.edu" {
+		t.Errorf("Expected the address with allowUnverifiedEmail, got %q, %v", mail, err)
+	}
+
+	// A document claiming to be another issuer is refused
+	impostor := testIssuer(t, func(string) string { return "https://accounts.example.com" })
+	if _, err := newProvider(ctx, providerConfig{Issuer: impostor.URL}, "", testClientID); err == nil {
+		t.Error("Discovery document for another issuer was accepted")
+	}
+}
+
+func TestClaimMapping(t *testing.T) {
+	claims := idClaims{all: map[string]interface{}{
+		"given_name":         "Test",
+		"name":               "Test Faculty",
+		"preferred_username": "test.faculty@a.edu",
+		"tid":                testTenant,
+		"upn":                "tf@a.onmicrosoft.com",
+		"dept":               "CSE",
+		"email_verified":     "true",
+	}}
+
+	azure := azureProvider("", testTenant, testClientID, claimMapping{})
+	name, mail, org, err := azure.user(claims)
+	if err != nil || name != "Test" || mail != "test.faculty@a.edu" || org != testTenant {
+		t.Errorf("Default mapping gave %q %q %q, %v", name, mail, org, err)
+	}
+
+	p := &provider{claims: claimMapping{Name: "name", Email: "upn", Organization: "dept"}}
+	name, mail, org, err = p.user(claims)
+	if err != nil || name != "Test Faculty" || mail != "tf@a.onmicrosoft.com" || org != "CSE" {
+		t.Errorf("Configured mapping gave %q %q %q, %v", name, mail, org, err)
+	}
+
+	// An Azure AD tenant can put any address in the email claim
+	claims.all["email"] = "admin@a.edu"
+	if _, mail, _, _ := azure.user(claims); mail != "test.faculty@a.edu" {
+		t.Errorf("Expected the user principal name, got %q", mail)
+	}
+	// and a guest's UPN is not verified by the tenant either
+	claims.all["idp"] = "https://sts.windows.net/other-tenant/"
+	if _, _, _, err := azure.user(claims); err == nil {
+		t.Error("Guest account was accepted")
+	}
+	delete(claims.all, "idp")
+	delete(claims.all, "email")
+
+	// preferred_username is only an e-mail address when it looks like one
+	claims.all["preferred_username"] = "tfaculty"
+	if _, mail, _, _ := (&provider{}).user(claims); mail != "" {
+		t.Errorf("Expected no e-mail address, got %q", mail)
+	}
+	if _, mail, _, _ := azure.user(claims); mail != "tf@a.onmicrosoft.com" {
+		t.Errorf("Expected the upn claim, got %q", mail)
+	}
+
+	if _, err := newProvider(ctx, providerConfig{Claims: claimMapping{Email: "email"}}, testTenant, testClientID); err == nil {
+		t.Error("Azure AD was configured to trust the email claim")
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/deebakkarthi/coraserver/db"
+)
+
+// requestIDHeader carries the id of a request, in both directions
+const requestIDHeader = "X-Request-ID"
+
+// Error codes, the machine readable part of an apiError
+const (
+	codeBadRequest   = "bad_request"
+	codeUnauthorized = "unauthorized"
+	codeForbidden    = "forbidden"
+	codeNotFound     = "not_found"
+	codeMethod       = "method_not_allowed"
+	codeConflict     = "conflict"
+	codeUnavailable  = "unavailable"
+	codeInternal     = "internal"
+)
+
+/*
+apiError is how every endpoint reports a failure, wrapped in an object under
+"error". Code is stable and meant for programs, Message is meant for people
+and never contains internal details such as database errors. Field names the
+offending parameter or body field of a bad request. RequestID matches the
+X-Request-ID response header and the server log.
+*/
+type apiError struct {
+	Code      string        `json:"code"`
+	Message   string        `json:"message"`
+	Field     string        `json:"field,omitempty"`
+	RequestID string        `json:"request_id,omitempty"`
+	Conflicts []db.Conflict `json:"conflicts,omitempty"`
+}
+
+type errorResponse struct {
+	Error apiError `json:"error"`
+}
+
+// fieldError is a bad request caused by one parameter or body field
+type fieldError struct {
+	field   string
+	message string
+}
+
+func (e *fieldError) Error() string {
+	return e.message
+}
+
+/*
+withRequestID gives every request an id, the client's X-Request-ID if it sent
+a sensible one. The id is echoed in the response header, where writeError
+finds it, and put in the request context for logging.
+*/
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			b := make([]byte, 12)
+			rand.Read(b)
+			id = hex.EncodeToString(b)
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+type requestIDKey struct{}
+
+/*
+withJSONErrors serves requests through router, except those it has no route
+for: ServeMux answers them with a plain text 404 or 405, which are replaced
+by an apiError like every other failure. The Allow header of a 405 is kept.
+*/
+func withJSONErrors(router *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, pattern := router.Handler(r)
+		if pattern != "" {
+			router.ServeHTTP(w, r)
+			return
+		}
+		answer := &muxAnswer{header: http.Header{}}
+		h.ServeHTTP(answer, r)
+		if allow := answer.header.Get("Allow"); allow != "" {
+			w.Header().Set("Allow", allow)
+		}
+		if answer.status == http.StatusMethodNotAllowed {
+			writeError(w, answer.status, apiError{Code: codeMethod, Message: "The method is not allowed here, see the Allow header"})
+			return
+		}
+		writeError(w, http.StatusNotFound, apiError{Code: codeNotFound, Message: "No such endpoint"})
+	})
+}
+
+// muxAnswer keeps the status and headers of ServeMux's own answer and drops
+// its plain text body
+type muxAnswer struct {
+	header http.Header
+	status int
+}
+
+func (a *muxAnswer) Header() http.Header { return a.header }
+
+func (a *muxAnswer) Write(b []byte) (int, error) { return len(b), nil }
+
+func (a *muxAnswer) WriteHeader(status int) { a.status = status }
+
+// requestIDFrom returns the id given to a request by withRequestID
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// writeJSON responds with v encoded as JSON
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	responseJSON, err := json.Marshal(v)
+	if err != nil {
+		log.Println("Error marshalling data", err)
+		writeError(w, http.StatusInternalServerError, apiError{Code: codeInternal, Message: "Internal server error"})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(responseJSON)
+}
+
+// writeError responds with e, filling in the request id
+func writeError(w http.ResponseWriter, status int, e apiError) {
+	e.RequestID = w.Header().Get(requestIDHeader)
+	responseJSON, _ := json.Marshal(errorResponse{e})
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	w.Write(responseJSON)
+}
+
+// writeBadRequest reports err, naming its field if it is a fieldError
+func writeBadRequest(w http.ResponseWriter, err error) {
+	e := apiError{Code: codeBadRequest, Message: err.Error()}
+	var fe *fieldError
+	if errors.As(err, &fe) {
+		e.Field = fe.field
+	}
+	writeError(w, http.StatusBadRequest, e)
+}
+
+/*
+writeDBError responds to a failed Store call with the status code matching the
+kind of failure. The error itself is only logged: what the SQL backends wrap
+comes straight from the driver. Only ErrForbidden and ErrInvalid, which the
+db package raises itself, pass their explanation on. When the request context
+was cancelled the client is no longer listening, so nothing is written.
+*/
+func writeDBError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	e := apiError{Code: codeInternal, Message: "Internal server error"}
+	var conflictErr *db.ConflictError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return
+	case errors.Is(err, db.ErrNotFound):
+		status = http.StatusNotFound
+		e = apiError{Code: codeNotFound, Message: "No such room, slot, subject, faculty, booking or token"}
+	case errors.As(err, &conflictErr):
+		status = http.StatusConflict
+		e = apiError{Code: codeConflict, Message: "Some slots can't be booked", Conflicts: conflictErr.Conflicts}
+	case errors.Is(err, db.ErrConflict):
+		status = http.StatusConflict
+		e = apiError{Code: codeConflict, Message: "This clashes with existing data"}
+	case errors.Is(err, db.ErrForbidden):
+		status = http.StatusForbidden
+		e = apiError{Code: codeForbidden, Message: explain(err, db.ErrForbidden)}
+	case errors.Is(err, db.ErrInvalid):
+		status = http.StatusBadRequest
+		e = apiError{Code: codeBadRequest, Message: explain(err, db.ErrInvalid)}
+	case errors.Is(err, db.ErrUnavailable), errors.Is(err, context.DeadlineExceeded):
+		status = http.StatusServiceUnavailable
+		e = apiError{Code: codeUnavailable, Message: "The database is unavailable, try again later"}
+	}
+	log.Println("Database error", w.Header().Get(requestIDHeader), err)
+	writeError(w, status, e)
+}
+
+// explain strips the sentinel from a db error, "db: forbidden: x" becomes "x"
+func explain(err error, sentinel error) string {
+	return strings.TrimPrefix(err.Error(), sentinel.Error()+": ")
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/deebakkarthi/coraserver/db"
+)
+
+func TestWriteDBError(t *testing.T) {
+	driverErr := errors.New("Error 1045 (28000): Access denied for user 'cora'@'10.0.0.7'")
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"Driver error", driverErr, http.StatusInternalServerError, codeInternal},
+		{"Unavailable", fmt.Errorf("%w: %w", db.ErrUnavailable, driverErr), http.StatusServiceUnavailable, codeUnavailable},
+		{"Not found", fmt.Errorf("%w: no booking of A104", db.ErrNotFound), http.StatusNotFound, codeNotFound},
+		{"Conflict", &db.ConflictError{Conflicts: []db.Conflict{{Slot: 8, Reason: db.ReasonBooked}}}, http.StatusConflict, codeConflict},
+		{"Forbidden", fmt.Errorf("%w: the booking belongs to somebody else", db.ErrForbidden), http.StatusForbidden, codeForbidden},
+		{"Invalid", fmt.Errorf("%w: day %q", db.ErrInvalid, "SUN"), http.StatusBadRequest, codeBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			w.Header().Set(requestIDHeader, "abc")
+			writeDBError(w, tt.err)
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, w.Code)
+			}
+			var response errorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Body is not an error envelope: %v: %s", err, w.Body)
+			}
+			if response.Error.Code != tt.code || response.Error.Message == "" || response.Error.RequestID != "abc" {
+				t.Errorf("Unexpected error %+v", response.Error)
+			}
+			if strings.Contains(w.Body.String(), "Access denied") || strings.Contains(w.Body.String(), "db: ") {
+				t.Errorf("Internal error leaked: %s", w.Body)
+			}
+		})
+	}
+}
+
+func TestWithRequestID(t *testing.T) {
+	h := withRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requestIDFrom(r.Context()) != w.Header().Get(requestIDHeader) {
+			t.Errorf("Request id in context and header differ")
+		}
+		writeBadRequest(w, &fieldError{"date", "date must look like 2006-01-02"})
+	}))
+	tests := []struct {
+		name string
+		sent string
+		keep bool
+	}{
+		{"Client id", "req-42.a_b", true},
+		{"No id", "", false},
+		{"Bad id", "<script>", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.sent != "" {
+				r.Header.Set(requestIDHeader, tt.sent)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			id := w.Header().Get(requestIDHeader)
+			if tt.keep != (id == tt.sent) || id == "" {
+				t.Errorf("Sent request id %q, got %q", tt.sent, id)
+			}
+			var response errorResponse
+			json.Unmarshal(w.Body.Bytes(), &response)
+			want := apiError{Code: codeBadRequest, Message: "date must look like 2006-01-02", Field: "date", RequestID: id}
+			if response.Error.Code != want.Code || response.Error.Field != want.Field || response.Error.RequestID != want.RequestID {
+				t.Errorf("Expected %+v, got %+v", want, response.Error)
+			}
+		})
+	}
+}
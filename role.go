@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+/*
+role is what a signed in user may do. Roles are ordered, each one may do
+everything the roles before it may:
+
+  - students can query availability and timetables
+  - faculty can also book rooms and cancel their own bookings
+  - admins can also change the static timetable and cancel any booking
+*/
+type role int
+
+const (
+	// roleStudent is the zero value so that a token without a role gets
+	// the least privilege
+	roleStudent role = iota
+	roleFaculty
+	roleAdmin
+)
+
+var roleNames = []string{"student", "faculty", "admin"}
+
+func (r role) String() string {
+	if r < 0 || int(r) >= len(roleNames) {
+		return fmt.Sprintf("role(%d)", int(r))
+	}
+	return roleNames[r]
+}
+
+func (r role) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *role) UnmarshalText(text []byte) error {
+	for i, name := range roleNames {
+		if string(text) == name {
+			*r = role(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown role %q", text)
+}
+
+/*
+rolePolicy derives a user's role from their e-mail address at login. Admins
+are listed one by one in config.json; faculty are recognised by patterns such
+as "*@cb.amrita.edu", in the syntax of path.Match. Everybody else who gets
+through the login is a student.
+*/
+type rolePolicy struct {
+	admins  map[string]bool
+	faculty []string
+}
+
+func newRolePolicy(admins []string, faculty []string) (*rolePolicy, error) {
+	p := &rolePolicy{admins: make(map[string]bool)}
+	for _, admin := range admins {
+		p.admins[strings.ToLower(admin)] = true
+	}
+	for _, pattern := range faculty {
+		// Reject malformed patterns now rather than never matching them
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("bad faculty pattern %q: %w", pattern, err)
+		}
+		p.faculty = append(p.faculty, strings.ToLower(pattern))
+	}
+	return p, nil
+}
+
+func (p *rolePolicy) roleOf(mail string) role {
+	mail = strings.ToLower(mail)
+	if p.admins[mail] {
+		return roleAdmin
+	}
+	for _, pattern := range p.faculty {
+		if ok, _ := path.Match(pattern, mail); ok {
+			return roleFaculty
+		}
+	}
+	return roleStudent
+}
+
+/*
+requireRole is requireSession for endpoints that need at least role min. A
+signed in user without the role is rejected with 403 Forbidden.
+*/
+func (m *sessionManager) requireRole(min role, next http.HandlerFunc) http.HandlerFunc {
+	return m.requireSession(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := identityFrom(r.Context())
+		if id.Role < min {
+			writeError(w, http.StatusForbidden, apiError{Code: codeForbidden, Message: fmt.Sprintf("%s access required", min)})
+			return
+		}
+		next(w, r)
+	})
+}
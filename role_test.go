@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoleOf(t *testing.T) {
+	p, err := newRolePolicy([]string{"Admin@cb.amrita.edu"}, []string{"*@cb.amrita.edu"})
+	if err != nil {
+		t.Fatalf("Failed to create role policy: %v", err)
+	}
+	tests := []struct {
+		mail string
+		want role
+	}{
+		{"admin@cb.amrita.edu", roleAdmin},
+		{"n_harini@cb.amrita.edu", roleFaculty},
+		{"N_Harini@CB.Amrita.edu", roleFaculty},
+		{"cb.en.u4cse20001@cb.students.amrita.edu", roleStudent},
+		{"someone@amrita.edu.example.com", roleStudent},
+	}
+	for _, tt := range tests {
+		if got := p.roleOf(tt.mail); got != tt.want {
+			t.Errorf("roleOf(%q) = %s, want %s", tt.mail, got, tt.want)
+		}
+	}
+
+	if _, err := newRolePolicy(nil, []string{"[@cb.amrita.edu"}); err == nil {
+		t.Error("Malformed faculty pattern was accepted")
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	m, _ := newSessionManager("", 0, false)
+	handler := m.requireRole(roleFaculty, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		role   role
+		status int
+	}{
+		{"Student", roleStudent, http.StatusForbidden},
+		{"Faculty", roleFaculty, http.StatusOK},
+		{"Admin", roleAdmin, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, _ := m.issue(identity{Mail: "test@test.com", Role: tt.role})
+			r := httptest.NewRequest("GET", "/db/booking", nil)
+			r.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			handler(w, r)
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, w.Code)
+			}
+		})
+	}
+
+	// Without a session it is still 401, not 403
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/db/booking", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", w.Code)
+	}
+}
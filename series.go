@@ -0,0 +1,214 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/deebakkarthi/coraserver/db"
+)
+
+// seriesRequestV2 is the body of POST /api/v2/series
+type seriesRequestV2 struct {
+	Room  string `json:"room"`
+	Start string `json:"start"`
+	// Every is the number of weeks between two dates, 1 by default
+	Every     int `json:"every"`
+	StartSlot int `json:"startSlot"`
+	// EndSlot defaults to StartSlot
+	EndSlot int `json:"endSlot"`
+	// The series ends on Until or after Count dates
+	Until   string   `json:"until"`
+	Count   int      `json:"count"`
+	Skip    []string `json:"skip"`
+	Subject string   `json:"subject"`
+	// Partial books the dates that are free and reports the others instead
+	// of booking nothing
+	Partial bool `json:"partial"`
+}
+
+// seriesV2 is a recurring booking as /api/v2 shows it
+type seriesV2 struct {
+	ID          int64    `json:"id"`
+	Room        string   `json:"room"`
+	Faculty     string   `json:"faculty"`
+	Subject     string   `json:"subject"`
+	Start       string   `json:"start"`
+	Until       string   `json:"until"`
+	Every       int      `json:"every"`
+	StartSlot   int      `json:"startSlot"`
+	EndSlot     int      `json:"endSlot"`
+	Skip        []string `json:"skip"`
+	Occurrences []string `json:"occurrences"`
+	// Conflicts are the reasons the skipped dates of a partial booking
+	// could not be booked
+	Conflicts []db.Conflict `json:"conflicts,omitempty"`
+}
+
+func formatDates(dates []time.Time) []string {
+	formatted := []string{}
+	for _, date := range dates {
+		formatted = append(formatted, date.Format(dateLayout))
+	}
+	return formatted
+}
+
+func toSeriesV2(s db.Series) seriesV2 {
+	return seriesV2{
+		ID:          s.ID,
+		Room:        s.Class,
+		Faculty:     s.Faculty,
+		Subject:     s.Subject,
+		Start:       s.Start.Format(dateLayout),
+		Until:       s.Until.Format(dateLayout),
+		Every:       s.Interval,
+		StartSlot:   s.StartSlot,
+		EndSlot:     s.EndSlot,
+		Skip:        formatDates(s.Skip),
+		Occurrences: formatDates(s.Occurrences),
+	}
+}
+
+/*
+v2CreateSeries books a room for the signed in user on the same weekday every
+week or every few weeks. Every date is checked like a booking of its own. The
+series is booked as a whole, or with partial set, the dates in the way are
+skipped and listed as conflicts.
+*/
+func (s *server) v2CreateSeries(w http.ResponseWriter, r *http.Request) {
+	var req seriesRequestV2
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	start, err := time.Parse(dateLayout, req.Start)
+	if err != nil {
+		writeBadRequest(w, &fieldError{"start", "start must look like 2006-01-02"})
+		return
+	}
+	var until time.Time
+	if req.Until != "" {
+		if until, err = time.Parse(dateLayout, req.Until); err != nil {
+			writeBadRequest(w, &fieldError{"until", "until must look like 2006-01-02"})
+			return
+		}
+	}
+	var skip []time.Time
+	for _, v := range req.Skip {
+		date, err := time.Parse(dateLayout, v)
+		if err != nil {
+			writeBadRequest(w, &fieldError{"skip", "skip must be a list of dates like 2006-01-02"})
+			return
+		}
+		skip = append(skip, date)
+	}
+	if req.EndSlot == 0 {
+		req.EndSlot = req.StartSlot
+	}
+	switch {
+	case req.Room == "":
+		writeBadRequest(w, &fieldError{"room", "room is required"})
+		return
+	case req.Subject == "":
+		writeBadRequest(w, &fieldError{"subject", "subject is required"})
+		return
+	case req.StartSlot <= 0:
+		writeBadRequest(w, &fieldError{"startSlot", "startSlot is required"})
+		return
+	case req.EndSlot < req.StartSlot:
+		writeBadRequest(w, &fieldError{"endSlot", "endSlot must not be before startSlot"})
+		return
+	case req.Every < 0:
+		writeBadRequest(w, &fieldError{"every", "every must be a number of weeks"})
+		return
+	case req.Count < 0:
+		writeBadRequest(w, &fieldError{"count", "count must not be negative"})
+		return
+	case req.Until == "" && req.Count == 0:
+		writeBadRequest(w, &fieldError{"until", "until or count is required"})
+		return
+	case req.Until != "" && req.Count != 0:
+		writeBadRequest(w, &fieldError{"count", "give until or count, not both"})
+		return
+	}
+	id, _ := identityFrom(r.Context())
+	series, conflicts, err := s.store.BookSeries(r.Context(), db.Series{
+		Class:     req.Room,
+		Faculty:   id.Mail,
+		Subject:   req.Subject,
+		StartSlot: req.StartSlot,
+		EndSlot:   req.EndSlot,
+		Start:     start,
+		Until:     until,
+		Interval:  req.Every,
+		Count:     req.Count,
+		Skip:      skip,
+	}, req.Partial)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	response := toSeriesV2(series)
+	response.Conflicts = conflicts
+	w.Header().Set("Location", "/api/v2/series/"+strconv.FormatInt(series.ID, 10))
+	writeJSON(w, http.StatusCreated, response)
+}
+
+// pathSeriesID reads the {id} of a /api/v2/series path. A malformed id is
+// reported as not found.
+func pathSeriesID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusNotFound, apiError{Code: codeNotFound, Message: "malformed series id", Field: "id"})
+		return 0, false
+	}
+	return id, true
+}
+
+// v2Series shows a series to its faculty member or an admin
+func (s *server) v2Series(w http.ResponseWriter, r *http.Request) {
+	seriesID, ok := pathSeriesID(w, r)
+	if !ok {
+		return
+	}
+	id, _ := identityFrom(r.Context())
+	series, err := s.store.GetSeries(r.Context(), actor(id), seriesID)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toSeriesV2(series))
+}
+
+// v2CancelSeries cancels every booking of a series
+func (s *server) v2CancelSeries(w http.ResponseWriter, r *http.Request) {
+	seriesID, ok := pathSeriesID(w, r)
+	if !ok {
+		return
+	}
+	id, _ := identityFrom(r.Context())
+	if err := s.store.CancelSeries(r.Context(), actor(id), seriesID); err != nil {
+		writeDBError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// v2CancelOccurrence cancels the bookings of a series on one date
+func (s *server) v2CancelOccurrence(w http.ResponseWriter, r *http.Request) {
+	seriesID, ok := pathSeriesID(w, r)
+	if !ok {
+		return
+	}
+	date, err := time.Parse(dateLayout, r.PathValue("date"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, apiError{Code: codeNotFound, Message: "malformed date", Field: "date"})
+		return
+	}
+	id, _ := identityFrom(r.Context())
+	if err := s.store.CancelOccurrence(r.Context(), actor(id), seriesID, date); err != nil {
+		writeDBError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	sessionCookie     = "cora_session"
+	defaultSessionTTL = 12 * time.Hour
+)
+
+var errInvalidSession = errors.New("invalid or expired session")
+
+/*
+identity is who a request was made by, as established by the OAuth login. It
+is the payload of the session token, so keep it small.
+*/
+type identity struct {
+	Name         string `json:"name"`
+	Mail         string `json:"mail"`
+	Organization string `json:"org"`
+	// Role is decided once at login, changes to the configuration apply
+	// from the next login
+	Role role `json:"role"`
+	// Expires is a unix timestamp
+	Expires int64 `json:"exp"`
+}
+
+/*
+sessionManager issues and verifies the session tokens handed out after a
+successful login. A token is the base64 encoded identity followed by an
+HMAC-SHA256 of it, so the server does not need to remember anything to check
+it. The same token is set as a cookie for browsers and returned in the
+exchange response for clients that prefer an Authorization: Bearer header.
+*/
+type sessionManager struct {
+	key    []byte
+	ttl    time.Duration
+	secure bool
+	// apiTokens, when set, lets requests authenticate with an API token
+	// instead of a session (see apitoken.go)
+	apiTokens apiTokenStore
+}
+
+/*
+newSessionManager decodes the base64 key from config.json. Without a key a
+random one is generated, which means every restart logs everybody out.
+*/
+func newSessionManager(key string, ttlSeconds int, secure bool) (*sessionManager, error) {
+	m := &sessionManager{ttl: defaultSessionTTL, secure: secure}
+	if ttlSeconds > 0 {
+		m.ttl = time.Duration(ttlSeconds) * time.Second
+	}
+	if key == "" {
+		log.Println("No sessionKey configured, sessions will not survive a restart")
+		m.key = make([]byte, 32)
+		if _, err := rand.Read(m.key); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) < 32 {
+		return nil, errors.New("sessionKey must be at least 32 bytes")
+	}
+	m.key = decoded
+	return m, nil
+}
+
+func (m *sessionManager) sign(payload string) string {
+	mac := hmac.New(sha256.New, m.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// issue returns a token for id that is valid for the configured lifetime
+func (m *sessionManager) issue(id identity) (string, error) {
+	id.Expires = time.Now().Add(m.ttl).Unix()
+	payloadJSON, err := json.Marshal(id)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(payloadJSON)
+	return payload + "." + m.sign(payload), nil
+}
+
+func (m *sessionManager) verify(token string) (identity, error) {
+	var id identity
+	dot := strings.IndexByte(token, '.')
+	if dot < 0 {
+		return id, errInvalidSession
+	}
+	payload, signature := token[:dot], token[dot+1:]
+	if !hmac.Equal([]byte(signature), []byte(m.sign(payload))) {
+		return id, errInvalidSession
+	}
+	payloadJSON, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return id, errInvalidSession
+	}
+	if err := json.Unmarshal(payloadJSON, &id); err != nil {
+		return id, errInvalidSession
+	}
+	if time.Now().Unix() >= id.Expires || id.Mail == "" {
+		return id, errInvalidSession
+	}
+	return id, nil
+}
+
+// setCookie hands token to the browser
+func (m *sessionManager) setCookie(w http.ResponseWriter, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(m.ttl.Seconds()),
+		HttpOnly: true,
+		Secure:   m.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (m *sessionManager) clearCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   m.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// tokenFrom finds the session token of r, preferring the Authorization header
+func tokenFrom(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+type identityKey struct{}
+
+func withIdentity(ctx context.Context, id identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// identityFrom returns the identity injected by requireSession
+func identityFrom(ctx context.Context) (identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(identity)
+	return id, ok
+}
+
+// authenticate returns who made r, from either a session or an API token
+func (m *sessionManager) authenticate(r *http.Request) (identity, error) {
+	token := tokenFrom(r)
+	if m.apiTokens != nil && strings.HasPrefix(token, apiTokenPrefix) {
+		return m.verifyAPIToken(r.Context(), token)
+	}
+	return m.verify(token)
+}
+
+/*
+requireSession only lets requests with a valid session or API token through
+to next, with the verified identity in the request context. Everything else
+is rejected with 401 Unauthorized.
+*/
+func (m *sessionManager) requireSession(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := m.authenticate(r)
+		if err != nil && !errors.Is(err, errInvalidSession) {
+			// The token could not be looked up
+			writeDBError(w, err)
+			return
+		}
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="coraserver"`)
+			writeError(w, http.StatusUnauthorized, apiError{Code: codeUnauthorized, Message: err.Error()})
+			return
+		}
+		next(w, r.WithContext(withIdentity(r.Context(), id)))
+	}
+}
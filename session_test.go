@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionToken(t *testing.T) {
+	m, err := newSessionManager("", 0, false)
+	if err != nil {
+		t.Fatalf("Failed to create session manager: %v", err)
+	}
+	want := identity{Name: "Test", Mail: "test.faculty@test.com", Organization: "Test University"}
+
+	token, err := m.issue(want)
+	if err != nil {
+		t.Fatalf("Failed to issue token: %v", err)
+	}
+	got, err := m.verify(token)
+	if err != nil {
+		t.Fatalf("Failed to verify token: %v", err)
+	}
+	if got.Mail != want.Mail || got.Name != want.Name || got.Organization != want.Organization {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	// Changing a single character of the payload must break the signature
+	tampered := "A" + token[1:]
+	if token[0] == 'A' {
+		tampered = "B" + token[1:]
+	}
+	if _, err := m.verify(tampered); err == nil {
+		t.Error("Tampered token was accepted")
+	}
+
+	// A token signed with another key is rejected
+	other, _ := newSessionManager("", 0, false)
+	if _, err := other.verify(token); err == nil {
+		t.Error("Token signed with another key was accepted")
+	}
+
+	// An expired token is rejected
+	m.ttl = -time.Minute
+	expired, _ := m.issue(want)
+	if _, err := m.verify(expired); err == nil {
+		t.Error("Expired token was accepted")
+	}
+}
+
+func TestRequireSession(t *testing.T) {
+	m, _ := newSessionManager("", 0, false)
+	token, _ := m.issue(identity{Mail: "test.faculty@test.com"})
+
+	handler := m.requireSession(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := identityFrom(r.Context())
+		if !ok {
+			t.Error("No identity in request context")
+		}
+		w.Write([]byte(id.Mail))
+	})
+
+	tests := []struct {
+		name   string
+		setup  func(r *http.Request)
+		status int
+	}{
+		{"No session", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"Bearer token", func(r *http.Request) {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}, http.StatusOK},
+		{"Cookie", func(r *http.Request) {
+			r.AddCookie(&http.Cookie{Name: sessionCookie, Value: token})
+		}, http.StatusOK},
+		{"Garbage", func(r *http.Request) {
+			r.Header.Set("Authorization", "Bearer garbage")
+		}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/db/getBooking", nil)
+			tt.setup(r)
+			w := httptest.NewRecorder()
+			handler(w, r)
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, w.Code)
+			}
+			if tt.status == http.StatusOK && !strings.Contains(w.Body.String(), "test.faculty@test.com") {
+				t.Errorf("Handler did not see the identity, got %q", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	stateCookie = "cora_oauth_state"
+	// stateTTL bounds how long a user may take to sign in at Azure AD
+	stateTTL = 10 * time.Minute
+)
+
+var (
+	errMissingState  = errors.New("missing OAuth state, start again at /oauth/login")
+	errStateMismatch = errors.New("OAuth state does not match this browser's login")
+)
+
+/*
+loginAttempt is what /oauth/login has to remember until /oauth/exchange: the
+state, which ties the authorization code to the browser that asked for it
+(preventing login CSRF), and the PKCE code verifier, which proves that whoever
+redeems the code is whoever requested it. bearer is set when the client
+asked for the session token in the body of the /oauth/exchange response.
+*/
+type loginAttempt struct {
+	state    string
+	verifier string
+	bearer   bool
+}
+
+// randomToken returns n bytes from crypto/rand, base64url encoded
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+/*
+setLoginCookie stores the attempt in a short lived cookie on the browser. The
+cookie is signed with the session key, so it can't be forged, and is HttpOnly
+so scripts can't read the verifier.
+*/
+func (m *sessionManager) setLoginCookie(w http.ResponseWriter, a loginAttempt) {
+	mode := "cookie"
+	if a.bearer {
+		mode = "bearer"
+	}
+	value := a.state + "." + a.verifier + "." + mode
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookie,
+		Value:    value + "." + m.sign(value),
+		Path:     "/oauth/",
+		MaxAge:   int(stateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   m.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (m *sessionManager) clearLoginCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookie,
+		Value:    "",
+		Path:     "/oauth/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   m.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+/*
+checkLogin returns the login attempt of r's browser if the state parameter of
+r matches it
+*/
+func (m *sessionManager) checkLogin(r *http.Request) (loginAttempt, error) {
+	var a loginAttempt
+	state := r.URL.Query().Get("state")
+	cookie, err := r.Cookie(stateCookie)
+	if state == "" || err != nil {
+		return a, errMissingState
+	}
+	parts := strings.Split(cookie.Value, ".")
+	if len(parts) != 4 {
+		return a, errMissingState
+	}
+	if subtle.ConstantTimeCompare([]byte(parts[3]), []byte(m.sign(strings.Join(parts[:3], ".")))) != 1 {
+		return a, errMissingState
+	}
+	if subtle.ConstantTimeCompare([]byte(state), []byte(parts[0])) != 1 {
+		return a, errStateMismatch
+	}
+	a.state, a.verifier, a.bearer = parts[0], parts[1], parts[2] == "bearer"
+	return a, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+// login runs /oauth/login and returns the state it sent to Azure AD and the
+// cookie it set on the browser
+func login(t *testing.T, s *server, path string) (string, *http.Cookie) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.oauthLoginHandler(w, httptest.NewRequest("GET", path, nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("Expected redirect, got status %d", w.Code)
+	}
+	loc, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("Bad redirect: %v", err)
+	}
+	q := loc.Query()
+	if q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256" {
+		t.Errorf("Redirect has no PKCE challenge: %s", loc)
+	}
+	for _, c := range w.Result().Cookies() {
+		if c.Name == stateCookie {
+			if !c.HttpOnly {
+				t.Error("State cookie is readable by scripts")
+			}
+			return q.Get("state"), c
+		}
+	}
+	t.Fatal("No state cookie set")
+	return "", nil
+}
+
+func TestLoginState(t *testing.T) {
+	m, _ := newSessionManager("", 0, false)
+	s := &server{sessions: m, provider: &provider{}}
+	oauthConfig = &oauth2.Config{
+		ClientID: "client",
+		Endpoint: oauth2.Endpoint{AuthURL: "https://login.example.com/authorize"},
+	}
+	state, cookie := login(t, s, "/oauth/login")
+	if state == "" {
+		t.Fatal("Redirect has no state")
+	}
+	otherState, otherCookie := login(t, s, "/oauth/login")
+	if otherState == state {
+		t.Error("Two logins got the same state")
+	}
+
+	tests := []struct {
+		name   string
+		state  string
+		cookie *http.Cookie
+		err    error
+	}{
+		{"Valid", state, cookie, nil},
+		{"No state", "", cookie, errMissingState},
+		{"No cookie", state, nil, errMissingState},
+		{"Mismatch", state, otherCookie, errStateMismatch},
+		{"Forged cookie", "forged", &http.Cookie{Name: stateCookie, Value: "forged.verifier.sig"}, errMissingState},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/oauth/exchange?code=abc&state="+url.QueryEscape(tt.state), nil)
+			if tt.cookie != nil {
+				r.AddCookie(tt.cookie)
+			}
+			a, err := m.checkLogin(r)
+			if err != tt.err {
+				t.Fatalf("Expected %v, got %v", tt.err, err)
+			}
+			if err == nil && (a.state != state || a.verifier == "") {
+				t.Errorf("Unexpected login attempt %+v", a)
+			}
+			if err != nil {
+				// The exchange must be refused before Azure AD is contacted
+				w := httptest.NewRecorder()
+				s.oauthExchangeHandler(w, r)
+				if w.Code != http.StatusBadRequest {
+					t.Errorf("Expected status 400, got %d", w.Code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,564 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deebakkarthi/coraserver/db"
+)
+
+// maxBodySize bounds the JSON request bodies of /api/v2
+const maxBodySize = 1 << 20
+
+/*
+route is one endpoint of /api/v2. The table in v2Routes is the single place
+they are listed, so that everything derived from it, such as the router,
+stays in step.
+*/
+type route struct {
+	Method string
+	// Path is a net/http pattern, e.g. /api/v2/rooms/{id}
+	Path string
+	// Role is the least role allowed to call the route
+	Role    role
+	Handler http.HandlerFunc
+}
+
+func (s *server) v2Routes() []route {
+	return []route{
+		{"GET", "/api/v2/rooms", roleStudent, s.v2Rooms},
+		{"GET", "/api/v2/rooms/{id}/availability", roleStudent, s.v2RoomAvailability},
+		{"GET", "/api/v2/rooms/{id}/timetable", roleStudent, s.v2RoomTimetable},
+		{"GET", "/api/v2/rooms/{id}/schedule", roleStudent, s.v2RoomSchedule},
+		{"PUT", "/api/v2/rooms/{id}/timetable/{day}/{slot}", roleAdmin, s.v2SetStatic},
+		{"GET", "/api/v2/availability", roleStudent, s.v2Availability},
+		{"GET", "/api/v2/slots", roleStudent, s.v2Slots},
+		{"GET", "/api/v2/timetables", roleAdmin, s.v2TimetableVersions},
+		{"PUT", "/api/v2/timetables/{version}", roleAdmin, s.v2SetTimetableVersion},
+		{"DELETE", "/api/v2/timetables/{version}", roleAdmin, s.v2DeleteTimetableVersion},
+		{"POST", "/api/v2/timetables/{version}/copy", roleAdmin, s.v2CopyTimetableVersion},
+		{"GET", "/api/v2/timetables/{version}/rooms/{id}/schedule", roleAdmin, s.v2PreviewSchedule},
+		{"GET", "/api/v2/calendar", roleStudent, s.v2Calendar},
+		{"PUT", "/api/v2/calendar", roleAdmin, s.v2SetCalendar},
+		{"GET", "/api/v2/subjects", roleStudent, s.v2Subjects},
+		{"GET", "/api/v2/bookings", roleFaculty, s.v2Bookings},
+		{"POST", "/api/v2/bookings", roleFaculty, s.v2CreateBooking},
+		{"GET", "/api/v2/bookings/{id}", roleFaculty, s.v2Booking},
+		{"DELETE", "/api/v2/bookings/{id}", roleFaculty, s.v2CancelBooking},
+		{"POST", "/api/v2/series", roleFaculty, s.v2CreateSeries},
+		{"GET", "/api/v2/series/{id}", roleFaculty, s.v2Series},
+		{"DELETE", "/api/v2/series/{id}", roleFaculty, s.v2CancelSeries},
+		{"DELETE", "/api/v2/series/{id}/occurrences/{date}", roleFaculty, s.v2CancelOccurrence},
+		{"GET", "/api/v2/me/schedule", roleFaculty, s.v2MySchedule},
+		{"POST", "/api/v2/me/calendar", roleFaculty, s.v2CreateCalendarToken},
+		{"GET", "/api/v2/tokens", roleAdmin, s.getAPITokensHandler},
+		{"POST", "/api/v2/tokens", roleAdmin, s.v2CreateAPIToken},
+		{"GET", "/api/v2/tokens/{id}", roleAdmin, s.v2APIToken},
+		{"DELETE", "/api/v2/tokens/{id}", roleAdmin, s.v2RevokeAPIToken},
+	}
+}
+
+// registerV2 adds every route of /api/v2 to router
+func (s *server) registerV2(router *http.ServeMux) {
+	for _, rt := range s.v2Routes() {
+		router.HandleFunc(rt.Method+" "+rt.Path, s.sessions.requireRole(rt.Role, rt.Handler))
+	}
+}
+
+/*
+decodeJSON reads the request body into v, rejecting unknown fields. The error
+names the field at fault where the decoder says which one it is.
+*/
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+	if err == nil {
+		return nil
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return &fieldError{typeErr.Field, fmt.Sprintf("%s must be a %s", typeErr.Field, typeErr.Type)}
+	}
+	// The decoder has no error type for this one
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		field, _ = strconv.Unquote(field)
+		return &fieldError{field, fmt.Sprintf("unknown field %q", field)}
+	}
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return &fieldError{"body", fmt.Sprintf("the body must not exceed %d bytes", maxErr.Limit)}
+	}
+	return &fieldError{"body", "the body must be a JSON object"}
+}
+
+// queryDate reads the date parameter, in the form 2006-01-02
+func queryDate(r *http.Request) (time.Time, error) {
+	date, err := time.Parse(dateLayout, r.URL.Query().Get("date"))
+	if err != nil {
+		return date, &fieldError{"date", "date must look like 2006-01-02"}
+	}
+	return date, nil
+}
+
+// queryInt reads an integer parameter
+func queryInt(r *http.Request, name string) (int, error) {
+	n, err := strconv.Atoi(r.URL.Query().Get(name))
+	if err != nil {
+		return 0, &fieldError{name, name + " must be a number"}
+	}
+	return n, nil
+}
+
+/*
+queryRange reads the from and to parameters. from defaults to the Monday of
+the week of now, to to the Friday of the week of from.
+*/
+func queryRange(r *http.Request, now time.Time) (from time.Time, to time.Time, err error) {
+	from = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from = from.AddDate(0, 0, -(int(from.Weekday())+6)%7)
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = time.Parse(dateLayout, v); err != nil {
+			return from, to, &fieldError{"from", "from must look like 2006-01-02"}
+		}
+	}
+	to = from.AddDate(0, 0, (int(time.Friday)-int(from.Weekday())+7)%7)
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = time.Parse(dateLayout, v); err != nil {
+			return from, to, &fieldError{"to", "to must look like 2006-01-02"}
+		}
+	}
+	if to.Before(from) {
+		return from, to, &fieldError{"to", "to must not be before from"}
+	}
+	return from, to, nil
+}
+
+/*
+bookingV2 is a booking as /api/v2 shows it. Bookings are keyed by room, date
+and slot, ID joins the three so that a booking can be addressed as
+/api/v2/bookings/{id}.
+*/
+type bookingV2 struct {
+	ID      string `json:"id"`
+	Room    string `json:"room"`
+	Date    string `json:"date"`
+	Slot    int    `json:"slot"`
+	Faculty string `json:"faculty"`
+	Subject string `json:"subject"`
+	// Series is the recurring booking the booking belongs to, if any
+	Series int64 `json:"series,omitempty"`
+}
+
+func bookingID(room string, date time.Time, slot int) string {
+	return fmt.Sprintf("%s_%s_%d", room, date.Format(dateLayout), slot)
+}
+
+// parseBookingID splits an ID made by bookingID
+func parseBookingID(id string) (room string, date time.Time, slot int, err error) {
+	parts := strings.Split(id, "_")
+	if len(parts) < 3 {
+		return "", date, 0, errors.New("malformed booking id")
+	}
+	n := len(parts)
+	room = strings.Join(parts[:n-2], "_")
+	if date, err = time.Parse(dateLayout, parts[n-2]); err != nil {
+		return "", date, 0, errors.New("malformed booking id")
+	}
+	if slot, err = strconv.Atoi(parts[n-1]); err != nil {
+		return "", date, 0, errors.New("malformed booking id")
+	}
+	return room, date, slot, nil
+}
+
+func toBookingV2(b db.BookingRecord) bookingV2 {
+	return bookingV2{
+		ID:      bookingID(b.Class, b.Date, b.Slot),
+		Room:    b.Class,
+		Date:    b.Date.Format(dateLayout),
+		Slot:    b.Slot,
+		Faculty: b.Faculty,
+		Subject: b.Subject,
+		Series:  b.Series,
+	}
+}
+
+func (s *server) v2Rooms(w http.ResponseWriter, r *http.Request) {
+	rooms, err := s.store.GetAllClass(r.Context())
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rooms)
+}
+
+// roomAvailabilityV2 is the answer of GET /api/v2/rooms/{id}/availability
+type roomAvailabilityV2 struct {
+	Room      string `json:"room"`
+	Date      string `json:"date"`
+	FreeSlots []int  `json:"freeSlots"`
+}
+
+func (s *server) v2RoomAvailability(w http.ResponseWriter, r *http.Request) {
+	date, err := queryDate(r)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	room := r.PathValue("id")
+	slots, err := s.store.GetFreeSlot(r.Context(), room, date)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	if slots == nil {
+		slots = []int{}
+	}
+	writeJSON(w, http.StatusOK, roomAvailabilityV2{Room: room, Date: date.Format(dateLayout), FreeSlots: slots})
+}
+
+func (s *server) v2RoomTimetable(w http.ResponseWriter, r *http.Request) {
+	date, err := queryDate(r)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	entries, err := s.store.GetTimetableByDay(r.Context(), r.PathValue("id"), date)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	if entries == nil {
+		entries = []db.TimetableEntry{}
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// dayV2 is one date of a timetable range
+type dayV2 struct {
+	Date string `json:"date"`
+	// Day is the day of the week, MON to SUN
+	Day string `json:"day"`
+	// Timetable is the day whose timetable the date follows according to
+	// the academic calendar, empty when there are no classes
+	Timetable string              `json:"timetable"`
+	Entries   []db.TimetableEntry `json:"entries"`
+}
+
+func toDaysV2(days []db.DayTimetable) []dayV2 {
+	response := []dayV2{}
+	for _, d := range days {
+		entries := d.Entries
+		if entries == nil {
+			entries = []db.TimetableEntry{}
+		}
+		response = append(response, dayV2{
+			Date:      d.Date.Format(dateLayout),
+			Day:       strings.ToUpper(d.Date.Weekday().String()[:3]),
+			Timetable: d.Day,
+			Entries:   entries,
+		})
+	}
+	return response
+}
+
+// v2RoomSchedule is the timetable of a room for a range of dates, by default
+// the current week
+func (s *server) v2RoomSchedule(w http.ResponseWriter, r *http.Request) {
+	from, to, err := queryRange(r, time.Now())
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	days, err := s.store.GetTimetableRange(r.Context(), r.PathValue("id"), from, to)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toDaysV2(days))
+}
+
+/*
+v2MySchedule is the signed in faculty member's week, or another range: the
+classes they teach and the rooms they booked
+*/
+func (s *server) v2MySchedule(w http.ResponseWriter, r *http.Request) {
+	from, to, err := queryRange(r, time.Now())
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	id, _ := identityFrom(r.Context())
+	days, err := s.store.GetFacultySchedule(r.Context(), id.Mail, from, to)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toDaysV2(days))
+}
+
+// staticV2 is the body of PUT /api/v2/rooms/{id}/timetable/{day}/{slot}
+type staticV2 struct {
+	Faculty string `json:"faculty"`
+	Subject string `json:"subject"`
+}
+
+func (s *server) v2SetStatic(w http.ResponseWriter, r *http.Request) {
+	slot, err := strconv.Atoi(r.PathValue("slot"))
+	if err != nil {
+		writeBadRequest(w, &fieldError{"slot", "slot must be a number"})
+		return
+	}
+	var body staticV2
+	if err := decodeJSON(w, r, &body); err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	id, _ := identityFrom(r.Context())
+	entry := db.StaticEntry{
+		Class:   r.PathValue("id"),
+		Day:     strings.ToUpper(r.PathValue("day")),
+		Slot:    slot,
+		Faculty: body.Faculty,
+		Subject: body.Subject,
+	}
+	// The version parameter changes a staged timetable instead
+	if version := r.URL.Query().Get("version"); version != "" {
+		err = s.store.SetVersionStatic(r.Context(), actor(id), version, entry)
+	} else {
+		err = s.store.SetStatic(r.Context(), actor(id), entry)
+	}
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+v2Availability lists the rooms free on date during slot, or during every slot
+from startSlot to endSlot.
+*/
+func (s *server) v2Availability(w http.ResponseWriter, r *http.Request) {
+	date, err := queryDate(r)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	var rooms []string
+	if r.URL.Query().Has("slot") {
+		slot, err := queryInt(r, "slot")
+		if err != nil {
+			writeBadRequest(w, err)
+			return
+		}
+		rooms, err = s.store.GetFreeClass(r.Context(), slot, date)
+		if err != nil {
+			writeDBError(w, err)
+			return
+		}
+	} else {
+		startSlot, err := queryInt(r, "startSlot")
+		if err != nil {
+			writeBadRequest(w, err)
+			return
+		}
+		endSlot, err := queryInt(r, "endSlot")
+		if err != nil {
+			writeBadRequest(w, err)
+			return
+		}
+		if endSlot < startSlot {
+			writeBadRequest(w, &fieldError{"endSlot", "endSlot must not be before startSlot"})
+			return
+		}
+		rooms, err = s.store.MultiFreeSlot(r.Context(), startSlot, endSlot, date)
+		if err != nil {
+			writeDBError(w, err)
+			return
+		}
+	}
+	if rooms == nil {
+		rooms = []string{}
+	}
+	writeJSON(w, http.StatusOK, rooms)
+}
+
+func (s *server) v2Slots(w http.ResponseWriter, r *http.Request) {
+	slots, err := s.store.GetAllSlot(r.Context())
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, slots)
+}
+
+func (s *server) v2Subjects(w http.ResponseWriter, r *http.Request) {
+	subjects, err := s.store.GetAllSubject(r.Context())
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, subjects)
+}
+
+// v2Bookings lists the bookings of the signed in user
+func (s *server) v2Bookings(w http.ResponseWriter, r *http.Request) {
+	id, _ := identityFrom(r.Context())
+	records, err := s.store.GetBooking(r.Context(), id.Mail)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	bookings := []bookingV2{}
+	for _, b := range records {
+		bookings = append(bookings, toBookingV2(b))
+	}
+	writeJSON(w, http.StatusOK, bookings)
+}
+
+// bookingRequestV2 is the body of POST /api/v2/bookings
+type bookingRequestV2 struct {
+	Room      string `json:"room"`
+	Date      string `json:"date"`
+	StartSlot int    `json:"startSlot"`
+	// EndSlot defaults to StartSlot
+	EndSlot int    `json:"endSlot"`
+	Subject string `json:"subject"`
+}
+
+/*
+v2CreateBooking books a room for the signed in user, for one slot or a range
+of them. The range is booked as a whole or not at all; 409 Conflict lists the
+slots in the way in the error.
+*/
+func (s *server) v2CreateBooking(w http.ResponseWriter, r *http.Request) {
+	var req bookingRequestV2
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	date, err := time.Parse(dateLayout, req.Date)
+	if err != nil {
+		writeBadRequest(w, &fieldError{"date", "date must look like 2006-01-02"})
+		return
+	}
+	if req.EndSlot == 0 {
+		req.EndSlot = req.StartSlot
+	}
+	switch {
+	case req.Room == "":
+		writeBadRequest(w, &fieldError{"room", "room is required"})
+		return
+	case req.Subject == "":
+		writeBadRequest(w, &fieldError{"subject", "subject is required"})
+		return
+	case req.StartSlot <= 0:
+		writeBadRequest(w, &fieldError{"startSlot", "startSlot is required"})
+		return
+	case req.EndSlot < req.StartSlot:
+		writeBadRequest(w, &fieldError{"endSlot", "endSlot must not be before startSlot"})
+		return
+	}
+	id, _ := identityFrom(r.Context())
+	_, err = s.store.MultiBooking(r.Context(), req.Room, date, req.StartSlot, req.EndSlot, id.Mail, req.Subject)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	var created []bookingV2
+	for slot := req.StartSlot; slot <= req.EndSlot; slot++ {
+		created = append(created, toBookingV2(db.BookingRecord{
+			Class: req.Room, Date: date, Slot: slot, Faculty: id.Mail, Subject: req.Subject,
+		}))
+	}
+	w.Header().Set("Location", "/api/v2/bookings/"+created[0].ID)
+	writeJSON(w, http.StatusCreated, created)
+}
+
+// v2Booking is one of the bookings v2Bookings lists, the one POST
+// /api/v2/bookings points to
+func (s *server) v2Booking(w http.ResponseWriter, r *http.Request) {
+	id, _ := identityFrom(r.Context())
+	records, err := s.store.GetBooking(r.Context(), id.Mail)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	for _, b := range records {
+		if booking := toBookingV2(b); booking.ID == r.PathValue("id") {
+			writeJSON(w, http.StatusOK, booking)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, apiError{Code: codeNotFound, Message: "No such booking of yours", Field: "id"})
+}
+
+func (s *server) v2CancelBooking(w http.ResponseWriter, r *http.Request) {
+	room, date, slot, err := parseBookingID(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, apiError{Code: codeNotFound, Message: err.Error(), Field: "id"})
+		return
+	}
+	id, _ := identityFrom(r.Context())
+	if err := s.store.CancelBooking(r.Context(), actor(id), room, date, slot); err != nil {
+		writeDBError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apiTokenRequestV2 is the body of POST /api/v2/tokens
+type apiTokenRequestV2 struct {
+	Name    string `json:"name"`
+	Scope   string `json:"scope"`
+	Faculty string `json:"faculty"`
+}
+
+func (s *server) v2CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	var req apiTokenRequestV2
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	id, _ := identityFrom(r.Context())
+	response, err := s.mintAPIToken(r, id, req.Name, req.Scope, req.Faculty)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/api/v2/tokens/"+response.ID)
+	writeJSON(w, http.StatusCreated, response)
+}
+
+// v2APIToken is one of the tokens GET /api/v2/tokens lists, without its
+// secret
+func (s *server) v2APIToken(w http.ResponseWriter, r *http.Request) {
+	id, _ := identityFrom(r.Context())
+	tokens, err := s.store.GetAPITokens(r.Context(), actor(id))
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	for _, t := range tokens {
+		if t.ID == r.PathValue("id") {
+			writeJSON(w, http.StatusOK, t)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, apiError{Code: codeNotFound, Message: "No such API token", Field: "id"})
+}
+
+func (s *server) v2RevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	id, _ := identityFrom(r.Context())
+	if err := s.store.RevokeAPIToken(r.Context(), actor(id), r.PathValue("id")); err != nil {
+		writeDBError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
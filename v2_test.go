@@ -0,0 +1,379 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deebakkarthi/coraserver/db"
+)
+
+// newV2TestServer serves every endpoint from the sample data
+func newV2TestServer(t *testing.T) (*server, http.Handler) {
+	t.Helper()
+	store, err := db.Open(db.Config{Driver: db.DriverMemory, Fixture: filepath.Join("db", "scripts", "fixture.json")})
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	m, _ := newSessionManager("", 0, false)
+	m.apiTokens = store
+	s := &server{store: store, sessions: m}
+	return s, withJSONErrors(s.newRouter())
+}
+
+// do sends a request as the user with the given mail and role
+func do(t *testing.T, s *server, h http.Handler, method string, path string, body string, mail string, r role) *httptest.ResponseRecorder {
+	t.Helper()
+	token, _ := s.sessions.issue(identity{Mail: mail, Role: r})
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestV2(t *testing.T) {
+	s, h := newV2TestServer(t)
+	const (
+		faculty = "a_arun@cb.amrita.edu"
+		other   = "pn_kumar@cb.amrita.edu"
+		student = "cb.en.u4cse20613@cb.students.amrita.edu"
+	)
+	booking := `{"room": "A104", "date": "2023-06-12", "startSlot": 8, "subject": "19CSE311"}`
+	calendar := `{"terms": [{"name": "2023 even", "start": "2023-06-01", "end": "2023-06-30"}],
+		"holidays": [{"name": "Holiday", "start": "2023-06-14"}], "dayOrders": [{"date": "2023-06-17", "day": "mon"}]}`
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		mail   string
+		role   role
+		status int
+		want   string
+	}{
+		{"Rooms", "GET", "/api/v2/rooms", "", student, roleStudent, http.StatusOK, `"A104"`},
+		{"Availability", "GET", "/api/v2/rooms/A104/availability?date=2023-06-12", "", student, roleStudent, http.StatusOK, `"freeSlots":[5,8]`},
+		{"Bad date", "GET", "/api/v2/rooms/A104/availability?date=12-06-2023", "", student, roleStudent, http.StatusBadRequest, `"field":"date"`},
+		{"Free rooms", "GET", "/api/v2/availability?date=2023-06-12&startSlot=8&endSlot=8", "", student, roleStudent, http.StatusOK, `"A104"`},
+		{"Student may not book", "POST", "/api/v2/bookings", booking, student, roleStudent, http.StatusForbidden, ""},
+		{"Book", "POST", "/api/v2/bookings", booking, faculty, roleFaculty, http.StatusCreated, `"id":"A104_2023-06-12_8"`},
+		{"Booking", "GET", "/api/v2/bookings/A104_2023-06-12_8", "", faculty, roleFaculty, http.StatusOK, `"id":"A104_2023-06-12_8","room":"A104","date":"2023-06-12","slot":8`},
+		{"Somebody else's booking", "GET", "/api/v2/bookings/A104_2023-06-12_8", "", other, roleFaculty, http.StatusNotFound, `"code":"not_found"`},
+		{"Booked", "GET", "/api/v2/rooms/A104/availability?date=2023-06-12", "", student, roleStudent, http.StatusOK, `"freeSlots":[5]`},
+		{"Double booking", "POST", "/api/v2/bookings", booking, other, roleFaculty, http.StatusConflict, `"reason":"booked"`},
+		{"Malformed body", "POST", "/api/v2/bookings", `{"room": `, faculty, roleFaculty, http.StatusBadRequest, `"field":"body"`},
+		{"Unknown field", "POST", "/api/v2/bookings", `{"class": "A104"}`, faculty, roleFaculty, http.StatusBadRequest, `"field":"class"`},
+		{"Missing subject", "POST", "/api/v2/bookings", `{"room": "A104", "date": "2023-06-12", "startSlot": 5}`, faculty, roleFaculty, http.StatusBadRequest, `"field":"subject"`},
+		{"My bookings", "GET", "/api/v2/bookings", "", faculty, roleFaculty, http.StatusOK, `"slot":8`},
+		{"Cancel somebody else's", "DELETE", "/api/v2/bookings/A104_2023-06-12_8", "", other, roleFaculty, http.StatusForbidden, ""},
+		{"Cancel", "DELETE", "/api/v2/bookings/A104_2023-06-12_8", "", faculty, roleFaculty, http.StatusNoContent, ""},
+		{"Cancel again", "DELETE", "/api/v2/bookings/A104_2023-06-12_8", "", faculty, roleFaculty, http.StatusNotFound, ""},
+		{"Bad booking id", "DELETE", "/api/v2/bookings/A104", "", faculty, roleFaculty, http.StatusNotFound, ""},
+		{"Wrong method", "PUT", "/api/v2/bookings", booking, faculty, roleFaculty, http.StatusMethodNotAllowed, `{"error":{"code":"method_not_allowed"`},
+		{"Unknown endpoint", "GET", "/api/v2/nosuch", "", faculty, roleFaculty, http.StatusNotFound, `{"error":{"code":"not_found"`},
+		{"Timetable needs admin", "PUT", "/api/v2/rooms/A104/timetable/mon/5", `{"faculty": "FREE", "subject": "FREE"}`, faculty, roleFaculty, http.StatusForbidden, ""},
+		{"Timetable", "PUT", "/api/v2/rooms/A104/timetable/mon/5", `{"faculty": "` + faculty + `", "subject": "19CSE311"}`, "admin@cb.amrita.edu", roleAdmin, http.StatusNoContent, ""},
+		{"Legacy timetable change by link", "GET", "/db/setStatic?class=A104&day=MON&slot=5&faculty=FREE&subject=FREE", "", "admin@cb.amrita.edu", roleAdmin, http.StatusMethodNotAllowed, ""},
+		{"Legacy timetable change", "POST", "/db/setStatic?class=A104&day=MON&slot=8&faculty=FREE&subject=FREE", "", "admin@cb.amrita.edu", roleAdmin, http.StatusNoContent, ""},
+		{"Timetable changed", "GET", "/api/v2/rooms/A104/availability?date=2023-06-12", "", student, roleStudent, http.StatusOK, `"freeSlots":[8]`},
+		{"Room timetable", "GET", "/api/v2/rooms/A104/timetable?date=2023-06-12", "", student, roleStudent, http.StatusOK, `"slot":1,"start":"08:50","end":"09:40"`},
+		{"Room schedule", "GET", "/api/v2/rooms/A104/schedule?from=2023-06-12&to=2023-06-13", "", student, roleStudent, http.StatusOK, `"date":"2023-06-13","day":"TUE","timetable":"TUE","entries":[{"room":"A104","slot":1`},
+		{"Schedule too long", "GET", "/api/v2/rooms/A104/schedule?from=2023-06-12&to=2024-06-12", "", student, roleStudent, http.StatusBadRequest, `"code":"bad_request"`},
+		{"My schedule", "GET", "/api/v2/me/schedule?from=2023-06-12&to=2023-06-12", "", "d_bharathi@cb.amrita.edu", roleFaculty, http.StatusOK, `"room":"A104","slot":3`},
+		{"Students have no schedule", "GET", "/api/v2/me/schedule", "", student, roleStudent, http.StatusForbidden, ""},
+		{"Calendar needs admin", "PUT", "/api/v2/calendar", calendar, faculty, roleFaculty, http.StatusForbidden, ""},
+		{"Bad calendar date", "PUT", "/api/v2/calendar", `{"holidays": [{"name": "Holiday", "start": "12-06-2023"}]}`, "admin@cb.amrita.edu", roleAdmin, http.StatusBadRequest, `"field":"holidays[0].start"`},
+		{"Bad day order", "PUT", "/api/v2/calendar", `{"dayOrders": [{"date": "2023-06-17", "day": "sat"}]}`, "admin@cb.amrita.edu", roleAdmin, http.StatusBadRequest, `"code":"bad_request"`},
+		{"Set calendar", "PUT", "/api/v2/calendar", calendar, "admin@cb.amrita.edu", roleAdmin, http.StatusNoContent, ""},
+		{"Calendar", "GET", "/api/v2/calendar", "", student, roleStudent, http.StatusOK, `"holidays":[{"name":"Holiday","start":"2023-06-14","end":"2023-06-14"}]`},
+		{"Holiday", "GET", "/api/v2/rooms/A104/availability?date=2023-06-14", "", student, roleStudent, http.StatusOK, `"freeSlots":[]`},
+		{"Book on a holiday", "POST", "/api/v2/bookings", `{"room": "A104", "date": "2023-06-14", "startSlot": 8, "subject": "19CSE311"}`, faculty, roleFaculty, http.StatusConflict, `"reason":"closed"`},
+		{"Legacy booking on a holiday", "GET", "/db/booking?class=A104&date=2023-06-14&slot=8&subject=19CSE311", "", faculty, roleFaculty, http.StatusOK, `"inserted":false,"conflicts":[{"class":"A104","date":"2023-06-14T00:00:00Z","slot":8,"reason":"closed"}]`},
+		{"Day order", "GET", "/api/v2/rooms/A104/schedule?from=2023-06-17&to=2023-06-17", "", student, roleStudent, http.StatusOK, `"day":"SAT","timetable":"MON"`},
+		{"Legacy route", "GET", "/db/getAllClass", "", student, roleStudent, http.StatusOK, `"A104"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(t, s, h, tt.method, tt.path, tt.body, tt.mail, tt.role)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("Expected %s in %s", tt.want, w.Body)
+			}
+		})
+	}
+}
+
+func TestV2Calendar(t *testing.T) {
+	s, h := newV2TestServer(t)
+	const (
+		admin   = "admin@cb.amrita.edu"
+		faculty = "a_arun@cb.amrita.edu"
+		student = "cb.en.u4cse20613@cb.students.amrita.edu"
+	)
+	// Two terms with a break between them, a week of exams and a Saturday
+	// following Friday's timetable
+	calendar := `{"terms": [{"name": "2023 even", "start": "2023-01-02", "end": "2023-05-12"},
+			{"name": "2023 odd", "start": "2023-06-05", "end": "2023-11-24"}],
+		"holidays": [{"name": "Exams", "start": "2023-06-19", "end": "2023-06-23"}],
+		"dayOrders": [{"date": "2023-06-17", "day": "fri"}]}`
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		mail   string
+		role   role
+		status int
+		want   string
+	}{
+		{"Empty calendar", "GET", "/api/v2/calendar", "", student, roleStudent, http.StatusOK, `{"terms":[],"holidays":[],"dayOrders":[]}`},
+		{"Students may not set it", "PUT", "/api/v2/calendar", calendar, student, roleStudent, http.StatusForbidden, ""},
+		{"Faculty may not set it", "PUT", "/api/v2/calendar", calendar, faculty, roleFaculty, http.StatusForbidden, ""},
+		{"Bad term date", "PUT", "/api/v2/calendar", `{"terms": [{"name": "2023 odd", "start": "2023-06-05", "end": "24-11-2023"}]}`, admin, roleAdmin, http.StatusBadRequest, `"field":"terms[0].end"`},
+		{"Term ends before it starts", "PUT", "/api/v2/calendar", `{"terms": [{"name": "2023 odd", "start": "2023-06-05", "end": "2023-01-01"}]}`, admin, roleAdmin, http.StatusBadRequest, `"code":"bad_request"`},
+		{"Holiday without a name", "PUT", "/api/v2/calendar", `{"holidays": [{"start": "2023-06-19"}]}`, admin, roleAdmin, http.StatusBadRequest, `"code":"bad_request"`},
+		{"Bad day order date", "PUT", "/api/v2/calendar", `{"dayOrders": [{"date": "17-06-2023", "day": "fri"}]}`, admin, roleAdmin, http.StatusBadRequest, `"field":"dayOrders[0].date"`},
+		{"Unknown field", "PUT", "/api/v2/calendar", `{"semesters": []}`, admin, roleAdmin, http.StatusBadRequest, `"field":"semesters"`},
+		{"Set calendar", "PUT", "/api/v2/calendar", calendar, admin, roleAdmin, http.StatusNoContent, ""},
+		{"Terms", "GET", "/api/v2/calendar", "", student, roleStudent, http.StatusOK, `"terms":[{"name":"2023 even","start":"2023-01-02","end":"2023-05-12"},{"name":"2023 odd","start":"2023-06-05","end":"2023-11-24"}]`},
+		{"Holidays", "GET", "/api/v2/calendar", "", student, roleStudent, http.StatusOK, `"holidays":[{"name":"Exams","start":"2023-06-19","end":"2023-06-23"}]`},
+		{"Day orders", "GET", "/api/v2/calendar", "", student, roleStudent, http.StatusOK, `"dayOrders":[{"date":"2023-06-17","day":"FRI"}]`},
+		{"In term", "GET", "/api/v2/rooms/A104/availability?date=2023-06-12", "", student, roleStudent, http.StatusOK, `"freeSlots":[5,8]`},
+		{"Between terms", "GET", "/api/v2/rooms/A104/availability?date=2023-05-29", "", student, roleStudent, http.StatusOK, `"freeSlots":[]`},
+		{"During the exams", "GET", "/api/v2/availability?date=2023-06-21&slot=8", "", student, roleStudent, http.StatusOK, `[]`},
+		{"Book between terms", "POST", "/api/v2/bookings", `{"room": "A104", "date": "2023-05-29", "startSlot": 8, "subject": "19CSE311"}`, faculty, roleFaculty, http.StatusConflict, `"reason":"closed"`},
+		{"Closed day in a schedule", "GET", "/api/v2/rooms/A104/schedule?from=2023-06-19&to=2023-06-19", "", student, roleStudent, http.StatusOK, `"day":"MON","timetable":"","entries":[]`},
+		{"Saturday following Friday", "GET", "/api/v2/rooms/A104/schedule?from=2023-06-17&to=2023-06-17", "", student, roleStudent, http.StatusOK, `"day":"SAT","timetable":"FRI"`},
+		{"Clear calendar", "PUT", "/api/v2/calendar", `{}`, admin, roleAdmin, http.StatusNoContent, ""},
+		{"Every weekday again", "GET", "/api/v2/rooms/A104/availability?date=2023-05-29", "", student, roleStudent, http.StatusOK, `"freeSlots":[5,8]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(t, s, h, tt.method, tt.path, tt.body, tt.mail, tt.role)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("Expected %s in %s", tt.want, w.Body)
+			}
+		})
+	}
+}
+
+func TestV2TimetableVersions(t *testing.T) {
+	s, h := newV2TestServer(t)
+	const (
+		admin   = "admin@cb.amrita.edu"
+		faculty = "a_arun@cb.amrita.edu"
+		other   = "pn_kumar@cb.amrita.edu"
+		student = "cb.en.u4cse20613@cb.students.amrita.edu"
+	)
+	// The odd semester takes over from June 19th with a class in A104 during
+	// slot 8 on Mondays, which the default timetable leaves free
+	class := `{"faculty": "` + other + `", "subject": "19CSE311"}`
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		mail   string
+		role   role
+		status int
+		want   string
+	}{
+		{"Students may not list versions", "GET", "/api/v2/timetables", "", student, roleStudent, http.StatusForbidden, ""},
+		{"Faculty may not list versions", "GET", "/api/v2/timetables", "", faculty, roleFaculty, http.StatusForbidden, ""},
+		{"Faculty may not stage", "PUT", "/api/v2/timetables/odd", `{"start": "2023-06-19"}`, faculty, roleFaculty, http.StatusForbidden, ""},
+		{"Bad version date", "PUT", "/api/v2/timetables/odd", `{"end": "30-06-2023"}`, admin, roleAdmin, http.StatusBadRequest, `"field":"end"`},
+		{"Version ends before it starts", "PUT", "/api/v2/timetables/odd", `{"start": "2023-06-19", "end": "2023-06-01"}`, admin, roleAdmin, http.StatusBadRequest, `"code":"bad_request"`},
+		{"Stage version", "PUT", "/api/v2/timetables/odd", `{"start": "2023-06-19"}`, admin, roleAdmin, http.StatusNoContent, ""},
+		{"Faculty may not copy", "POST", "/api/v2/timetables/odd/copy", `{"from": "default"}`, faculty, roleFaculty, http.StatusForbidden, ""},
+		{"Copy needs from", "POST", "/api/v2/timetables/odd/copy", `{}`, admin, roleAdmin, http.StatusBadRequest, `"field":"from"`},
+		{"Copy from unknown version", "POST", "/api/v2/timetables/odd/copy", `{"from": "even"}`, admin, roleAdmin, http.StatusNotFound, ""},
+		{"Copy version", "POST", "/api/v2/timetables/odd/copy", `{"from": "default"}`, admin, roleAdmin, http.StatusNoContent, ""},
+		{"Faculty may not change a version", "PUT", "/api/v2/rooms/A104/timetable/mon/8?version=odd", class, faculty, roleFaculty, http.StatusForbidden, ""},
+		{"Staged timetable", "PUT", "/api/v2/rooms/A104/timetable/mon/8?version=odd", class, admin, roleAdmin, http.StatusNoContent, ""},
+		{"Unknown version", "PUT", "/api/v2/rooms/A104/timetable/mon/8?version=even", `{"faculty": "FREE", "subject": "FREE"}`, admin, roleAdmin, http.StatusNotFound, ""},
+		{"Versions", "GET", "/api/v2/timetables", "", admin, roleAdmin, http.StatusOK, `[{"name":"default","start":"","end":"","published":true},{"name":"odd","start":"2023-06-19","end":"","published":false}]`},
+		{"Staged is not in force", "GET", "/api/v2/rooms/A104/availability?date=2023-06-19", "", student, roleStudent, http.StatusOK, `"freeSlots":[5,8]`},
+		{"Faculty may not preview", "GET", "/api/v2/timetables/odd/rooms/A104/schedule?from=2023-06-19&to=2023-06-19", "", faculty, roleFaculty, http.StatusForbidden, ""},
+		{"Preview", "GET", "/api/v2/timetables/odd/rooms/A104/schedule?from=2023-06-19&to=2023-06-19", "", admin, roleAdmin, http.StatusOK, `"room":"A104","slot":8,"start":"16:10","end":"17:00","subject":"19CSE311","subjectName":"Computer Security","faculty":"` + other + `"`},
+		{"Preview before the version starts", "GET", "/api/v2/timetables/odd/rooms/A104/schedule?from=2023-06-12&to=2023-06-12", "", admin, roleAdmin, http.StatusOK, `"faculty":"` + other + `"`},
+		{"Preview unknown version", "GET", "/api/v2/timetables/even/rooms/A104/schedule?from=2023-06-19&to=2023-06-19", "", admin, roleAdmin, http.StatusNotFound, ""},
+		{"Preview bad range", "GET", "/api/v2/timetables/odd/rooms/A104/schedule?from=2023-06-19&to=2024-06-19", "", admin, roleAdmin, http.StatusBadRequest, `"code":"bad_request"`},
+		{"Faculty may not publish", "PUT", "/api/v2/timetables/odd", `{"start": "2023-06-19", "published": true}`, faculty, roleFaculty, http.StatusForbidden, ""},
+		{"Publish version", "PUT", "/api/v2/timetables/odd", `{"start": "2023-06-19", "published": true}`, admin, roleAdmin, http.StatusNoContent, ""},
+		{"Version in force", "GET", "/api/v2/rooms/A104/availability?date=2023-06-19", "", student, roleStudent, http.StatusOK, `"freeSlots":[5]`},
+		{"Version not yet in force", "GET", "/api/v2/rooms/A104/availability?date=2023-06-12", "", student, roleStudent, http.StatusOK, `"freeSlots":[5,8]`},
+		{"Stage overlapping version", "PUT", "/api/v2/timetables/even", `{"start": "2023-07-01"}`, admin, roleAdmin, http.StatusNoContent, ""},
+		{"Publish over a live version", "PUT", "/api/v2/timetables/even", `{"start": "2023-07-01", "published": true}`, admin, roleAdmin, http.StatusConflict, `"code":"conflict"`},
+		{"Overlap not published", "GET", "/api/v2/timetables", "", admin, roleAdmin, http.StatusOK, `{"name":"even","start":"2023-07-01","end":"","published":false}`},
+		{"Publish before the live version", "PUT", "/api/v2/timetables/even", `{"start": "2023-06-01", "end": "2023-06-18", "published": true}`, admin, roleAdmin, http.StatusNoContent, ""},
+		{"Faculty may not delete", "DELETE", "/api/v2/timetables/even", "", faculty, roleFaculty, http.StatusForbidden, ""},
+		{"Delete published version", "DELETE", "/api/v2/timetables/odd", "", admin, roleAdmin, http.StatusBadRequest, `"code":"bad_request"`},
+		{"Unpublish version", "PUT", "/api/v2/timetables/odd", `{"start": "2023-06-19"}`, admin, roleAdmin, http.StatusNoContent, ""},
+		{"Delete staged version", "DELETE", "/api/v2/timetables/odd", "", admin, roleAdmin, http.StatusNoContent, ""},
+		{"Delete again", "DELETE", "/api/v2/timetables/odd", "", admin, roleAdmin, http.StatusNotFound, ""},
+		{"Default timetable again", "GET", "/api/v2/rooms/A104/availability?date=2023-06-19", "", student, roleStudent, http.StatusOK, `"freeSlots":[5,8]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(t, s, h, tt.method, tt.path, tt.body, tt.mail, tt.role)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("Expected %s in %s", tt.want, w.Body)
+			}
+		})
+	}
+}
+
+func TestV2Tokens(t *testing.T) {
+	s, h := newV2TestServer(t)
+	admin := "admin@cb.amrita.edu"
+	w := do(t, s, h, "POST", "/api/v2/tokens", `{"name": "Kiosk", "scope": "read"}`, admin, roleAdmin)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Failed to create token: %d %s", w.Code, w.Body)
+	}
+	var token apiTokenResponse
+	json.Unmarshal(w.Body.Bytes(), &token)
+	location := w.Header().Get("Location")
+	if w := do(t, s, h, "GET", location, "", admin, roleAdmin); w.Code != http.StatusOK ||
+		!strings.Contains(w.Body.String(), `"name":"Kiosk"`) || strings.Contains(w.Body.String(), token.Token) {
+		t.Errorf("Expected the token without its secret at %s, got %d %s", location, w.Code, w.Body)
+	}
+
+	req := httptest.NewRequest("GET", "/api/v2/rooms", nil)
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("API token rejected: %d", w.Code)
+	}
+
+	if w := do(t, s, h, "DELETE", "/api/v2/tokens/"+token.ID, "", admin, roleAdmin); w.Code != http.StatusNoContent {
+		t.Errorf("Failed to revoke token: %d %s", w.Code, w.Body)
+	}
+	if w := do(t, s, h, "DELETE", "/api/v2/tokens/"+token.ID, "", admin, roleAdmin); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 revoking twice, got %d", w.Code)
+	}
+	if w := do(t, s, h, "GET", location, "", admin, roleAdmin); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a revoked token, got %d", w.Code)
+	}
+
+	// The legacy endpoints can't be reached by a link from another site
+	if w := do(t, s, h, "GET", "/db/createAPIToken?name=Kiosk&scope=admin", "", admin, roleAdmin); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 creating a token with GET, got %d", w.Code)
+	}
+	w = do(t, s, h, "POST", "/db/createAPIToken?name=Kiosk&scope=read", "", admin, roleAdmin)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Failed to create token: %d %s", w.Code, w.Body)
+	}
+	json.Unmarshal(w.Body.Bytes(), &token)
+	if w := do(t, s, h, "GET", "/db/revokeAPIToken?id="+token.ID, "", admin, roleAdmin); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 revoking a token with GET, got %d", w.Code)
+	}
+	if w := do(t, s, h, "DELETE", "/db/revokeAPIToken?id="+token.ID, "", admin, roleAdmin); w.Code != http.StatusNoContent {
+		t.Errorf("Failed to revoke token: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestQueryRange(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		now   string
+		from  string
+		to    string
+	}{
+		{"This week", "", "2023-06-14", "2023-06-12", "2023-06-16"},
+		{"Sunday", "", "2023-06-18", "2023-06-12", "2023-06-16"},
+		{"Week of from", "?from=2023-06-19", "2023-06-14", "2023-06-19", "2023-06-23"},
+		{"Range", "?from=2023-06-01&to=2023-06-30", "2023-06-14", "2023-06-01", "2023-06-30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now, _ := time.Parse(dateLayout, tt.now)
+			from, to, err := queryRange(httptest.NewRequest("GET", "/"+tt.query, nil), now.Add(15*time.Hour))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if from.Format(dateLayout) != tt.from || to.Format(dateLayout) != tt.to {
+				t.Errorf("Expected %s to %s, got %s to %s", tt.from, tt.to, from.Format(dateLayout), to.Format(dateLayout))
+			}
+		})
+	}
+}
+
+func TestV2Series(t *testing.T) {
+	s, h := newV2TestServer(t)
+	const (
+		faculty = "a_arun@cb.amrita.edu"
+		other   = "pn_kumar@cb.amrita.edu"
+	)
+	// Slot 8 of A104 is free on Mondays; the second Monday is taken
+	if w := do(t, s, h, "POST", "/api/v2/bookings", `{"room": "A104", "date": "2023-06-19", "startSlot": 8, "subject": "19CSE311"}`, other, roleFaculty); w.Code != http.StatusCreated {
+		t.Fatalf("Failed to book: %d %s", w.Code, w.Body)
+	}
+	series := `{"room": "A104", "start": "2023-06-12", "startSlot": 8, "count": 3, "subject": "19CSE311"`
+
+	w := do(t, s, h, "POST", "/api/v2/series", series+`}`, faculty, roleFaculty)
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), `"date":"2023-06-19T00:00:00Z","slot":8,"reason":"booked"`) {
+		t.Fatalf("Expected a conflict on June 19th, got %d %s", w.Code, w.Body)
+	}
+	w = do(t, s, h, "POST", "/api/v2/series", `{"room": "A104", "start": "2023-06-12", "startSlot": 8, "subject": "19CSE311"}`, faculty, roleFaculty)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"field":"until"`) {
+		t.Errorf("Expected until to be required, got %d %s", w.Code, w.Body)
+	}
+
+	w = do(t, s, h, "POST", "/api/v2/series", series+`, "partial": true}`, faculty, roleFaculty)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Failed to book the series: %d %s", w.Code, w.Body)
+	}
+	var created seriesV2
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if created.Until != "2023-06-26" || strings.Join(created.Occurrences, " ") != "2023-06-12 2023-06-26" ||
+		strings.Join(created.Skip, " ") != "2023-06-19" || len(created.Conflicts) != 1 {
+		t.Errorf("Unexpected series %s", w.Body)
+	}
+	path := w.Header().Get("Location")
+
+	if w := do(t, s, h, "GET", "/api/v2/bookings", "", faculty, roleFaculty); !strings.Contains(w.Body.String(), fmt.Sprintf(`"series":%d`, created.ID)) {
+		t.Errorf("Expected the bookings to name the series, got %s", w.Body)
+	}
+	if w := do(t, s, h, "DELETE", path+"/occurrences/2023-06-26", "", other, roleFaculty); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 cancelling somebody else's, got %d", w.Code)
+	}
+	if w := do(t, s, h, "DELETE", path+"/occurrences/2023-06-26", "", faculty, roleFaculty); w.Code != http.StatusNoContent {
+		t.Errorf("Failed to cancel an occurrence: %d %s", w.Code, w.Body)
+	}
+	if w := do(t, s, h, "GET", path, "", other, roleFaculty); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 reading somebody else's, got %d", w.Code)
+	}
+	w = do(t, s, h, "GET", path, "", faculty, roleFaculty)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"skip":["2023-06-19","2023-06-26"],"occurrences":["2023-06-12"]`) {
+		t.Errorf("Expected June 26th skipped, got %d %s", w.Code, w.Body)
+	}
+	if w := do(t, s, h, "DELETE", path, "", faculty, roleFaculty); w.Code != http.StatusNoContent {
+		t.Errorf("Failed to cancel the series: %d %s", w.Code, w.Body)
+	}
+	if w := do(t, s, h, "GET", path, "", faculty, roleFaculty); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 after cancelling, got %d", w.Code)
+	}
+	if w := do(t, s, h, "GET", "/api/v2/series/abc", "", faculty, roleFaculty); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a malformed id, got %d", w.Code)
+	}
+}
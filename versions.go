@@ -0,0 +1,133 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/deebakkarthi/coraserver/db"
+)
+
+// versionV2 is a version of the static timetable as /api/v2 shows it
+type versionV2 struct {
+	Name string `json:"name"`
+	// Start and End are empty when the version has no first or last date
+	Start     string `json:"start"`
+	End       string `json:"end"`
+	Published bool   `json:"published"`
+}
+
+// versionRequestV2 is the body of PUT /api/v2/timetables/{version}
+type versionRequestV2 struct {
+	Start     string `json:"start"`
+	End       string `json:"end"`
+	Published bool   `json:"published"`
+}
+
+// copyRequestV2 is the body of POST /api/v2/timetables/{version}/copy
+type copyRequestV2 struct {
+	From string `json:"from"`
+}
+
+// formatOptionalDate formats date, or gives "" for a zero one
+func formatOptionalDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format(dateLayout)
+}
+
+func (s *server) v2TimetableVersions(w http.ResponseWriter, r *http.Request) {
+	id, _ := identityFrom(r.Context())
+	versions, err := s.store.GetTimetableVersions(r.Context(), actor(id))
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	response := []versionV2{}
+	for _, v := range versions {
+		response = append(response, versionV2{v.Name, formatOptionalDate(v.Start), formatOptionalDate(v.End), v.Published})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+/*
+v2SetTimetableVersion adds a version of the static timetable or changes its
+dates. A new version is usually staged, filled in and previewed, then
+published by sending it again with published set.
+*/
+func (s *server) v2SetTimetableVersion(w http.ResponseWriter, r *http.Request) {
+	var body versionRequestV2
+	if err := decodeJSON(w, r, &body); err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	start, err := parseDate("start", body.Start, true)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	end, err := parseDate("end", body.End, true)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	id, _ := identityFrom(r.Context())
+	err = s.store.SetTimetableVersion(r.Context(), actor(id), db.TimetableVersion{
+		Name:      r.PathValue("version"),
+		Start:     start,
+		End:       end,
+		Published: body.Published,
+	})
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// v2DeleteTimetableVersion deletes a staged version and its timetable
+func (s *server) v2DeleteTimetableVersion(w http.ResponseWriter, r *http.Request) {
+	id, _ := identityFrom(r.Context())
+	if err := s.store.DeleteTimetableVersion(r.Context(), actor(id), r.PathValue("version")); err != nil {
+		writeDBError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// v2CopyTimetableVersion replaces the timetable of a version with the one of
+// the version in the body
+func (s *server) v2CopyTimetableVersion(w http.ResponseWriter, r *http.Request) {
+	var body copyRequestV2
+	if err := decodeJSON(w, r, &body); err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	if body.From == "" {
+		writeBadRequest(w, &fieldError{"from", "from is required"})
+		return
+	}
+	id, _ := identityFrom(r.Context())
+	if err := s.store.CopyTimetableVersion(r.Context(), actor(id), body.From, r.PathValue("version")); err != nil {
+		writeDBError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// v2PreviewSchedule is v2RoomSchedule as if the version were in force on
+// every date of the range
+func (s *server) v2PreviewSchedule(w http.ResponseWriter, r *http.Request) {
+	from, to, err := queryRange(r, time.Now())
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	id, _ := identityFrom(r.Context())
+	days, err := s.store.PreviewTimetableRange(r.Context(), actor(id), r.PathValue("version"), r.PathValue("id"), from, to)
+	if err != nil {
+		writeDBError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toDaysV2(days))
+}